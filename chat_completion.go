@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"slices"
 	"sync"
+	"time"
 
 	"github.com/zachczx/openroutergo/internal/debug"
 	"github.com/zachczx/openroutergo/internal/optional"
@@ -46,10 +47,26 @@ func (c *Client) NewChatCompletion() *chatCompletionBuilder {
 		responseFormat:     optional.MapStringAny{IsSet: false},
 		structuredOutputs:  optional.Bool{IsSet: false},
 		stop:               []string{},
+		prediction:         optional.String{IsSet: false},
+		jsonRepair:         false,
+		sanitizeSchemas:    false,
+		toolEmulation:      EmulationOff,
+		formatEmulation:    EmulationOff,
+		structuredRetries:  0,
+		truncation:         TruncationOff,
+		contextLength:      optional.Int{IsSet: false},
+		reservedTokens:     optional.Int{IsSet: false},
+		keepLastMessages:   defaultKeepLastMessages,
 		tools:              []chatCompletionToolFunction{},
+		toolRegistry:       nil,
+		maxToolIterations:  defaultMaxToolIterations,
+		toolConcurrency:    defaultToolConcurrency,
+		toolTimeout:        0,
+		toolArgsFeedback:   false,
 		toolChoice:         optional.String{IsSet: false},
 		maxPromptPrice:     optional.Float64{IsSet: false},
 		maxCompletionPrice: optional.Float64{IsSet: false},
+		stored:             nil,
 	}
 }
 
@@ -78,10 +95,26 @@ type chatCompletionBuilder struct {
 	responseFormat     optional.MapStringAny
 	structuredOutputs  optional.Bool
 	stop               []string
+	prediction         optional.String
+	jsonRepair         bool
+	sanitizeSchemas    bool
+	toolEmulation      EmulationMode
+	formatEmulation    EmulationMode
+	structuredRetries  int
+	truncation         TruncationStrategy
+	contextLength      optional.Int
+	reservedTokens     optional.Int
+	keepLastMessages   int
 	tools              []chatCompletionToolFunction
+	toolRegistry       *ToolRegistry
+	maxToolIterations  int
+	toolConcurrency    int
+	toolTimeout        time.Duration
+	toolArgsFeedback   bool
 	toolChoice         optional.String
 	maxPromptPrice     optional.Float64
 	maxCompletionPrice optional.Float64
+	stored             *storedConversation
 }
 
 // Clone returns a completely new chat completion builder with the same configuration as the current
@@ -95,9 +128,9 @@ func (b *chatCompletionBuilder) Clone() *chatCompletionBuilder {
 		executing:          false,
 		debug:              b.debug,
 		ctx:                b.ctx,
-		messages:           b.messages,
+		messages:           slices.Clone(b.messages),
 		model:              b.model,
-		fallbackModels:     b.fallbackModels,
+		fallbackModels:     slices.Clone(b.fallbackModels),
 		temperature:        b.temperature,
 		topP:               b.topP,
 		topK:               b.topK,
@@ -113,11 +146,27 @@ func (b *chatCompletionBuilder) Clone() *chatCompletionBuilder {
 		topLogprobs:        b.topLogprobs,
 		responseFormat:     b.responseFormat,
 		structuredOutputs:  b.structuredOutputs,
-		stop:               b.stop,
-		tools:              b.tools,
+		stop:               slices.Clone(b.stop),
+		prediction:         b.prediction,
+		jsonRepair:         b.jsonRepair,
+		sanitizeSchemas:    b.sanitizeSchemas,
+		toolEmulation:      b.toolEmulation,
+		formatEmulation:    b.formatEmulation,
+		structuredRetries:  b.structuredRetries,
+		truncation:         b.truncation,
+		contextLength:      b.contextLength,
+		reservedTokens:     b.reservedTokens,
+		keepLastMessages:   b.keepLastMessages,
+		tools:              slices.Clone(b.tools),
+		toolRegistry:       b.toolRegistry,
+		maxToolIterations:  b.maxToolIterations,
+		toolConcurrency:    b.toolConcurrency,
+		toolTimeout:        b.toolTimeout,
+		toolArgsFeedback:   b.toolArgsFeedback,
 		toolChoice:         b.toolChoice,
 		maxPromptPrice:     b.maxPromptPrice,
 		maxCompletionPrice: b.maxCompletionPrice,
+		stored:             b.stored,
 	}
 }
 
@@ -144,6 +193,13 @@ type ChatCompletionTool struct {
 	//   - Format example: https://platform.openai.com/docs/guides/function-calling
 	//   - JSON Schema reference: https://json-schema.org/understanding-json-schema/reference
 	Parameters map[string]any `json:"parameters"`
+	// Strict enables the strict mode of the models that support it, the arguments
+	// generated by the model always match the parameters schema.
+	//
+	// Strict mode requires all the properties to be required and additionalProperties to
+	// be false, the parameters are rewritten before sending them so the optional
+	// properties become nullable, see StrictSchema.
+	Strict bool `json:"strict,omitempty"`
 }
 
 // WithDebug sets the debug flag for the chat completion request.
@@ -166,12 +222,23 @@ func (b *chatCompletionBuilder) WithContext(ctx context.Context) *chatCompletion
 //
 // If not set, the default model configured in the OpenRouter user's account will be used.
 //
+// The model ID is validated when the request is executed, see [ModelID] for the
+// format and WithModelID to set a model ID composed with its helpers.
+//
 // You can search for models here: https://openrouter.ai/models
 func (b *chatCompletionBuilder) WithModel(model string) *chatCompletionBuilder {
 	b.model = optional.String{IsSet: true, Value: model}
 	return b
 }
 
+// WithModelID is like WithModel but receives a [ModelID], for example, one composed
+// with variants like VariantNitro or VariantFree:
+//
+//	completion.WithModelID(openroutergo.NewModelID("openai", "gpt-4o").WithVariant(openroutergo.VariantNitro))
+func (b *chatCompletionBuilder) WithModelID(model ModelID) *chatCompletionBuilder {
+	return b.WithModel(model.String())
+}
+
 // WithModelFallback adds a model to the fallback list for the chat completion request.
 //
 // You can call this method up to 3 times to add more than one fallback model.
@@ -478,6 +545,22 @@ func (b *chatCompletionBuilder) WithStop(stop []string) *chatCompletionBuilder {
 	return b
 }
 
+// WithPrediction sets the predicted output for the chat completion request.
+//
+// When most of the response is known in advance (for example, when asking the model to
+// make small edits to a file), passing the expected content as a prediction lets
+// supported models skip generating the matching tokens, which greatly reduces latency.
+//
+// The number of accepted and rejected prediction tokens is reported in the
+// [ChatCompletionResponseUsage] completion token details.
+//
+//   - Docs: https://openrouter.ai/docs/api-reference/parameters#predicted-outputs
+//   - More info: https://platform.openai.com/docs/guides/predicted-outputs
+func (b *chatCompletionBuilder) WithPrediction(content string) *chatCompletionBuilder {
+	b.prediction = optional.String{IsSet: true, Value: content}
+	return b
+}
+
 // WithTool adds a tool to the chat completion request so the model can return a tool call.
 //
 // If your tool requires parameters, read the [ChatCompletionTool] type documentation
@@ -530,6 +613,35 @@ type errorResponse struct {
 	} `json:"error"`
 }
 
+// APIError is returned when the OpenRouter API responds with an error, for example,
+// when a parameter is not supported by any provider of the model.
+//
+// It matches ErrRequestFailed when using errors.Is.
+//
+//   - Docs: https://openrouter.ai/docs/api-reference/errors
+type APIError struct {
+	// The HTTP status code of the response.
+	StatusCode int
+	// The error message returned by the API.
+	Message string
+	// The additional details of the error returned by the API, for example, the raw
+	// error of the provider, nil if there are none.
+	Metadata map[string]any
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s with status code %d", ErrRequestFailed, e.StatusCode)
+	}
+	return fmt.Sprintf("%s with status code %d: %s", ErrRequestFailed, e.StatusCode, e.Message)
+}
+
+// Unwrap returns ErrRequestFailed.
+func (e *APIError) Unwrap() error {
+	return ErrRequestFailed
+}
+
 // Execute the chat completion request with the configured parameters.
 //
 // Returns:
@@ -567,6 +679,11 @@ type errorResponse struct {
 //
 //	fmt.Println("Response: ", resp.Choices[0].Message.Content)
 func (b *chatCompletionBuilder) Execute() (*chatCompletionBuilder, ChatCompletionResponse, error) {
+	return b.execute(b.ctx)
+}
+
+// execute runs the chat completion request using the given context, see Execute.
+func (b *chatCompletionBuilder) execute(ctx context.Context) (*chatCompletionBuilder, ChatCompletionResponse, error) {
 	if b.executing {
 		return b, ChatCompletionResponse{}, ErrAlreadyExecuting
 	}
@@ -585,6 +702,17 @@ func (b *chatCompletionBuilder) Execute() (*chatCompletionBuilder, ChatCompletio
 		return b, ChatCompletionResponse{}, ErrMessagesRequired
 	}
 
+	if b.model.IsSet {
+		if err := ModelID(b.model.Value).Validate(); err != nil {
+			return b, ChatCompletionResponse{}, err
+		}
+	}
+	for _, fallbackModel := range b.fallbackModels {
+		if err := ModelID(fallbackModel).Validate(); err != nil {
+			return b, ChatCompletionResponse{}, err
+		}
+	}
+
 	requestBodyMap := map[string]any{}
 	if len(b.messages) > 0 {
 		requestBodyMap["messages"] = b.messages
@@ -634,22 +762,69 @@ func (b *chatCompletionBuilder) Execute() (*chatCompletionBuilder, ChatCompletio
 	if b.topLogprobs.IsSet {
 		requestBodyMap["top_logprobs"] = b.topLogprobs.Value
 	}
+	emulateFormat, err := b.shouldEmulateResponseFormat(ctx)
+	if err != nil {
+		return b, ChatCompletionResponse{}, err
+	}
+	formatInstructions := ""
 	if b.responseFormat.IsSet {
-		requestBodyMap["response_format"] = b.responseFormat.Value
+		responseFormat, err := b.requestResponseFormat()
+		if err != nil {
+			return b, ChatCompletionResponse{}, err
+		}
+		if emulateFormat {
+			formatInstructions, err = emulatedResponseFormatInstructions(responseFormat)
+			if err != nil {
+				return b, ChatCompletionResponse{}, err
+			}
+		} else {
+			requestBodyMap["response_format"] = responseFormat
+		}
 	}
-	if b.structuredOutputs.IsSet {
+	if b.structuredOutputs.IsSet && !emulateFormat {
 		requestBodyMap["structured_outputs"] = b.structuredOutputs.Value
 	}
 	if len(b.stop) > 0 {
 		requestBodyMap["stop"] = b.stop
 	}
-	if len(b.tools) > 0 {
-		requestBodyMap["tools"] = b.tools
+	if b.prediction.IsSet {
+		requestBodyMap["prediction"] = map[string]string{
+			"type":    "content",
+			"content": b.prediction.Value,
+		}
+	}
+	tools, err := b.prepareTools(b.requestTools())
+	if err != nil {
+		return b, ChatCompletionResponse{}, err
+	}
+	messages, truncatedMessages, err := b.truncatedMessages(ctx, tools)
+	if err != nil {
+		return b, ChatCompletionResponse{}, err
+	}
+	requestBodyMap["messages"] = messages
+	emulateTools := false
+	if len(tools) > 0 {
+		if emulateTools, err = b.shouldEmulate(ctx, b.toolEmulation, "tools"); err != nil {
+			return b, ChatCompletionResponse{}, err
+		}
+	}
+	if emulateTools {
+		messages, err := b.emulatedToolMessages(messages, tools)
+		if err != nil {
+			return b, ChatCompletionResponse{}, err
+		}
+		requestBodyMap["messages"] = messages
+	} else if len(tools) > 0 {
+		requestBodyMap["tools"] = tools
+	}
+	if emulateFormat {
+		messages, _ := requestBodyMap["messages"].([]ChatCompletionMessage)
+		requestBodyMap["messages"] = withSystemInstructions(messages, formatInstructions)
 	}
 
 	requestBodyMap["stream"] = true
 
-	if b.toolChoice.IsSet {
+	if b.toolChoice.IsSet && !emulateTools {
 		if slices.Contains([]string{"none", "auto", "required"}, b.toolChoice.Value) {
 			requestBodyMap["tool_choice"] = b.toolChoice.Value
 		} else {
@@ -682,7 +857,7 @@ func (b *chatCompletionBuilder) Execute() (*chatCompletionBuilder, ChatCompletio
 		return b, ChatCompletionResponse{}, fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
-	req, err := b.client.newRequest(b.ctx, http.MethodPost, "/chat/completions", requestBodyBytes)
+	req, err := b.client.newRequest(ctx, http.MethodPost, "/chat/completions", requestBodyBytes)
 	if err != nil {
 		return b, ChatCompletionResponse{}, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -718,7 +893,11 @@ func (b *chatCompletionBuilder) Execute() (*chatCompletionBuilder, ChatCompletio
 		if err := json.Unmarshal(bodyBytes, &errorResponse); err != nil {
 			return b, ChatCompletionResponse{}, fmt.Errorf("failed to decode error response: %w", err)
 		}
-		return b, ChatCompletionResponse{}, fmt.Errorf("request failed with status code %d: %s", resp.StatusCode, errorResponse.Error.Message)
+		return b, ChatCompletionResponse{}, &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    errorResponse.Error.Message,
+			Metadata:   errorResponse.Error.Metadata,
+		}
 	}
 
 	var response ChatCompletionResponse
@@ -726,6 +905,19 @@ func (b *chatCompletionBuilder) Execute() (*chatCompletionBuilder, ChatCompletio
 		return b, ChatCompletionResponse{}, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	if emulateTools {
+		parseEmulatedToolCalls(&response)
+	}
+
+	if emulateFormat {
+		extractEmulatedResponseFormat(&response)
+	}
+	response.TruncatedMessages = truncatedMessages
+
+	if b.jsonRepair {
+		b.repairResponse(&response)
+	}
+
 	// Add all the response messages to the builder so we can continue the conversation
 	if len(response.Choices) > 0 {
 		for _, choice := range response.Choices {
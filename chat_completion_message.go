@@ -12,11 +12,19 @@ type ChatCompletionMessage struct {
 	// The name of the entity that sent the message or the name of the tool, if the message is a tool call.
 	Name string `json:"name,omitempty,omitzero"`
 	// Who the message is from. Must be one of openroutergo.RoleSystem, openroutergo.RoleUser, or openroutergo.RoleAssistant.
-	Role chatCompletionRole `json:"role"`
+	Role ChatCompletionRole `json:"role"`
 	// The content of the message
 	Content string `json:"content"`
+	// The refusal message generated by the model, if the model refused to answer.
+	Refusal string `json:"refusal,omitempty,omitzero"`
+	// True if the content was malformed JSON that was repaired, only set when the
+	// WithJSONRepair option is enabled.
+	ContentRepaired bool `json:"-"`
 	// When the model decided to call a tool
 	ToolCalls []ChatCompletionMessageToolCall `json:"tool_calls,omitempty,omitzero"`
+	// True if the tool calls were parsed from the content because tool calling was
+	// emulated, see the WithToolEmulation method.
+	ToolCallsEmulated bool `json:"-"`
 }
 
 // HasToolCalls returns true if the message has tool calls.
@@ -24,36 +32,57 @@ func (c ChatCompletionMessage) HasToolCalls() bool {
 	return len(c.ToolCalls) > 0
 }
 
-// chatCompletionRole is an enum for the role of a message in a chat completion.
-type chatCompletionRole enum.Member[string]
+// HasRefusal returns true if the model refused to answer.
+func (c ChatCompletionMessage) HasRefusal() bool {
+	return c.Refusal != ""
+}
+
+// ChatCompletionRole is an enum for the role of a message in a chat completion.
+//
+// Roles not known by this library are preserved as they are, use the IsKnown method
+// to detect them.
+type ChatCompletionRole enum.Member[string]
+
+// String returns the raw value of the role.
+func (ccr ChatCompletionRole) String() string {
+	return ccr.Value
+}
 
-// MarshalJSON implements the json.Marshaler interface for chatCompletionRole.
-func (ccr chatCompletionRole) MarshalJSON() ([]byte, error) {
+// IsKnown returns true if the role is one of the roles defined in this library.
+func (ccr ChatCompletionRole) IsKnown() bool {
+	return Roles.Contains(ccr)
+}
+
+// MarshalJSON implements the json.Marshaler interface for ChatCompletionRole.
+func (ccr ChatCompletionRole) MarshalJSON() ([]byte, error) {
 	return json.Marshal(ccr.Value)
 }
 
-// UnmarshalJSON implements the json.Unmarshaler interface for chatCompletionRole.
-func (ccr *chatCompletionRole) UnmarshalJSON(data []byte) error {
+// UnmarshalJSON implements the json.Unmarshaler interface for ChatCompletionRole.
+func (ccr *ChatCompletionRole) UnmarshalJSON(data []byte) error {
 	var value string
 	if err := json.Unmarshal(data, &value); err != nil {
 		return err
 	}
 
-	*ccr = chatCompletionRole{Value: value}
+	*ccr = ChatCompletionRole{Value: value}
 	return nil
 }
 
 var (
 	// RoleSystem is the role of a system message in a chat completion.
-	RoleSystem = chatCompletionRole{"system"}
+	RoleSystem = ChatCompletionRole{"system"}
 	// RoleDeveloper is the role of a developer message in a chat completion.
-	RoleDeveloper = chatCompletionRole{"developer"}
+	RoleDeveloper = ChatCompletionRole{"developer"}
 	// RoleUser is the role of a user message in a chat completion.
-	RoleUser = chatCompletionRole{"user"}
+	RoleUser = ChatCompletionRole{"user"}
 	// RoleAssistant is the role of an assistant message in a chat completion.
-	RoleAssistant = chatCompletionRole{"assistant"}
+	RoleAssistant = ChatCompletionRole{"assistant"}
 	// RoleTool is the role of a tool message in a chat completion.
-	RoleTool = chatCompletionRole{"tool"}
+	RoleTool = ChatCompletionRole{"tool"}
+
+	// Roles contains all the roles known by this library.
+	Roles = enum.New(RoleSystem, RoleDeveloper, RoleUser, RoleAssistant, RoleTool)
 )
 
 type ChatCompletionMessageToolCall struct {
@@ -71,8 +100,12 @@ type ChatCompletionMessageToolCallFunction struct {
 	// The arguments to call the function with, as generated by the model in JSON
 	// format. Note that the model does not always generate valid JSON, and may
 	// hallucinate parameters not defined by your function schema. Validate the
-	// arguments in your code before calling your function.
+	// arguments in your code before calling your function, you can use the
+	// ValidateToolArguments function to check them against your tool's schema.
 	//
 	// You have to unmarshal the arguments to the correct type yourself.
 	Arguments string `json:"arguments"`
+	// True if the arguments were malformed JSON that were repaired, only set when the
+	// WithJSONRepair option is enabled.
+	ArgumentsRepaired bool `json:"-"`
 }
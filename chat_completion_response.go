@@ -2,42 +2,66 @@ package openroutergo
 
 import (
 	"encoding/json"
+	"math"
 
 	"github.com/orsinium-labs/enum"
 )
 
-// chatCompletionFinishReason is an enum for the reason the model stopped generating tokens.
+// ChatCompletionFinishReason is an enum for the reason the model stopped generating tokens.
+//
+// Finish reasons not known by this library are preserved as they are, use the IsKnown
+// method to detect them.
 //
 //   - https://openrouter.ai/docs/api-reference/overview#finish-reason
-type chatCompletionFinishReason enum.Member[string]
+type ChatCompletionFinishReason enum.Member[string]
+
+// String returns the raw value of the finish reason.
+func (cfr ChatCompletionFinishReason) String() string {
+	return cfr.Value
+}
+
+// IsKnown returns true if the finish reason is one of the finish reasons defined in
+// this library.
+func (cfr ChatCompletionFinishReason) IsKnown() bool {
+	return FinishReasons.Contains(cfr)
+}
 
-// MarshalJSON implements the json.Marshaler interface for chatCompletionFinishReason.
-func (cfr chatCompletionFinishReason) MarshalJSON() ([]byte, error) {
+// MarshalJSON implements the json.Marshaler interface for ChatCompletionFinishReason.
+func (cfr ChatCompletionFinishReason) MarshalJSON() ([]byte, error) {
 	return json.Marshal(cfr.Value)
 }
 
-// UnmarshalJSON implements the json.Unmarshaler interface for chatCompletionFinishReason.
-func (cfr *chatCompletionFinishReason) UnmarshalJSON(data []byte) error {
+// UnmarshalJSON implements the json.Unmarshaler interface for ChatCompletionFinishReason.
+func (cfr *ChatCompletionFinishReason) UnmarshalJSON(data []byte) error {
 	var value string
 	if err := json.Unmarshal(data, &value); err != nil {
 		return err
 	}
 
-	*cfr = chatCompletionFinishReason{Value: value}
+	*cfr = ChatCompletionFinishReason{Value: value}
 	return nil
 }
 
 var (
 	// FinishReasonStop is when the model hit a natural stop point or a provided stop sequence.
-	FinishReasonStop = chatCompletionFinishReason{"stop"}
+	FinishReasonStop = ChatCompletionFinishReason{"stop"}
 	// FinishReasonLength is when the maximum number of tokens specified in the request was reached.
-	FinishReasonLength = chatCompletionFinishReason{"length"}
+	FinishReasonLength = ChatCompletionFinishReason{"length"}
 	// FinishReasonContentFilter is when content was omitted due to a flag from our content filters.
-	FinishReasonContentFilter = chatCompletionFinishReason{"content_filter"}
+	FinishReasonContentFilter = ChatCompletionFinishReason{"content_filter"}
 	// FinishReasonToolCalls is when the model called a tool.
-	FinishReasonToolCalls = chatCompletionFinishReason{"tool_calls"}
+	FinishReasonToolCalls = ChatCompletionFinishReason{"tool_calls"}
 	// FinishReasonError is when the model returned an error.
-	FinishReasonError = chatCompletionFinishReason{"error"}
+	FinishReasonError = ChatCompletionFinishReason{"error"}
+
+	// FinishReasons contains all the finish reasons known by this library.
+	FinishReasons = enum.New(
+		FinishReasonStop,
+		FinishReasonLength,
+		FinishReasonContentFilter,
+		FinishReasonToolCalls,
+		FinishReasonError,
+	)
 )
 
 // ChatCompletionResponse is the response from the OpenRouter API for a chat completion request.
@@ -59,6 +83,15 @@ type ChatCompletionResponse struct {
 	Provider string `json:"provider"`
 	// The object type, which is always "chat.completion"
 	Object string `json:"object"`
+	// The fingerprint of the backend configuration the model ran with, it can be used
+	// together with the seed to understand when backend changes might impact determinism.
+	SystemFingerprint string `json:"system_fingerprint"`
+	// True if the response format was emulated with prompting because the model
+	// doesn't support it, see WithResponseFormatEmulation.
+	ResponseFormatEmulated bool `json:"-"`
+	// The number of messages of the conversation that were dropped or trimmed to fit in
+	// the context window of the model, see WithTruncation.
+	TruncatedMessages int `json:"-"`
 }
 
 // HasChoices returns true if the chat completion has choices.
@@ -67,14 +100,131 @@ func (c ChatCompletionResponse) HasChoices() bool {
 }
 
 type ChatCompletionResponseChoice struct {
+	// The index of the choice in the list of choices.
+	Index int `json:"index"`
 	// The reason the model stopped generating tokens. This will be `stop` if the model hit a
 	// natural stop point or a provided stop sequence, `length` if the maximum number of
 	// tokens specified in the request was reached, `content_filter` if content was omitted
 	// due to a flag from our content filters, `tool_calls` if the model called a tool, or
 	// `error` if the model returned an error.
-	FinishReason chatCompletionFinishReason `json:"finish_reason"`
+	//
+	// Use the IsKnown method to detect finish reasons not defined in this library.
+	FinishReason ChatCompletionFinishReason `json:"finish_reason"`
+	// The raw finish reason returned by the provider, before OpenRouter normalized it,
+	// useful to detect provider-specific stop reasons.
+	NativeFinishReason string `json:"native_finish_reason"`
 	// A chat completion message generated by the model.
 	Message ChatCompletionMessage `json:"message"`
+	// Log probability information for the choice, only present when logprobs were
+	// requested using the WithLogprobs method and the model supports them.
+	Logprobs ChatCompletionResponseChoiceLogprobs `json:"logprobs"`
+	// The error returned by the provider for this choice, only present when the finish
+	// reason is `error`.
+	Error ChatCompletionResponseChoiceError `json:"error"`
+}
+
+// HasError returns true if the provider returned an error for the choice.
+func (c ChatCompletionResponseChoice) HasError() bool {
+	return c.Error.Code != 0 || c.Error.Message != ""
+}
+
+// HasLogprobs returns true if the choice has log probability information for its content.
+func (c ChatCompletionResponseChoice) HasLogprobs() bool {
+	return len(c.Logprobs.Content) > 0
+}
+
+type ChatCompletionResponseChoiceError struct {
+	// The error code.
+	Code int `json:"code"`
+	// The error message.
+	Message string `json:"message"`
+	// Additional provider-specific information about the error.
+	Metadata map[string]any `json:"metadata"`
+}
+
+type ChatCompletionResponseChoiceLogprobs struct {
+	// A list of message content tokens with log probability information.
+	Content []ChatCompletionResponseChoiceLogprobsToken `json:"content"`
+	// A list of message refusal tokens with log probability information.
+	Refusal []ChatCompletionResponseChoiceLogprobsToken `json:"refusal"`
+}
+
+// LogLikelihood returns the log-likelihood of the whole generated content, which is
+// the sum of the log probabilities of all the content tokens.
+func (l ChatCompletionResponseChoiceLogprobs) LogLikelihood() float64 {
+	sum := 0.0
+	for _, token := range l.Content {
+		sum += token.Logprob
+	}
+	return sum
+}
+
+// AverageLogprob returns the mean log probability of the content tokens.
+//
+// Returns 0 if there are no content tokens.
+func (l ChatCompletionResponseChoiceLogprobs) AverageLogprob() float64 {
+	if len(l.Content) == 0 {
+		return 0
+	}
+	return l.LogLikelihood() / float64(len(l.Content))
+}
+
+// Perplexity returns the perplexity of the generated content, calculated as the
+// exponential of the negative mean log probability of the content tokens.
+//
+// A perplexity of 1 means the model was completely certain about every token, higher
+// values mean the model was less certain about its answer.
+//
+// Returns 0 if there are no content tokens.
+func (l ChatCompletionResponseChoiceLogprobs) Perplexity() float64 {
+	if len(l.Content) == 0 {
+		return 0
+	}
+	return math.Exp(-l.AverageLogprob())
+}
+
+// Confidences returns the probability (between 0 and 1) of each content token, in the
+// same order they were generated.
+func (l ChatCompletionResponseChoiceLogprobs) Confidences() []float64 {
+	confidences := make([]float64, len(l.Content))
+	for i, token := range l.Content {
+		confidences[i] = token.Probability()
+	}
+	return confidences
+}
+
+type ChatCompletionResponseChoiceLogprobsToken struct {
+	// The token.
+	Token string `json:"token"`
+	// The log probability of this token.
+	Logprob float64 `json:"logprob"`
+	// The UTF-8 bytes representation of the token. Useful when characters are represented
+	// by multiple tokens and their byte representations must be combined to generate the
+	// correct text representation. Can be empty if there is no bytes representation.
+	Bytes []int `json:"bytes"`
+	// List of the most likely tokens and their log probability at this token position.
+	// The number of items is controlled by the WithTopLogprobs method.
+	TopLogprobs []ChatCompletionResponseChoiceLogprobsTopToken `json:"top_logprobs"`
+}
+
+// Probability returns the probability (between 0 and 1) of the token.
+func (t ChatCompletionResponseChoiceLogprobsToken) Probability() float64 {
+	return math.Exp(t.Logprob)
+}
+
+type ChatCompletionResponseChoiceLogprobsTopToken struct {
+	// The token.
+	Token string `json:"token"`
+	// The log probability of this token.
+	Logprob float64 `json:"logprob"`
+	// The UTF-8 bytes representation of the token. Can be empty if there is no bytes
+	// representation.
+	Bytes []int `json:"bytes"`
+}
+
+// Probability returns the probability (between 0 and 1) of the token.
+func (t ChatCompletionResponseChoiceLogprobsTopToken) Probability() float64 {
+	return math.Exp(t.Logprob)
 }
 
 type ChatCompletionResponseUsage struct {
@@ -84,4 +234,16 @@ type ChatCompletionResponseUsage struct {
 	CompletionTokens int `json:"completion_tokens"`
 	// The total number of tokens used in the request (prompt + completion).
 	TotalTokens int `json:"total_tokens"`
+	// Breakdown of the tokens used in the generated completion.
+	CompletionTokensDetails ChatCompletionResponseUsageCompletionTokensDetails `json:"completion_tokens_details"`
+}
+
+type ChatCompletionResponseUsageCompletionTokensDetails struct {
+	// When using predicted outputs, the number of tokens in the prediction that
+	// appeared in the completion.
+	AcceptedPredictionTokens int `json:"accepted_prediction_tokens"`
+	// When using predicted outputs, the number of tokens in the prediction that did
+	// not appear in the completion. These tokens are still counted and billed as
+	// completion tokens.
+	RejectedPredictionTokens int `json:"rejected_prediction_tokens"`
 }
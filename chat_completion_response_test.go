@@ -0,0 +1,176 @@
+package openroutergo
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+// assertFloat checks that two floats are equal up to the rounding errors.
+func assertFloat(t *testing.T, expected float64, actual float64) {
+	t.Helper()
+	if math.Abs(expected-actual) > 1e-9 {
+		t.Fatalf("expected %v to equal %v", actual, expected)
+	}
+}
+
+func TestChatCompletionResponseChoiceLogprobs(t *testing.T) {
+	token := func(text string, probability float64) ChatCompletionResponseChoiceLogprobsToken {
+		return ChatCompletionResponseChoiceLogprobsToken{
+			Token:       text,
+			Logprob:     math.Log(probability),
+			Bytes:       nil,
+			TopLogprobs: nil,
+		}
+	}
+
+	tests := []struct {
+		name          string
+		content       []ChatCompletionResponseChoiceLogprobsToken
+		logLikelihood float64
+		average       float64
+		perplexity    float64
+		confidences   []float64
+	}{
+		{"Nil", nil, 0, 0, 0, []float64{}},
+		{"Empty", []ChatCompletionResponseChoiceLogprobsToken{}, 0, 0, 0, []float64{}},
+		{"Certain", []ChatCompletionResponseChoiceLogprobsToken{token("Hi", 1), token("!", 1)}, 0, 0, 1, []float64{1, 1}},
+		{
+			"Uncertain",
+			[]ChatCompletionResponseChoiceLogprobsToken{token("Par", 0.5), token("is", 0.25)},
+			math.Log(0.125),
+			math.Log(0.125) / 2,
+			math.Sqrt(8),
+			[]float64{0.5, 0.25},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logprobs := ChatCompletionResponseChoiceLogprobs{Content: tt.content, Refusal: nil}
+
+			assertFloat(t, tt.logLikelihood, logprobs.LogLikelihood())
+			assertFloat(t, tt.average, logprobs.AverageLogprob())
+			assertFloat(t, tt.perplexity, logprobs.Perplexity())
+
+			confidences := logprobs.Confidences()
+			assert.Equal(t, len(tt.confidences), len(confidences))
+			for i, confidence := range confidences {
+				assertFloat(t, tt.confidences[i], confidence)
+				assertFloat(t, tt.confidences[i], tt.content[i].Probability())
+			}
+		})
+	}
+}
+
+func TestChatCompletionResponseChoiceLogprobsDecoding(t *testing.T) {
+	var choices []ChatCompletionResponseChoice
+	err := json.Unmarshal([]byte(`[
+		{"message":{"role":"assistant","content":"Hi"},"logprobs":null},
+		{"message":{"role":"assistant","content":"Hi"},"logprobs":{"content":[
+			{"token":"Hi","logprob":-0.5,"bytes":[72,105],"top_logprobs":[
+				{"token":"Hi","logprob":-0.5,"bytes":[72,105]},
+				{"token":"Hey","logprob":-1.5,"bytes":null}
+			]}
+		],"refusal":null}}
+	]`), &choices)
+	assert.NoError(t, err)
+
+	// The choices without logprobs have no tokens
+	assert.False(t, choices[0].HasLogprobs())
+	assertFloat(t, 0, choices[0].Logprobs.Perplexity())
+
+	assert.True(t, choices[1].HasLogprobs())
+	token := choices[1].Logprobs.Content[0]
+	assert.Equal(t, "Hi", token.Token)
+	assert.Equal(t, 2, len(token.Bytes))
+	assert.Equal(t, 2, len(token.TopLogprobs))
+	assert.Equal(t, "Hey", token.TopLogprobs[1].Token)
+	assertFloat(t, math.Exp(-1.5), token.TopLogprobs[1].Probability())
+	assertFloat(t, math.Exp(0.5), choices[1].Logprobs.Perplexity())
+}
+
+func TestChatCompletionResponseDecoding(t *testing.T) {
+	fixture := `{
+		"id": "gen-123",
+		"provider": "OpenAI",
+		"model": "openai/gpt-4o",
+		"object": "chat.completion",
+		"created": 1735689600,
+		"system_fingerprint": "fp_abc123",
+		"choices": [
+			{
+				"index": 0,
+				"finish_reason": "stop",
+				"native_finish_reason": "end_turn",
+				"message": {"role": "assistant", "content": "Paris"}
+			},
+			{
+				"index": 1,
+				"finish_reason": "stop",
+				"native_finish_reason": "refusal",
+				"message": {"role": "assistant", "content": "", "refusal": "I can't help with that."}
+			},
+			{
+				"index": 2,
+				"finish_reason": "error",
+				"native_finish_reason": "overloaded",
+				"message": {"role": "assistant", "content": ""},
+				"error": {"code": 502, "message": "Provider overloaded", "metadata": {"provider": "OpenAI"}}
+			},
+			{
+				"index": 3,
+				"finish_reason": "paused",
+				"message": {"role": "critic", "content": "Hmm"}
+			}
+		]
+	}`
+
+	var resp ChatCompletionResponse
+	assert.NoError(t, json.Unmarshal([]byte(fixture), &resp))
+	assert.Equal(t, "gen-123", resp.ID)
+	assert.Equal(t, "OpenAI", resp.Provider)
+	assert.Equal(t, "chat.completion", resp.Object)
+	assert.Equal(t, 1735689600, resp.Created)
+	assert.Equal(t, "fp_abc123", resp.SystemFingerprint)
+	assert.Equal(t, 4, len(resp.Choices))
+
+	answer := resp.Choices[0]
+	assert.Equal(t, FinishReasonStop, answer.FinishReason)
+	assert.True(t, answer.FinishReason.IsKnown())
+	assert.Equal(t, "end_turn", answer.NativeFinishReason)
+	assert.Equal(t, RoleAssistant, answer.Message.Role)
+	assert.True(t, answer.Message.Role.IsKnown())
+	assert.False(t, answer.Message.HasRefusal())
+	assert.False(t, answer.HasError())
+
+	refusal := resp.Choices[1]
+	assert.True(t, refusal.Message.HasRefusal())
+	assert.Equal(t, "I can't help with that.", refusal.Message.Refusal)
+	assert.Equal(t, "refusal", refusal.NativeFinishReason)
+
+	failed := resp.Choices[2]
+	assert.Equal(t, FinishReasonError, failed.FinishReason)
+	assert.True(t, failed.HasError())
+	assert.Equal(t, 502, failed.Error.Code)
+	assert.Equal(t, "Provider overloaded", failed.Error.Message)
+	assert.Equal(t, any("OpenAI"), failed.Error.Metadata["provider"])
+
+	// The unknown values are preserved as they are
+	unknown := resp.Choices[3]
+	assert.False(t, unknown.FinishReason.IsKnown())
+	assert.Equal(t, "paused", unknown.FinishReason.String())
+	assert.Equal(t, "", unknown.NativeFinishReason)
+	assert.False(t, unknown.Message.Role.IsKnown())
+	assert.Equal(t, "critic", unknown.Message.Role.String())
+
+	// And encoded back without changes
+	encoded, err := json.Marshal(unknown.FinishReason)
+	assert.NoError(t, err)
+	assert.Equal(t, `"paused"`, string(encoded))
+	encoded, err = json.Marshal(unknown.Message.Role)
+	assert.NoError(t, err)
+	assert.Equal(t, `"critic"`, string(encoded))
+}
@@ -0,0 +1,41 @@
+package openroutergo
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func TestWithPrediction(t *testing.T) {
+	server := newFakeOpenRouter(t, fakeResponse{
+		status: http.StatusOK,
+		body: `{"id":"gen","model":"openai/gpt-4o","choices":[{"finish_reason":"stop",` +
+			`"message":{"role":"assistant","content":"func add(a, b int) int { return a + b }"}}],` +
+			`"usage":{"prompt_tokens":10,"completion_tokens":15,"total_tokens":25,` +
+			`"completion_tokens_details":{"accepted_prediction_tokens":9,"rejected_prediction_tokens":2}}}`,
+	}, textResponse("Done"))
+
+	completion := server.client.NewChatCompletion().
+		WithModel("openai/gpt-4o").
+		WithUserMessage("Rename the function to add").
+		WithPrediction("func sum(a, b int) int { return a + b }")
+
+	_, resp, err := completion.Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, 9, resp.Usage.CompletionTokensDetails.AcceptedPredictionTokens)
+	assert.Equal(t, 2, resp.Usage.CompletionTokensDetails.RejectedPredictionTokens)
+
+	prediction, ok := server.request(0)["prediction"].(map[string]any)
+	assert.True(t, ok)
+	assert.Equal(t, any("content"), prediction["type"])
+	assert.Equal(t, any("func sum(a, b int) int { return a + b }"), prediction["content"])
+
+	// Without a prediction the parameter is not sent and the details are zero
+	_, resp, err = server.client.NewChatCompletion().WithModel("openai/gpt-4o").WithUserMessage("Hi").Execute()
+	assert.NoError(t, err)
+	_, hasPrediction := server.request(1)["prediction"]
+	assert.False(t, hasPrediction)
+	assert.Equal(t, 0, resp.Usage.CompletionTokensDetails.AcceptedPredictionTokens)
+	assert.Equal(t, 0, resp.Usage.CompletionTokensDetails.RejectedPredictionTokens)
+}
@@ -0,0 +1,335 @@
+package openroutergo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/zachczx/openroutergo/internal/optional"
+)
+
+// classifyTopLogprobs is the number of alternative tokens requested at each position
+// when classifying, it is the maximum value accepted by most providers.
+const classifyTopLogprobs = 20
+
+// classifyLabelValueRegex matches the beginning of the label value in the JSON object
+// returned by the model, so we can find the token where the label starts.
+var classifyLabelValueRegex = regexp.MustCompile(`"label"\s*:\s*"`)
+
+// ClassifyResult is the result of a Classify call.
+type ClassifyResult struct {
+	// The label chosen by the model.
+	Label string
+	// The probability of each label, normalized so all the values add up to 1.
+	//
+	// When the model does not return log probabilities, the chosen label will have
+	// a probability of 1 and the rest of the labels a probability of 0.
+	Probabilities map[string]float64
+	// True if the probabilities were calculated using the log probabilities returned
+	// by the model, false if the fallback (chosen label gets all the probability) was used.
+	UsedLogprobs bool
+	// The response from the OpenRouter API.
+	Response ChatCompletionResponse
+}
+
+// Classify asks the model to pick exactly one of the given labels for the conversation
+// configured in the builder, for example, the sentiment of the last user message.
+//
+// The builder is cloned so its conversation is not modified. The clone is configured to:
+//
+//   - Include a system message that instructs the model to pick one of the labels.
+//   - Constrain the output using a JSON Schema with an enum of the labels.
+//   - Request log probabilities, so a probability distribution across all the labels
+//     can be calculated from the alternative tokens at the position where the label starts.
+//
+// If the model does not support log probabilities, the chosen label gets all the
+// probability and UsedLogprobs is set to false. If the model does not support JSON Schema,
+// the label is matched from the plain text response. When the provider rejects the
+// request because of these parameters, it's retried without log probabilities and then
+// without the JSON Schema, relying only on the system message.
+//
+// Example:
+//
+//	completion := client.
+//		NewChatCompletion().
+//		WithModel("...").
+//		WithUserMessage("I love this product!")
+//
+//	result, err := openroutergo.Classify(ctx, completion, []string{"positive", "negative", "neutral"})
+//	if err != nil {
+//		// handle error
+//	}
+//
+//	fmt.Println(result.Label, result.Probabilities[result.Label])
+func Classify(ctx context.Context, builder *chatCompletionBuilder, labels []string) (ClassifyResult, error) {
+	seen := map[string]bool{}
+	for _, label := range labels {
+		if label == "" || seen[label] {
+			return ClassifyResult{}, ErrClassifyLabelsInvalid
+		}
+		seen[label] = true
+	}
+	if len(labels) == 0 {
+		return ClassifyResult{}, ErrClassifyLabelsInvalid
+	}
+
+	quotedLabels := make([]string, len(labels))
+	for i, label := range labels {
+		quotedLabels[i] = fmt.Sprintf("%q", label)
+	}
+
+	systemMessage := ChatCompletionMessage{
+		Role: RoleSystem,
+		Content: "Classify the conversation into exactly one of the following labels: " +
+			strings.Join(quotedLabels, ", ") + ". " +
+			`Respond only with a JSON object in the format {"label": "<label>"}.`,
+	}
+
+	// The attempts from the most to the least capable, a provider that rejects a
+	// parameter is retried without it
+	promptOnly := builder.Clone().WithContext(ctx)
+	promptOnly.messages = append([]ChatCompletionMessage{systemMessage}, promptOnly.messages...)
+	promptOnly.responseFormat = optional.MapStringAny{IsSet: false}
+	promptOnly.logprobs = optional.Bool{IsSet: false}
+	promptOnly.topLogprobs = optional.Int{IsSet: false}
+
+	withSchema := promptOnly.Clone().WithResponseFormat(map[string]any{
+		"type": "json_schema",
+		"json_schema": map[string]any{
+			"name":   "classification",
+			"strict": true,
+			"schema": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"label": map[string]any{
+						"type": "string",
+						"enum": labels,
+					},
+				},
+				"required":             []string{"label"},
+				"additionalProperties": false,
+			},
+		},
+	})
+
+	withLogprobs := withSchema.Clone().WithLogprobs(true)
+	withLogprobs.topLogprobs = builder.topLogprobs
+	if !withLogprobs.topLogprobs.IsSet {
+		withLogprobs.WithTopLogprobs(classifyTopLogprobs)
+	}
+
+	// Each attempt is retried with the next one only when the provider reports that a
+	// parameter dropped by the next one is not supported
+	attempts := []struct {
+		completion *chatCompletionBuilder
+		dropped    []string
+	}{
+		{withLogprobs, []string{"logprobs"}},
+		{withSchema, []string{"response_format", "json_schema", "structured_output"}},
+		{promptOnly, nil},
+	}
+
+	var resp ChatCompletionResponse
+	var err error
+	for _, attempt := range attempts {
+		_, resp, err = attempt.completion.Execute()
+		if !isParameterRejected(err, attempt.dropped...) {
+			break
+		}
+	}
+	if err != nil {
+		return ClassifyResult{}, err
+	}
+	if !resp.HasChoices() {
+		return ClassifyResult{}, ErrClassifyNoLabel
+	}
+
+	choice := resp.Choices[0]
+	label, ok := matchClassifyLabel(choice.Message.Content, labels)
+	if !ok {
+		return ClassifyResult{}, fmt.Errorf("%w: %q", ErrClassifyNoLabel, choice.Message.Content)
+	}
+
+	result := ClassifyResult{
+		Label:         label,
+		Probabilities: map[string]float64{},
+		UsedLogprobs:  false,
+		Response:      resp,
+	}
+
+	if choice.HasLogprobs() {
+		if probabilities, ok := classifyProbabilities(choice.Logprobs, label, labels); ok {
+			result.Probabilities = probabilities
+			result.UsedLogprobs = true
+			return result, nil
+		}
+	}
+
+	for _, l := range labels {
+		result.Probabilities[l] = 0
+	}
+	result.Probabilities[label] = 1
+	return result, nil
+}
+
+// isParameterRejected returns true if the request failed because the provider doesn't
+// support one of the given parameters, OpenRouter responds with a 400 or, when there is
+// no provider that supports all the parameters, a 404 status code, and the message or
+// the metadata of the error names the parameter.
+func isParameterRejected(err error, parameters ...string) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.StatusCode != http.StatusBadRequest && apiErr.StatusCode != http.StatusNotFound {
+		return false
+	}
+
+	details := apiErr.Message
+	if apiErr.Metadata != nil {
+		if metadata, err := json.Marshal(apiErr.Metadata); err == nil {
+			details += " " + string(metadata)
+		}
+	}
+	details = strings.ToLower(details)
+	if !strings.Contains(details, "support") {
+		return false
+	}
+	for _, parameter := range parameters {
+		if strings.Contains(details, parameter) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchClassifyLabel finds the label chosen by the model in the response content.
+//
+// It first tries to decode the expected JSON object and, if that fails (for example,
+// the model does not support JSON Schema), it falls back to match the content as
+// plain text.
+func matchClassifyLabel(content string, labels []string) (string, bool) {
+	var decoded struct {
+		Label string `json:"label"`
+	}
+	if err := json.Unmarshal([]byte(content), &decoded); err == nil {
+		for _, label := range labels {
+			if label == decoded.Label {
+				return label, true
+			}
+		}
+	}
+
+	trimmed := strings.Trim(strings.TrimSpace(content), `"'.`)
+	for _, label := range labels {
+		if strings.EqualFold(trimmed, label) {
+			return label, true
+		}
+	}
+
+	// As a last resort, pick the longest label contained in the content so labels that
+	// are prefixes of other labels do not win over them
+	best := ""
+	lowerContent := strings.ToLower(content)
+	for _, label := range labels {
+		if strings.Contains(lowerContent, strings.ToLower(label)) && len(label) > len(best) {
+			best = label
+		}
+	}
+
+	return best, best != ""
+}
+
+// classifyProbabilities calculates the normalized probability distribution across the
+// labels using the alternative tokens at the position where the chosen label starts.
+//
+// Each alternative token is assigned to the labels it can be the beginning of, if an
+// alternative token can be the beginning of more than one label, its probability is
+// split equally between them.
+//
+// Returns false if the distribution can't be calculated.
+func classifyProbabilities(
+	logprobs ChatCompletionResponseChoiceLogprobs, label string, labels []string,
+) (map[string]float64, bool) {
+	text := ""
+	for _, token := range logprobs.Content {
+		text += token.Token
+	}
+
+	loc := classifyLabelValueRegex.FindStringIndex(text)
+	if loc == nil {
+		return nil, false
+	}
+	valueStart := loc[1]
+
+	offset := 0
+	for _, token := range logprobs.Content {
+		tokenStart, tokenEnd := offset, offset+len(token.Token)
+		offset = tokenEnd
+		if valueStart < tokenStart || valueStart >= tokenEnd {
+			continue
+		}
+
+		// The token can include some characters before the label (e.g. the opening quote)
+		prefix := token.Token[:valueStart-tokenStart]
+
+		alternatives := token.TopLogprobs
+		hasChosen := false
+		for _, alternative := range alternatives {
+			if alternative.Token == token.Token {
+				hasChosen = true
+				break
+			}
+		}
+		if !hasChosen {
+			alternatives = append(alternatives, ChatCompletionResponseChoiceLogprobsTopToken{
+				Token:   token.Token,
+				Logprob: token.Logprob,
+			})
+		}
+
+		probabilities := map[string]float64{}
+		for _, l := range labels {
+			probabilities[l] = 0
+		}
+
+		total := 0.0
+		for _, alternative := range alternatives {
+			if !strings.HasPrefix(alternative.Token, prefix) {
+				continue
+			}
+			// The token can include the JSON characters after the label (e.g. `positive"}`)
+			start := strings.TrimRight(alternative.Token[len(prefix):], "\"}] \t\r\n,")
+			if start == "" {
+				continue
+			}
+
+			matches := []string{}
+			for _, l := range labels {
+				if strings.HasPrefix(l, start) {
+					matches = append(matches, l)
+				}
+			}
+			for _, l := range matches {
+				share := alternative.Probability() / float64(len(matches))
+				probabilities[l] += share
+				total += share
+			}
+		}
+
+		if total == 0 || probabilities[label] == 0 {
+			return nil, false
+		}
+
+		for l := range probabilities {
+			probabilities[l] /= total
+		}
+		return probabilities, true
+	}
+
+	return nil, false
+}
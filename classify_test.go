@@ -0,0 +1,166 @@
+package openroutergo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func TestClassifyRetriesRejectedParameters(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []fakeResponse
+		requests  int
+	}{
+		{"Supported", []fakeResponse{textResponse(`{"label":"positive"}`)}, 1},
+		{
+			"LogprobsRejected",
+			[]fakeResponse{apiErrorResponse(http.StatusBadRequest, "logprobs not supported"), textResponse(`{"label":"positive"}`)},
+			2,
+		},
+		{
+			// The provider error is only in the metadata
+			"LogprobsRejectedInMetadata",
+			[]fakeResponse{
+				{
+					status: http.StatusBadRequest,
+					body:   `{"error":{"code":400,"message":"Provider returned error","metadata":{"raw":"logprobs are not supported"}}}`,
+				},
+				textResponse(`{"label":"positive"}`),
+			},
+			2,
+		},
+		{
+			"SchemaRejected",
+			[]fakeResponse{
+				apiErrorResponse(http.StatusNotFound, "No endpoints found that support logprobs"),
+				apiErrorResponse(http.StatusBadRequest, "json_schema not supported"),
+				textResponse("Positive."),
+			},
+			3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeOpenRouter(t, tt.responses...)
+			completion := server.client.NewChatCompletion().WithModel("openai/gpt-4o").WithUserMessage("I love it!")
+
+			result, err := Classify(context.Background(), completion, []string{"positive", "negative"})
+			assert.NoError(t, err)
+			assert.Equal(t, "positive", result.Label)
+			assert.Equal(t, 1.0, result.Probabilities["positive"])
+			assert.False(t, result.UsedLogprobs)
+			assert.Equal(t, tt.requests, server.requestCount())
+
+			// Each retry drops the logprobs first and then the response format
+			for i := range tt.requests {
+				request := server.request(i)
+				_, hasLogprobs := request["logprobs"]
+				_, hasFormat := request["response_format"]
+				assert.Equal(t, i == 0, hasLogprobs)
+				assert.Equal(t, i <= 1, hasFormat)
+			}
+
+			// The conversation of the builder is not modified
+			assert.Equal(t, 1, len(completion.Messages()))
+		})
+	}
+}
+
+func TestClassifyDoesNotRetryOtherErrors(t *testing.T) {
+	server := newFakeOpenRouter(t, apiErrorResponse(http.StatusTooManyRequests, "rate limited"))
+	completion := server.client.NewChatCompletion().WithUserMessage("I love it!")
+
+	_, err := Classify(context.Background(), completion, []string{"positive", "negative"})
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+	assert.True(t, errors.Is(err, ErrRequestFailed))
+	assert.Equal(t, 1, server.requestCount())
+}
+
+func TestClassifyDoesNotRetryOtherBadRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		message string
+	}{
+		{"InvalidModel", http.StatusBadRequest, "invalid/model is not a valid model ID"},
+		{"ModelNotFound", http.StatusNotFound, "No endpoints found for invalid/model."},
+		// The schema is rejected but the next attempt only drops the logprobs
+		{"SchemaRejectedFirst", http.StatusBadRequest, "json_schema not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeOpenRouter(t, apiErrorResponse(tt.status, tt.message), textResponse("positive"))
+			completion := server.client.NewChatCompletion().WithModel("invalid/model").WithUserMessage("I love it!")
+
+			_, err := Classify(context.Background(), completion, []string{"positive", "negative"})
+			var apiErr *APIError
+			assert.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, tt.status, apiErr.StatusCode)
+			assert.Equal(t, tt.message, apiErr.Message)
+			assert.Equal(t, 1, server.requestCount())
+		})
+	}
+}
+
+func TestClassifyProbabilities(t *testing.T) {
+	token := func(text string, probability float64) ChatCompletionResponseChoiceLogprobsTopToken {
+		return ChatCompletionResponseChoiceLogprobsTopToken{Token: text, Logprob: math.Log(probability), Bytes: nil}
+	}
+
+	tests := []struct {
+		name         string
+		tokens       []string
+		alternatives []ChatCompletionResponseChoiceLogprobsTopToken
+		expected     map[string]float64
+	}{
+		{
+			// The alternatives include the closing characters of the JSON object
+			"ClosingCharacters",
+			[]string{`{"`, `label`, `":"`, `positive"}`},
+			[]ChatCompletionResponseChoiceLogprobsTopToken{
+				token(`positive"}`, 0.6), token(`negative"}`, 0.3), token(`neutral" }`, 0.1),
+			},
+			map[string]float64{"positive": 0.6, "negative": 0.3, "neutral": 0.1},
+		},
+		{
+			// The label starts in the same token as the opening quote
+			"Prefix",
+			[]string{`{"label":`, ` "pos`, `itive"}`},
+			[]ChatCompletionResponseChoiceLogprobsTopToken{token(` "pos`, 0.5), token(` "neg`, 0.25), token(` "ne`, 0.25)},
+			map[string]float64{"positive": 0.5, "negative": 0.375, "neutral": 0.125},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logprobs := ChatCompletionResponseChoiceLogprobs{
+				Content: []ChatCompletionResponseChoiceLogprobsToken{},
+				Refusal: nil,
+			}
+			// The chosen token is the first alternative
+			for _, text := range tt.tokens {
+				chosen := ChatCompletionResponseChoiceLogprobsToken{Token: text, Logprob: 0, Bytes: nil, TopLogprobs: nil}
+				if text == tt.alternatives[0].Token {
+					chosen.Logprob = tt.alternatives[0].Logprob
+					chosen.TopLogprobs = tt.alternatives
+				}
+				logprobs.Content = append(logprobs.Content, chosen)
+			}
+
+			probabilities, ok := classifyProbabilities(logprobs, "positive", []string{"positive", "negative", "neutral"})
+			assert.True(t, ok)
+			for label, expected := range tt.expected {
+				assert.True(t, math.Abs(probabilities[label]-expected) < 1e-9)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/zachczx/openroutergo/internal/optional"
@@ -22,6 +23,7 @@ type Client struct {
 	refererURL   optional.String
 	refererTitle optional.String
 	httpClient   *http.Client
+	catalog      *modelCatalog
 }
 
 // clientBuilder is a chainable builder for the OpenRouter client.
@@ -38,6 +40,7 @@ func NewClient() *clientBuilder {
 			refererURL:   optional.String{IsSet: false},
 			refererTitle: optional.String{IsSet: false},
 			httpClient:   &http.Client{Timeout: defaultTimeout},
+			catalog:      &modelCatalog{mu: sync.Mutex{}, models: nil, fetchedAt: time.Time{}},
 		},
 	}
 }
@@ -0,0 +1,115 @@
+// Command openroutergo-mcp is an MCP server that lets MCP hosts, like IDE agents,
+// delegate questions to any model available on OpenRouter.
+//
+// It communicates over stdio and exposes the chat and list_models tools. The API key
+// is read from the OPENROUTER_API_KEY environment variable.
+//
+// Example configuration for an MCP host:
+//
+//	{
+//	  "mcpServers": {
+//	    "openrouter": {
+//	      "command": "openroutergo-mcp",
+//	      "args": ["-models", "openai/gpt-4o-mini,google/gemini-2.0-flash-001", "-budget", "5"],
+//	      "env": { "OPENROUTER_API_KEY": "sk-or-..." }
+//	    }
+//	  }
+//	}
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/zachczx/openroutergo"
+	"github.com/zachczx/openroutergo/mcp"
+)
+
+const version = "1.0.0"
+
+func main() {
+	if err := run(); err != nil {
+		// stdout is used by the protocol, errors go to stderr
+		fmt.Fprintln(os.Stderr, "openroutergo-mcp:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	models := flag.String("models", "", "comma separated list of the allowed models, all models are allowed if empty")
+	defaultModel := flag.String("default-model", "", "model used when the host doesn't choose one")
+	maxPromptPrice := flag.Float64("max-prompt-price", 0, "maximum price in USD per million prompt tokens, 0 means no limit")
+	maxCompletionPrice := flag.Float64(
+		"max-completion-price", 0, "maximum price in USD per million completion tokens, 0 means no limit",
+	)
+	maxTokens := flag.Int("max-tokens", 0, "maximum number of tokens generated per request, 0 means no limit")
+	budget := flag.Float64("budget", 0, "total amount in USD that can be spent, 0 means no limit")
+	baseURL := flag.String("base-url", "", "custom base URL for the OpenRouter API")
+	flag.Parse()
+
+	apiKey := os.Getenv("OPENROUTER_API_KEY")
+	if apiKey == "" {
+		return fmt.Errorf("the OPENROUTER_API_KEY environment variable is required")
+	}
+
+	builder := openroutergo.NewClient().WithAPIKey(apiKey).WithRefererTitle("openroutergo-mcp")
+	if *baseURL != "" {
+		builder.WithBaseURL(*baseURL)
+	}
+	client, err := builder.Create()
+	if err != nil {
+		return err
+	}
+
+	chatTools := mcp.NewChatTools(client)
+	for _, model := range strings.Split(*models, ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			chatTools.WithAllowedModels(openroutergo.ModelID(model))
+		}
+	}
+	if *defaultModel != "" {
+		chatTools.WithDefaultModel(openroutergo.ModelID(*defaultModel))
+	}
+	if *maxPromptPrice > 0 || *maxCompletionPrice > 0 {
+		chatTools.WithMaxPrice(priceOrUnlimited(*maxPromptPrice), priceOrUnlimited(*maxCompletionPrice))
+	}
+	if *maxTokens > 0 {
+		chatTools.WithMaxTokens(*maxTokens)
+	}
+	if *budget > 0 {
+		chatTools.WithBudget(*budget)
+	}
+
+	tools, err := chatTools.Create()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = mcp.NewServer("openroutergo-mcp", version).
+		WithTools(tools...).
+		WithInstructions("Use the chat tool to ask other AI models, use list_models to discover them.").
+		Create().
+		ServeStdio(ctx)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// priceOrUnlimited returns a price high enough to not limit any model when the price
+// is not set.
+func priceOrUnlimited(price float64) float64 {
+	if price <= 0 {
+		return 1_000_000
+	}
+	return price
+}
@@ -0,0 +1,297 @@
+package openroutergo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/orsinium-labs/enum"
+	"github.com/zachczx/openroutergo/internal/optional"
+	"github.com/zachczx/openroutergo/tokenizer"
+)
+
+const (
+	// defaultReservedCompletionTokens is the number of tokens of the context window
+	// reserved for the completion when WithMaxTokens is not used.
+	defaultReservedCompletionTokens = 1024
+	// defaultKeepLastMessages is the number of messages kept by TruncationKeepLast.
+	defaultKeepLastMessages = 20
+	// truncatedToolResult replaces the content of the tool results removed by
+	// TruncationToolResultsFirst.
+	truncatedToolResult = "[tool result removed to fit the context window]"
+)
+
+// TruncationStrategy is an enum for how the messages of a conversation are truncated
+// when they don't fit in the context window of the model, see WithTruncation.
+type TruncationStrategy enum.Member[string]
+
+// MarshalJSON implements the json.Marshaler interface for TruncationStrategy.
+func (ts TruncationStrategy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ts.Value)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for TruncationStrategy.
+func (ts *TruncationStrategy) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	*ts = TruncationStrategy{Value: value}
+	return nil
+}
+
+var (
+	// TruncationOff sends all the messages, the request fails if they don't fit.
+	TruncationOff = TruncationStrategy{"off"}
+	// TruncationSlidingWindow drops the oldest messages until the conversation fits.
+	TruncationSlidingWindow = TruncationStrategy{"sliding_window"}
+	// TruncationKeepLast keeps only the last messages (see WithKeepLastMessages) and
+	// then drops the oldest ones until the conversation fits.
+	TruncationKeepLast = TruncationStrategy{"keep_last"}
+	// TruncationToolResultsFirst replaces the content of the oldest tool results with a
+	// placeholder, and if that's not enough, drops the oldest messages until the
+	// conversation fits.
+	TruncationToolResultsFirst = TruncationStrategy{"tool_results_first"}
+
+	// TruncationStrategies contains all the truncation strategies.
+	TruncationStrategies = enum.New(
+		TruncationOff, TruncationSlidingWindow, TruncationKeepLast, TruncationToolResultsFirst,
+	)
+)
+
+// WithTruncation sets how the messages are truncated when the conversation doesn't fit
+// in the context window of the model.
+//
+// The truncation only affects the messages sent in the request, the conversation of
+// the builder keeps all of them. With every strategy:
+//
+//   - The system and developer messages are always kept.
+//   - The last message (or the last assistant message and its tool results) is always
+//     kept, even if it doesn't fit.
+//   - An assistant message with tool calls and its tool results are dropped together,
+//     so a tool message is never sent without the assistant message that requested it.
+//
+// The context length is taken from the models catalog (see the ListModels method) or
+// from WithContextLength, if it's unknown the messages are not truncated, and Execute
+// returns an error if the catalog can't be requested. The number of tokens is
+// estimated like EstimatePromptTokens does, see WithReservedCompletionTokens to leave
+// some margin.
+//
+//   - Default: TruncationOff
+func (b *chatCompletionBuilder) WithTruncation(strategy TruncationStrategy) *chatCompletionBuilder {
+	b.truncation = strategy
+	return b
+}
+
+// WithContextLength sets the context length (in tokens) used to truncate the messages,
+// instead of the one of the models catalog. See WithTruncation.
+func (b *chatCompletionBuilder) WithContextLength(contextLength int) *chatCompletionBuilder {
+	b.contextLength = optional.Int{IsSet: true, Value: contextLength}
+	return b
+}
+
+// WithReservedCompletionTokens sets the number of tokens of the context window reserved
+// for the completion when truncating the messages. See WithTruncation.
+//
+//   - Default: the value of WithMaxTokens, or 1024 if it's not set
+func (b *chatCompletionBuilder) WithReservedCompletionTokens(tokens int) *chatCompletionBuilder {
+	b.reservedTokens = optional.Int{IsSet: true, Value: tokens}
+	return b
+}
+
+// WithKeepLastMessages sets the number of messages kept by TruncationKeepLast, without
+// counting the system and developer messages.
+//
+//   - Default: 20
+func (b *chatCompletionBuilder) WithKeepLastMessages(messages int) *chatCompletionBuilder {
+	b.keepLastMessages = messages
+	return b
+}
+
+// requestContextLength returns the context length of the model of the request, or false
+// if it's unknown because the model is not set or not in the catalog.
+//
+// Returns an error if the catalog can't be requested.
+func (b *chatCompletionBuilder) requestContextLength(ctx context.Context) (int, bool, error) {
+	if b.contextLength.IsSet {
+		return b.contextLength.Value, true, nil
+	}
+	if !b.model.IsSet {
+		return 0, false, nil
+	}
+
+	model, err := b.client.GetModel(ctx, ModelID(b.model.Value))
+	if errors.Is(err, ErrModelNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get the context length of %q: %w", b.model.Value, err)
+	}
+
+	contextLength := model.ContextLength
+	if limit := model.TopProvider.ContextLength; limit > 0 && (contextLength == 0 || limit < contextLength) {
+		contextLength = limit
+	}
+	return contextLength, contextLength > 0, nil
+}
+
+// truncatedMessages returns the messages that fit in the context window using the
+// truncation strategy of the builder, and the number of messages dropped or trimmed.
+func (b *chatCompletionBuilder) truncatedMessages(
+	ctx context.Context, tools []chatCompletionToolFunction,
+) ([]ChatCompletionMessage, int, error) {
+	if b.truncation == TruncationOff || b.truncation.Value == "" {
+		return b.messages, 0, nil
+	}
+
+	contextLength, ok, err := b.requestContextLength(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !ok {
+		return b.messages, 0, nil
+	}
+
+	reserved := defaultReservedCompletionTokens
+	if b.reservedTokens.IsSet {
+		reserved = b.reservedTokens.Value
+	} else if b.maxTokens.IsSet {
+		reserved = b.maxTokens.Value
+	}
+
+	tk := b.tokenizer()
+	budget := contextLength - reserved - replyTokensOverhead - estimateToolsTokens(tk, tools)
+	messages, truncated := truncateMessages(tk, b.messages, b.truncation, budget, b.keepLastMessages)
+	return messages, truncated, nil
+}
+
+// messageGroup is a set of consecutive messages that must be kept or dropped together.
+type messageGroup struct {
+	messages []ChatCompletionMessage
+	pinned   bool
+	// The number of tool results of the group replaced with a placeholder.
+	trimmed int
+}
+
+// groupMessages groups the assistant messages with tool calls with their tool results,
+// the system and developer messages and the last group are pinned.
+//
+// A tool message is grouped with the last assistant message with tool calls before it,
+// and the messages between them, even if its ID doesn't match a tool call, so it's
+// never sent without the message that requested it.
+func groupMessages(messages []ChatCompletionMessage) []messageGroup {
+	groups := []messageGroup{}
+	for _, message := range messages {
+		if message.Role == RoleTool && len(groups) > 0 {
+			start := len(groups) - 1
+			for i := start; i >= 0; i-- {
+				if first := groups[i].messages[0]; first.Role == RoleAssistant && first.HasToolCalls() {
+					start = i
+					break
+				}
+			}
+
+			merged := groups[start]
+			for _, group := range groups[start+1:] {
+				merged.messages = append(merged.messages, group.messages...)
+				merged.pinned = merged.pinned || group.pinned
+			}
+			merged.messages = append(merged.messages, message)
+			groups = append(groups[:start], merged)
+			continue
+		}
+
+		groups = append(groups, messageGroup{
+			messages: []ChatCompletionMessage{message},
+			pinned:   message.Role == RoleSystem || message.Role == RoleDeveloper,
+			trimmed:  0,
+		})
+	}
+
+	if len(groups) > 0 {
+		groups[len(groups)-1].pinned = true
+	}
+	return groups
+}
+
+// truncateMessages returns the messages that fit in the token budget using the given
+// strategy, and the number of messages dropped or trimmed.
+func truncateMessages(
+	tk tokenizer.Tokenizer, messages []ChatCompletionMessage, strategy TruncationStrategy, budget int, keepLast int,
+) ([]ChatCompletionMessage, int) {
+	groups := groupMessages(messages)
+	truncated := 0
+
+	tokens := 0
+	for _, group := range groups {
+		tokens += estimateMessagesTokens(tk, group.messages)
+	}
+
+	// dropOldest drops the oldest group that is not pinned, returns false if there is none
+	dropOldest := func() bool {
+		for i, group := range groups {
+			if group.pinned {
+				continue
+			}
+			tokens -= estimateMessagesTokens(tk, group.messages)
+			truncated += len(group.messages) - group.trimmed
+			groups = append(groups[:i], groups[i+1:]...)
+			return true
+		}
+		return false
+	}
+
+	switch strategy {
+	case TruncationKeepLast:
+		if keepLast <= 0 {
+			keepLast = defaultKeepLastMessages
+		}
+		for {
+			count := 0
+			for _, group := range groups {
+				if group.messages[0].Role != RoleSystem && group.messages[0].Role != RoleDeveloper {
+					count += len(group.messages)
+				}
+			}
+			if count <= keepLast || !dropOldest() {
+				break
+			}
+		}
+	case TruncationToolResultsFirst:
+		for i := 0; i < len(groups) && tokens > budget; i++ {
+			if groups[i].pinned {
+				continue
+			}
+			for j, message := range groups[i].messages {
+				if message.Role != RoleTool || message.Content == truncatedToolResult {
+					continue
+				}
+				trimmed := message
+				trimmed.Content = truncatedToolResult
+				tokens += estimateMessageTokens(tk, trimmed) - estimateMessageTokens(tk, message)
+				groups[i].messages = append([]ChatCompletionMessage{}, groups[i].messages...)
+				groups[i].messages[j] = trimmed
+				groups[i].trimmed++
+				truncated++
+			}
+		}
+	}
+
+	for tokens > budget {
+		if !dropOldest() {
+			break
+		}
+	}
+
+	if truncated == 0 {
+		return messages, 0
+	}
+
+	result := make([]ChatCompletionMessage, 0, len(messages))
+	for _, group := range groups {
+		result = append(result, group.messages...)
+	}
+	return result, truncated
+}
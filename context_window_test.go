@@ -0,0 +1,221 @@
+package openroutergo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+	"github.com/zachczx/openroutergo/tokenizer"
+)
+
+func toolCallMessage(ids ...string) ChatCompletionMessage {
+	toolCalls := []ChatCompletionMessageToolCall{}
+	for _, id := range ids {
+		toolCalls = append(toolCalls, ChatCompletionMessageToolCall{
+			ID:       id,
+			Type:     "function",
+			Function: ChatCompletionMessageToolCallFunction{Name: "search", Arguments: `{"query":"x"}`},
+		})
+	}
+	return ChatCompletionMessage{Role: RoleAssistant, Content: "", ToolCalls: toolCalls}
+}
+
+func toolResultMessage(id string) ChatCompletionMessage {
+	content := "The result of " + id + strings.Repeat(" with a lot of text", 20)
+	return ChatCompletionMessage{Role: RoleTool, Content: content, ToolCallID: id}
+}
+
+func TestTruncateMessages(t *testing.T) {
+	tk := tokenizer.Heuristic{CharsPerToken: 4}
+	conversation := []ChatCompletionMessage{
+		{Role: RoleSystem, Content: "You are helpful"},
+		{Role: RoleUser, Content: "First question"},
+		toolCallMessage("call_1", "call_2"),
+		toolResultMessage("call_1"),
+		toolResultMessage("call_2"),
+		{Role: RoleAssistant, Content: "First answer"},
+		{Role: RoleUser, Content: "Second question"},
+		toolCallMessage("call_3"),
+		toolResultMessage("call_3"),
+	}
+	// The budget to keep the messages at the given positions
+	tokensOf := func(positions ...int) int {
+		tokens := 0
+		for _, i := range positions {
+			tokens += estimateMessageTokens(tk, conversation[i])
+		}
+		return tokens
+	}
+	trimmedTokens := func(ids ...string) int {
+		tokens := 0
+		for _, id := range ids {
+			trimmed := toolResultMessage(id)
+			trimmed.Content = truncatedToolResult
+			tokens += estimateMessageTokens(tk, trimmed)
+		}
+		return tokens
+	}
+
+	tests := []struct {
+		name      string
+		strategy  TruncationStrategy
+		budget    int
+		keepLast  int
+		roles     string
+		truncated int
+	}{
+		{"Fits", TruncationSlidingWindow, tokensOf(0, 1, 2, 3, 4, 5, 6, 7, 8), 0, "system,user,assistant,tool,tool,assistant,user,assistant,tool", 0},
+		{"SlidingWindow", TruncationSlidingWindow, tokensOf(0, 6, 7, 8), 0, "system,user,assistant,tool", 5},
+		// The assistant message with tool calls is dropped with its tool results
+		{"SlidingWindowToolGroup", TruncationSlidingWindow, tokensOf(0, 2, 5, 6, 7, 8), 0, "system,assistant,user,assistant,tool", 4},
+		// The system message and the last group are kept even if they don't fit
+		{"SlidingWindowPinned", TruncationSlidingWindow, 0, 0, "system,assistant,tool", 6},
+		{"KeepLast", TruncationKeepLast, 1000, 3, "system,user,assistant,tool", 5},
+		// The groups are not split to keep exactly the number of messages
+		{"KeepLastGroup", TruncationKeepLast, 1000, 2, "system,assistant,tool", 6},
+		{
+			"ToolResultsFirst",
+			TruncationToolResultsFirst,
+			tokensOf(0, 1, 2, 5, 6, 7, 8) + trimmedTokens("call_1", "call_2"),
+			0,
+			"system,user,assistant,tool,tool,assistant,user,assistant,tool",
+			2,
+		},
+		{
+			// The trimmed tool results are not counted again when their group is dropped
+			"ToolResultsFirstThenDrop",
+			TruncationToolResultsFirst,
+			tokensOf(0, 5, 6, 7, 8),
+			0,
+			"system,assistant,user,assistant,tool",
+			4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, truncated := truncateMessages(tk, conversation, tt.strategy, tt.budget, tt.keepLast)
+			assert.Equal(t, tt.roles, roles(messages))
+			assert.Equal(t, tt.truncated, truncated)
+			assertNoOrphanToolMessages(t, messages)
+
+			// The last tool result is never trimmed
+			assert.Equal(t, conversation[8].Content, messages[len(messages)-1].Content)
+		})
+	}
+}
+
+func TestTruncateMessagesToolResultsFirstContent(t *testing.T) {
+	tk := tokenizer.Heuristic{CharsPerToken: 4}
+	messages := []ChatCompletionMessage{
+		toolCallMessage("call_1"),
+		toolResultMessage("call_1"),
+		{Role: RoleUser, Content: "Question"},
+	}
+	original := messages[1].Content
+
+	// The trimmed tool result is counted once when its group is dropped
+	truncated, count := truncateMessages(tk, messages, TruncationToolResultsFirst, 0, 0)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, "user", roles(truncated))
+
+	budget := estimateMessagesTokens(tk, messages) - 1
+	truncated, count = truncateMessages(tk, messages, TruncationToolResultsFirst, budget, 0)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, truncatedToolResult, truncated[1].Content)
+
+	// The conversation is not modified
+	assert.Equal(t, original, messages[1].Content)
+}
+
+func TestTruncateMessagesNeverOrphansToolMessages(t *testing.T) {
+	tk := tokenizer.Heuristic{CharsPerToken: 4}
+	tests := []struct {
+		name     string
+		messages []ChatCompletionMessage
+	}{
+		{
+			"MismatchedID",
+			[]ChatCompletionMessage{
+				toolCallMessage("call_1"),
+				toolResultMessage("call_1"),
+				toolResultMessage("call_other"),
+				{Role: RoleUser, Content: "Question"},
+			},
+		},
+		{
+			"ToolAfterUser",
+			[]ChatCompletionMessage{
+				toolCallMessage("call_1"),
+				{Role: RoleUser, Content: "Interruption"},
+				toolResultMessage("call_1"),
+				{Role: RoleUser, Content: "Question"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, strategy := range []TruncationStrategy{TruncationSlidingWindow, TruncationKeepLast, TruncationToolResultsFirst} {
+			t.Run(tt.name+"/"+strategy.Value, func(t *testing.T) {
+				for budget := 0; budget <= estimateMessagesTokens(tk, tt.messages); budget++ {
+					messages, _ := truncateMessages(tk, tt.messages, strategy, budget, 1)
+					assertNoOrphanToolMessages(t, messages)
+				}
+			})
+		}
+	}
+}
+
+// assertNoOrphanToolMessages checks that every tool message follows an assistant
+// message with tool calls, directly or after other messages of the same turn.
+func assertNoOrphanToolMessages(t *testing.T, messages []ChatCompletionMessage) {
+	t.Helper()
+	requested := false
+	for _, message := range messages {
+		switch {
+		case message.Role == RoleAssistant:
+			requested = message.HasToolCalls()
+		case message.Role == RoleTool:
+			assert.True(t, requested)
+		}
+	}
+}
+
+func TestExecuteTruncationContextLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		models   string
+		messages int
+		err      bool
+	}{
+		{"KnownModel", `{"data":[{"id":"openai/gpt-4o","context_length":1100}]}`, 1, false},
+		{"UnknownModel", `{"data":[]}`, 3, false},
+		{"CatalogError", `not json`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeOpenRouter(t, textResponse("Hi"))
+			server.models = tt.models
+
+			_, _, err := server.client.NewChatCompletion().
+				WithModel("openai/gpt-4o").
+				WithTruncation(TruncationSlidingWindow).
+				WithUserMessage(strings.Repeat("old question ", 100)).
+				WithAssistantMessage(strings.Repeat("old answer ", 100)).
+				WithUserMessage("Hello").
+				Execute()
+
+			if tt.err {
+				assert.NotNil(t, err)
+				assert.False(t, errors.Is(err, ErrModelNotFound))
+				assert.Equal(t, 0, server.requestCount())
+				return
+			}
+			assert.NoError(t, err)
+			messages, _ := server.request(0)["messages"].([]any)
+			assert.Equal(t, tt.messages, len(messages))
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package openroutergo
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// conversationIDRegex matches the valid conversation IDs, they are used as file names
+// by FileConversationStore so they are restricted to safe characters.
+var conversationIDRegex = regexp.MustCompile(`^[a-zA-Z0-9_-][a-zA-Z0-9._-]{0,127}$`)
+
+// ConversationStore persists conversations, the state of a chat completion builder, so
+// they can be continued between requests, see the Session type.
+//
+// Conversation IDs must be 1 to 128 characters long and contain only letters, numbers,
+// dots, underscores and dashes, without a leading dot.
+type ConversationStore interface {
+	// Load returns the conversation with the given ID or ErrConversationNotFound.
+	Load(ctx context.Context, id string) (ChatCompletionSnapshot, error)
+	// Save creates or replaces the conversation with the given ID.
+	Save(ctx context.Context, id string, snapshot ChatCompletionSnapshot) error
+	// Append adds messages to the existing conversation with the given ID or returns
+	// ErrConversationNotFound.
+	Append(ctx context.Context, id string, messages ...ChatCompletionMessage) error
+	// List returns the IDs of all the conversations sorted alphabetically.
+	List(ctx context.Context) ([]string, error)
+	// Delete removes the conversation with the given ID, it's not an error if it
+	// doesn't exist.
+	Delete(ctx context.Context, id string) error
+}
+
+// validateConversationID returns ErrInvalidConversationID if the ID is not valid.
+func validateConversationID(id string) error {
+	if !conversationIDRegex.MatchString(id) {
+		return fmt.Errorf("%w: %q", ErrInvalidConversationID, id)
+	}
+	return nil
+}
+
+// MemoryConversationStore is a ConversationStore that keeps the conversations in memory,
+// useful for tests and single process applications. It's safe for concurrent use.
+type MemoryConversationStore struct {
+	mu            sync.RWMutex
+	conversations map[string]ChatCompletionSnapshot
+}
+
+// NewMemoryConversationStore creates a new empty in-memory conversation store.
+func NewMemoryConversationStore() *MemoryConversationStore {
+	return &MemoryConversationStore{
+		mu:            sync.RWMutex{},
+		conversations: map[string]ChatCompletionSnapshot{},
+	}
+}
+
+// Load implements the ConversationStore interface.
+func (s *MemoryConversationStore) Load(_ context.Context, id string) (ChatCompletionSnapshot, error) {
+	if err := validateConversationID(id); err != nil {
+		return ChatCompletionSnapshot{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot, ok := s.conversations[id]
+	if !ok {
+		return ChatCompletionSnapshot{}, fmt.Errorf("%w: %q", ErrConversationNotFound, id)
+	}
+	snapshot.Messages = slices.Clone(snapshot.Messages)
+	return snapshot, nil
+}
+
+// Save implements the ConversationStore interface.
+func (s *MemoryConversationStore) Save(_ context.Context, id string, snapshot ChatCompletionSnapshot) error {
+	if err := validateConversationID(id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot.Messages = slices.Clone(snapshot.Messages)
+	s.conversations[id] = snapshot
+	return nil
+}
+
+// Append implements the ConversationStore interface.
+func (s *MemoryConversationStore) Append(_ context.Context, id string, messages ...ChatCompletionMessage) error {
+	if err := validateConversationID(id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot, ok := s.conversations[id]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrConversationNotFound, id)
+	}
+	snapshot.Messages = append(slices.Clone(snapshot.Messages), messages...)
+	s.conversations[id] = snapshot
+	return nil
+}
+
+// List implements the ConversationStore interface.
+func (s *MemoryConversationStore) List(_ context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.conversations))
+	for id := range s.conversations {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Delete implements the ConversationStore interface.
+func (s *MemoryConversationStore) Delete(_ context.Context, id string) error {
+	if err := validateConversationID(id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.conversations, id)
+	return nil
+}
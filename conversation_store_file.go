@@ -0,0 +1,341 @@
+package openroutergo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// fileConversationExt is the extension of the conversation files.
+	fileConversationExt = ".jsonl"
+	// fileConversationLockExt is the extension of the lock file of each conversation.
+	fileConversationLockExt = ".lock"
+	// fileConversationLockMinDelay is the first delay between the attempts to lock a
+	// conversation, it doubles on each attempt up to fileConversationLockMaxDelay.
+	fileConversationLockMinDelay = time.Millisecond
+	// fileConversationLockMaxDelay is the maximum delay between the attempts to lock a
+	// conversation.
+	fileConversationLockMaxDelay = 50 * time.Millisecond
+)
+
+// FileConversationStore is a ConversationStore that keeps each conversation in a JSONL
+// file inside a directory, named after the conversation ID.
+//
+// The first line of the file has the snapshot without the messages, and each of the
+// following lines has a message, so Append only adds lines to the end of the file. Save
+// writes a temporary file and renames it, so a conversation is never left half written.
+//
+// It's safe for concurrent use, including from several processes sharing the directory:
+// each conversation has a lock file next to it, <id>.lock, that is locked with flock on
+// Unix systems (on other systems only the access from the same store is synchronized),
+// so only the operations on the same conversation wait for each other. The lock files
+// are kept when a conversation is deleted, because removing a file that another process
+// may be waiting to lock is not safe. The methods stop waiting for the lock when the
+// context is done.
+type FileConversationStore struct {
+	mu    sync.Mutex
+	locks map[string]*fileConversationLock
+	dir   string
+}
+
+// fileConversationLock synchronizes the access to a conversation from the same store,
+// it's removed from the store when nobody is using it.
+type fileConversationLock struct {
+	mu   sync.RWMutex
+	refs int
+}
+
+// NewFileConversationStore creates a conversation store that uses the given directory,
+// it's created if it doesn't exist.
+func NewFileConversationStore(dir string) (*FileConversationStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create conversations directory: %w", err)
+	}
+
+	return &FileConversationStore{
+		mu:    sync.Mutex{},
+		locks: map[string]*fileConversationLock{},
+		dir:   dir,
+	}, nil
+}
+
+// path returns the path of the file of the conversation.
+func (s *FileConversationStore) path(id string) string {
+	return filepath.Join(s.dir, id+fileConversationExt)
+}
+
+// conversationLock returns the lock of the conversation in the store, release must be
+// called when it's not used anymore.
+func (s *FileConversationStore) conversationLock(id string) (*fileConversationLock, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.locks[id]
+	if !ok {
+		l = &fileConversationLock{mu: sync.RWMutex{}, refs: 0}
+		s.locks[id] = l
+	}
+	l.refs++
+
+	return l, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		l.refs--
+		if l.refs == 0 {
+			delete(s.locks, id)
+		}
+	}
+}
+
+// lock locks the conversation for reading or writing and returns the function to
+// unlock it.
+//
+// The lock is polled instead of waited for, so it stops waiting when the context is done.
+func (s *FileConversationStore) lock(ctx context.Context, id string, exclusive bool) (func(), error) {
+	l, release := s.conversationLock(id)
+	tryLockMutex, unlockMutex := l.mu.TryRLock, l.mu.RUnlock
+	if exclusive {
+		tryLockMutex, unlockMutex = l.mu.TryLock, l.mu.Unlock
+	}
+
+	file, err := os.OpenFile(filepath.Join(s.dir, id+fileConversationLockExt), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	delay := fileConversationLockMinDelay
+	for {
+		if tryLockMutex() {
+			locked, err := tryLockFile(file, exclusive)
+			if err != nil {
+				unlockMutex()
+				file.Close()
+				release()
+				return nil, fmt.Errorf("failed to lock conversation: %w", err)
+			}
+			if locked {
+				return func() {
+					_ = unlockFile(file)
+					file.Close()
+					unlockMutex()
+					release()
+				}, nil
+			}
+			unlockMutex()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			file.Close()
+			release()
+			return nil, fmt.Errorf("failed to lock conversation: %w", ctx.Err())
+		case <-timer.C:
+		}
+		delay = min(delay*2, fileConversationLockMaxDelay)
+	}
+}
+
+// Load implements the ConversationStore interface.
+func (s *FileConversationStore) Load(ctx context.Context, id string) (ChatCompletionSnapshot, error) {
+	if err := validateConversationID(id); err != nil {
+		return ChatCompletionSnapshot{}, err
+	}
+
+	unlock, err := s.lock(ctx, id, false)
+	if err != nil {
+		return ChatCompletionSnapshot{}, err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ChatCompletionSnapshot{}, fmt.Errorf("%w: %q", ErrConversationNotFound, id)
+	}
+	if err != nil {
+		return ChatCompletionSnapshot{}, fmt.Errorf("failed to read conversation: %w", err)
+	}
+
+	return decodeConversationFile(data)
+}
+
+// decodeConversationFile decodes the content of a conversation file. A last line without
+// a new line is ignored, it's an append that didn't finish.
+func decodeConversationFile(data []byte) (ChatCompletionSnapshot, error) {
+	if end := bytes.LastIndexByte(data, '\n'); end != len(data)-1 {
+		data = data[:end+1]
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+
+	var snapshot ChatCompletionSnapshot
+	if !scanner.Scan() {
+		return ChatCompletionSnapshot{}, fmt.Errorf("%w: empty conversation file", ErrInvalidSnapshot)
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &snapshot); err != nil {
+		return ChatCompletionSnapshot{}, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+
+	snapshot.Messages = []ChatCompletionMessage{}
+	for scanner.Scan() {
+		var message ChatCompletionMessage
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			return ChatCompletionSnapshot{}, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+		}
+		snapshot.Messages = append(snapshot.Messages, message)
+	}
+	if err := scanner.Err(); err != nil {
+		return ChatCompletionSnapshot{}, fmt.Errorf("failed to read conversation: %w", err)
+	}
+
+	return snapshot, nil
+}
+
+// encodeConversationLines encodes each value as a JSON line.
+func encodeConversationLines[T any](buf *bytes.Buffer, values ...T) error {
+	for _, value := range values {
+		line, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return nil
+}
+
+// Save implements the ConversationStore interface.
+func (s *FileConversationStore) Save(ctx context.Context, id string, snapshot ChatCompletionSnapshot) error {
+	if err := validateConversationID(id); err != nil {
+		return err
+	}
+
+	header := snapshot
+	header.Messages = nil
+	buf := &bytes.Buffer{}
+	if err := encodeConversationLines(buf, header); err != nil {
+		return fmt.Errorf("failed to encode conversation: %w", err)
+	}
+	if err := encodeConversationLines(buf, snapshot.Messages...); err != nil {
+		return fmt.Errorf("failed to encode conversation: %w", err)
+	}
+
+	unlock, err := s.lock(ctx, id, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	temp, err := os.CreateTemp(s.dir, "."+id+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create conversation file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(buf.Bytes()); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write conversation: %w", err)
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write conversation: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write conversation: %w", err)
+	}
+	if err := os.Rename(temp.Name(), s.path(id)); err != nil {
+		return fmt.Errorf("failed to write conversation: %w", err)
+	}
+
+	return nil
+}
+
+// Append implements the ConversationStore interface.
+func (s *FileConversationStore) Append(ctx context.Context, id string, messages ...ChatCompletionMessage) error {
+	if err := validateConversationID(id); err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := encodeConversationLines(buf, messages...); err != nil {
+		return fmt.Errorf("failed to encode messages: %w", err)
+	}
+
+	unlock, err := s.lock(ctx, id, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file, err := os.OpenFile(s.path(id), os.O_WRONLY|os.O_APPEND, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %q", ErrConversationNotFound, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open conversation: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to append messages: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to append messages: %w", err)
+	}
+
+	return nil
+}
+
+// List implements the ConversationStore interface.
+//
+// It doesn't lock the conversations, the files are only created by renames so a listed
+// conversation is always complete.
+func (s *FileConversationStore) List(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+
+	ids := []string{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), fileConversationExt)
+		if !ok || entry.IsDir() || validateConversationID(id) != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Delete implements the ConversationStore interface.
+func (s *FileConversationStore) Delete(ctx context.Context, id string) error {
+	if err := validateConversationID(id); err != nil {
+		return err
+	}
+
+	unlock, err := s.lock(ctx, id, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete conversation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package openroutergo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func newTestConversationStores(t *testing.T) map[string]ConversationStore {
+	fileStore, err := NewFileConversationStore(t.TempDir())
+	assert.NoError(t, err)
+
+	return map[string]ConversationStore{
+		"Memory": NewMemoryConversationStore(),
+		"File":   fileStore,
+	}
+}
+
+func TestConversationStores(t *testing.T) {
+	ctx := context.Background()
+	model := "openai/gpt-4o"
+	snapshot := ChatCompletionSnapshot{
+		Version: ChatCompletionSnapshotVersion,
+		Model:   &model,
+		Messages: []ChatCompletionMessage{
+			{Role: RoleUser, Content: "Hello"},
+		},
+	}
+
+	for name, store := range newTestConversationStores(t) {
+		t.Run(name, func(t *testing.T) {
+			_, err := store.Load(ctx, "conversation")
+			assert.True(t, errors.Is(err, ErrConversationNotFound))
+			err = store.Append(ctx, "conversation", ChatCompletionMessage{Role: RoleUser, Content: "Hi"})
+			assert.True(t, errors.Is(err, ErrConversationNotFound))
+
+			assert.NoError(t, store.Save(ctx, "conversation", snapshot))
+			assert.NoError(t, store.Save(ctx, "another", snapshot))
+			assert.NoError(t, store.Append(ctx, "conversation", ChatCompletionMessage{Role: RoleAssistant, Content: "Hi"}))
+
+			loaded, err := store.Load(ctx, "conversation")
+			assert.NoError(t, err)
+			assert.Equal(t, model, *loaded.Model)
+			assert.Equal(t, "user,assistant", roles(loaded.Messages))
+			assert.Equal(t, "Hi", loaded.Messages[1].Content)
+
+			// The stored conversation is not modified through the loaded snapshot
+			loaded.Messages[0].Content = "Modified"
+			loaded, err = store.Load(ctx, "conversation")
+			assert.NoError(t, err)
+			assert.Equal(t, "Hello", loaded.Messages[0].Content)
+
+			ids, err := store.List(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, "another,conversation", strings.Join(ids, ","))
+
+			assert.NoError(t, store.Delete(ctx, "conversation"))
+			assert.NoError(t, store.Delete(ctx, "conversation"))
+			_, err = store.Load(ctx, "conversation")
+			assert.True(t, errors.Is(err, ErrConversationNotFound))
+		})
+	}
+}
+
+func TestConversationStoresInvalidID(t *testing.T) {
+	ctx := context.Background()
+	snapshot := ChatCompletionSnapshot{Version: ChatCompletionSnapshotVersion}
+
+	for name, store := range newTestConversationStores(t) {
+		for _, id := range []string{"", ".hidden", "../escape", "a/b", string(make([]byte, 129))} {
+			t.Run(name, func(t *testing.T) {
+				_, err := store.Load(ctx, id)
+				assert.True(t, errors.Is(err, ErrInvalidConversationID))
+				assert.True(t, errors.Is(store.Save(ctx, id, snapshot), ErrInvalidConversationID))
+				assert.True(t, errors.Is(store.Append(ctx, id), ErrInvalidConversationID))
+				assert.True(t, errors.Is(store.Delete(ctx, id), ErrInvalidConversationID))
+			})
+		}
+	}
+}
+
+func TestFileConversationStoreTornLastLine(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileConversationStore(dir)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	snapshot := ChatCompletionSnapshot{
+		Version:  ChatCompletionSnapshotVersion,
+		Messages: []ChatCompletionMessage{{Role: RoleUser, Content: "Hello"}},
+	}
+	assert.NoError(t, store.Save(ctx, "conversation", snapshot))
+
+	// An append that didn't finish leaves a last line without a new line
+	file, err := os.OpenFile(filepath.Join(dir, "conversation.jsonl"), os.O_WRONLY|os.O_APPEND, 0)
+	assert.NoError(t, err)
+	_, err = file.WriteString(`{"role":"assistant","content":"Hi the`)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	loaded, err := store.Load(ctx, "conversation")
+	assert.NoError(t, err)
+	assert.Equal(t, "user", roles(loaded.Messages))
+
+	// A complete line that is not valid is an error
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.jsonl"), []byte("{}\nnot json\n"), 0o644))
+	_, err = store.Load(ctx, "invalid")
+	assert.True(t, errors.Is(err, ErrInvalidSnapshot))
+}
+
+func TestFileConversationStoreConcurrentAppends(t *testing.T) {
+	store, err := NewFileConversationStore(t.TempDir())
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	ids := []string{"first", "second"}
+	for _, id := range ids {
+		assert.NoError(t, store.Save(ctx, id, ChatCompletionSnapshot{Version: ChatCompletionSnapshotVersion}))
+	}
+
+	wg := sync.WaitGroup{}
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, store.Append(ctx, ids[i%len(ids)], ChatCompletionMessage{Role: RoleUser, Content: "Hi"}))
+		}()
+	}
+	wg.Wait()
+
+	for _, id := range ids {
+		loaded, err := store.Load(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, 10, len(loaded.Messages))
+	}
+
+	// The locks of the conversations are removed when they are not used
+	store.mu.Lock()
+	assert.Equal(t, 0, len(store.locks))
+	store.mu.Unlock()
+}
@@ -0,0 +1,43 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly || illumos
+
+package openroutergo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func TestFileConversationStoreLockContext(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileConversationStore(dir)
+	assert.NoError(t, err)
+
+	// Another process holding the lock is simulated with another open file description
+	file, err := os.OpenFile(filepath.Join(dir, "conversation"+fileConversationLockExt), os.O_CREATE|os.O_RDWR, 0o644)
+	assert.NoError(t, err)
+	defer file.Close()
+	assert.NoError(t, syscall.Flock(int(file.Fd()), syscall.LOCK_EX))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = store.Load(ctx, "conversation")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// The other conversations are not locked
+	assert.NoError(t, store.Save(context.Background(), "other", ChatCompletionSnapshot{Version: ChatCompletionSnapshotVersion}))
+	ids, err := store.List(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "other", strings.Join(ids, ","))
+
+	// The store can be used again once the lock is released
+	assert.NoError(t, syscall.Flock(int(file.Fd()), syscall.LOCK_UN))
+	assert.NoError(t, store.Save(context.Background(), "conversation", ChatCompletionSnapshot{Version: ChatCompletionSnapshotVersion}))
+}
@@ -0,0 +1,102 @@
+package openroutergo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/orsinium-labs/enum"
+)
+
+// EmulationMode is an enum for when the library emulates a feature with prompting for
+// the models that don't support it natively.
+type EmulationMode enum.Member[string]
+
+// MarshalJSON implements the json.Marshaler interface for EmulationMode.
+func (em EmulationMode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(em.Value)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for EmulationMode.
+func (em *EmulationMode) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	*em = EmulationMode{Value: value}
+	return nil
+}
+
+var (
+	// EmulationOff never emulates the feature, the request is sent as it is.
+	EmulationOff = EmulationMode{"off"}
+	// EmulationAuto emulates the feature when the models catalog says that the model or
+	// any of the fallback models doesn't support it. The models that are not in the
+	// catalog are ignored, and the request fails if the catalog can't be fetched.
+	EmulationAuto = EmulationMode{"auto"}
+	// EmulationAlways always emulates the feature, even for the models that support it.
+	EmulationAlways = EmulationMode{"always"}
+
+	// EmulationModes contains all the emulation modes.
+	EmulationModes = enum.New(EmulationOff, EmulationAuto, EmulationAlways)
+)
+
+// shouldEmulate returns true if a feature that depends on the given request parameter
+// must be emulated for the models of the request.
+//
+// In auto mode the models catalog is used, see the ListModels method. The feature is
+// emulated if any of the models doesn't support it, because the request can be served
+// by any of them. The models that are not in the catalog are ignored.
+func (b *chatCompletionBuilder) shouldEmulate(ctx context.Context, mode EmulationMode, parameter string) (bool, error) {
+	switch mode {
+	case EmulationAlways:
+		return true, nil
+	case EmulationAuto:
+		for _, id := range b.requestModels() {
+			model, err := b.client.GetModel(ctx, id)
+			if errors.Is(err, ErrModelNotFound) {
+				continue
+			}
+			if err != nil {
+				return false, fmt.Errorf("failed to check if %q supports %s: %w", id, parameter, err)
+			}
+			if !model.SupportsParameter(parameter) {
+				return true, nil
+			}
+		}
+		return false, nil
+	default:
+		return false, nil
+	}
+}
+
+// requestModels returns the model and the fallback models of the request, it's empty
+// if the default model of the account is used.
+func (b *chatCompletionBuilder) requestModels() []ModelID {
+	models := []ModelID{}
+	if b.model.IsSet {
+		models = append(models, ModelID(b.model.Value))
+	}
+	for _, fallbackModel := range b.fallbackModels {
+		models = append(models, ModelID(fallbackModel))
+	}
+	return models
+}
+
+// withSystemInstructions returns a copy of the messages with the instructions appended
+// to the first system message, or a new system message with the instructions at the
+// beginning if there is none, because some models only accept one system message.
+func withSystemInstructions(messages []ChatCompletionMessage, instructions string) []ChatCompletionMessage {
+	result := make([]ChatCompletionMessage, 0, len(messages)+1)
+	if len(messages) > 0 && messages[0].Role == RoleSystem {
+		first := messages[0]
+		first.Content = first.Content + "\n\n" + instructions
+		result = append(result, first)
+		return append(result, messages[1:]...)
+	}
+
+	result = append(result, ChatCompletionMessage{Role: RoleSystem, Content: instructions})
+	return append(result, messages...)
+}
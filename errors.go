@@ -15,4 +15,75 @@ var (
 	// ErrAlreadyExecuting is returned when the user tries to execute an action while
 	// there is already an action in progress.
 	ErrAlreadyExecuting = errors.New("race condition: the client is currently executing an action")
+
+	// ErrInvalidModelID is returned when a model ID does not have a valid format.
+	ErrInvalidModelID = errors.New("invalid model ID")
+
+	// ErrInvalidToolParameters is returned when the parameters schema of a tool can't be
+	// generated from a Go type.
+	ErrInvalidToolParameters = errors.New("invalid tool parameters type")
+
+	// ErrInvalidToolArguments is returned when the arguments of a tool call made by the
+	// model can't be decoded or are not valid.
+	ErrInvalidToolArguments = errors.New("invalid tool call arguments")
+
+	// ErrToolNotFound is returned when the model calls a tool that can't be handled.
+	ErrToolNotFound = errors.New("tool not found")
+
+	// ErrToolMaxIterations is returned when the model keeps calling tools after the
+	// maximum number of iterations allowed.
+	ErrToolMaxIterations = errors.New("the model kept calling tools after the maximum number of iterations")
+
+	// ErrToolPanicked is returned when a tool panics while handling a tool call.
+	ErrToolPanicked = errors.New("the tool panicked")
+
+	// ErrToolTimeout is returned when a tool doesn't finish before its timeout, see the
+	// WithToolTimeout method.
+	ErrToolTimeout = errors.New("the tool timed out")
+
+	// ErrUnrepairableJSON is returned when a malformed JSON generated by the model can't
+	// be repaired.
+	ErrUnrepairableJSON = errors.New("the JSON can't be repaired")
+
+	// ErrToolCallRejected is returned when a tool call is rejected by its approval function.
+	ErrToolCallRejected = errors.New("the tool call was rejected")
+
+	// ErrToolApprovalPending is returned when the conversation is paused because a tool
+	// call is waiting for a human decision.
+	ErrToolApprovalPending = errors.New("tool calls are waiting for approval")
+
+	// ErrModelNotFound is returned when a model is not available on OpenRouter.
+	ErrModelNotFound = errors.New("model not found")
+
+	// ErrStrictSchema is returned when a JSON Schema can't be rewritten for strict mode.
+	ErrStrictSchema = errors.New("invalid strict mode schema")
+
+	// ErrInvalidStructuredOutputType is returned when the JSON Schema of a structured
+	// output can't be generated from a Go type.
+	ErrInvalidStructuredOutputType = errors.New("invalid structured output type")
+
+	// ErrInvalidStructuredOutput is returned when the content generated by the model
+	// can't be decoded into the structured output type or doesn't match its schema.
+	ErrInvalidStructuredOutput = errors.New("invalid structured output")
+
+	// ErrInvalidSnapshot is returned when a chat completion snapshot can't be restored.
+	ErrInvalidSnapshot = errors.New("invalid chat completion snapshot")
+
+	// ErrConversationNotFound is returned when a conversation doesn't exist in the store.
+	ErrConversationNotFound = errors.New("conversation not found")
+
+	// ErrInvalidConversationID is returned when a conversation ID has invalid characters.
+	ErrInvalidConversationID = errors.New("invalid conversation ID")
+
+	// ErrRequestFailed is returned when the OpenRouter API responds with an error, see
+	// APIError for the details.
+	ErrRequestFailed = errors.New("request failed")
+
+	// ErrClassifyLabelsInvalid is returned when the labels passed to Classify are empty,
+	// duplicated or contain an empty label.
+	ErrClassifyLabelsInvalid = errors.New("at least one unique and non-empty label is required")
+
+	// ErrClassifyNoLabel is returned when the model response does not match any of the
+	// labels passed to Classify.
+	ErrClassifyNoLabel = errors.New("the model response does not match any of the labels")
 )
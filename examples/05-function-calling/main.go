@@ -1,7 +1,7 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
 
@@ -21,10 +21,16 @@ const (
 	model  = "google/gemini-2.0-flash-exp:free"
 )
 
-func getWeather(city string) string {
+// getWeatherArgs are the arguments of the getWeather tool, the JSON Schema sent to
+// the model is generated from this struct.
+type getWeatherArgs struct {
+	City string `json:"city" jsonschema:"description=The name of the city"`
+}
+
+func getWeather(_ context.Context, args getWeatherArgs) (string, error) {
 	// This is a fake function that returns a string but you can
 	// do calculations, api calls, database queries, etc.
-	return "It's cold and -120 celsius degrees in " + city + " right now. Literally freezing."
+	return "It's cold and -120 celsius degrees in " + args.City + " right now. Literally freezing.", nil
 }
 
 func main() {
@@ -33,58 +39,39 @@ func main() {
 		log.Fatalf("Failed to create client: %v", err)
 	}
 
-	completion := client.
+	weatherTool, err := openroutergo.NewTool(
+		"getWeather",
+		"Get the weather of a city, use this every time the user asks for the weather",
+		getWeather,
+	)
+	if err != nil {
+		log.Fatalf("Failed to create tool: %v", err)
+	}
+
+	// The tools in the registry are sent to the model and their calls are
+	// handled automatically by RunWithTools
+	registry := openroutergo.NewToolRegistry(weatherTool)
+
+	// RunWithTools executes the completion, calls the tools requested by the model
+	// with the decoded arguments and sends the results back until the model
+	// returns the final response
+	_, result, err := client.
 		NewChatCompletion().
 		WithDebug(true).  // Enable debug mode to see the request and response in the console
 		WithModel(model). // Change the model if you want
-		WithTool(openroutergo.ChatCompletionTool{
-			Name:        "getWeather",
-			Description: "Get the weather of a city, use this every time the user asks for the weather",
-			Parameters: map[string]any{
-				// The parameters definition should be a JSON object
-				"type": "object",
-				"properties": map[string]any{
-					"city": map[string]any{
-						"type": "string",
-					},
-				},
-				"required": []string{"city"},
-			},
-		}).
+		WithToolRegistry(registry).
 		WithSystemMessage("You are a helpful assistant expert in geography.").
-		WithUserMessage("I want to know the weather in the capital of Brazil and a joke about it")
-
-	completion, resp, err := completion.Execute()
+		WithUserMessage("I want to know the weather in the capital of Brazil and a joke about it").
+		RunWithTools(context.Background())
 	if err != nil {
 		log.Fatalf("Failed to execute completion: %v", err)
 	}
 
-	if !resp.HasChoices() || !resp.Choices[0].Message.HasToolCalls() {
-		log.Fatalf("No tool calls returned")
-	}
-
-	toolCall := resp.Choices[0].Message.ToolCalls[0]
-	toolName := toolCall.Function.Name
-	if toolName != "getWeather" {
-		log.Fatalf("Unexpected tool name: %s", toolName)
-	}
-
-	toolCallArguments := toolCall.Function.Arguments
-	args := map[string]any{}
-	if err := json.Unmarshal([]byte(toolCallArguments), &args); err != nil {
-		log.Fatalf("Failed to unmarshal tool call arguments: %v", err)
-	}
-
-	// Call the function with the arguments provided by the model
-	weather := getWeather(args["city"].(string))
-
-	// Use the tool response to continue the conversation
-	_, resp, err = completion.
-		WithToolMessage(toolCall, weather).
-		Execute()
-	if err != nil {
-		log.Fatalf("Failed to execute completion: %v", err)
+	for _, step := range result.Steps {
+		for _, toolCall := range step.ToolCalls {
+			fmt.Printf("Called %s in %s\n", toolCall.ToolCall.Function.Name, toolCall.Duration)
+		}
 	}
 
-	fmt.Println("Response:", resp.Choices[0].Message.Content)
+	fmt.Println("Response:", result.Response.Choices[0].Message.Content)
 }
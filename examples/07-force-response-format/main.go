@@ -94,4 +94,26 @@ func main() {
 		myResponse.Capital,
 		myResponse.CuriousFact,
 	)
+
+	// ExecuteInto does all of the above for you, it generates the JSON Schema from
+	// the struct, sends it in strict mode, and validates and decodes the response.
+	type capitalResponse struct {
+		Country     string `json:"country"`
+		Capital     string `json:"capital"`
+		CuriousFact string `json:"curious_fact" jsonschema:"description=A curious fact about the capital"`
+	}
+
+	capital, _, err := openroutergo.ExecuteInto[capitalResponse](
+		completion.WithUserMessage("And what about Japan?"),
+	)
+	if err != nil {
+		log.Fatalf("Failed to execute completion: %v", err)
+	}
+
+	fmt.Printf(
+		"The capital of %s is %s and here's a curious fact: %s\n",
+		capital.Country,
+		capital.Capital,
+		capital.CuriousFact,
+	)
 }
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/zachczx/openroutergo"
+	"github.com/zachczx/openroutergo/mcp"
+)
+
+// This example demonstrates how to use the tools of a Model Context Protocol server
+// with any model that supports tools.
+//
+// The MCP server is started as a subprocess using the stdio transport, this one
+// requires Node.js. Servers reachable over HTTP can be used with the
+// WithStreamableHTTP method instead.
+//
+// You can copy this code modify the api key, model, and run it.
+
+const (
+	apiKey = "sk......."
+	model  = "google/gemini-2.0-flash-exp:free"
+)
+
+func main() {
+	ctx := context.Background()
+
+	client, err := openroutergo.NewClient().WithAPIKey(apiKey).Create()
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+
+	mcpClient, err := mcp.NewClient().
+		WithStdioCommand("npx", "-y", "@modelcontextprotocol/server-everything").
+		WithStderr(os.Stderr). // Show the logs of the server
+		Connect(ctx)
+	if err != nil {
+		log.Fatalf("Failed to connect to the MCP server: %v", err)
+	}
+	defer mcpClient.Close()
+
+	// The tools of the MCP server are converted into tools that call the server
+	tools, err := mcpClient.Tools(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list the MCP tools: %v", err)
+	}
+
+	_, result, err := client.
+		NewChatCompletion().
+		WithModel(model). // Change the model if you want
+		WithToolRegistry(openroutergo.NewToolRegistry(tools...)).
+		WithSystemMessage("You are a helpful assistant.").
+		WithUserMessage("Add 1234 and 5678 using the available tools").
+		RunWithTools(ctx)
+	if err != nil {
+		log.Fatalf("Failed to execute completion: %v", err)
+	}
+
+	for _, step := range result.Steps {
+		for _, toolCall := range step.ToolCalls {
+			fmt.Printf("Called %s with %s\n", toolCall.ToolCall.Function.Name, toolCall.ToolCall.Function.Arguments)
+		}
+	}
+
+	fmt.Println("Response:", result.Response.Choices[0].Message.Content)
+}
@@ -0,0 +1,16 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly || illumos)
+
+package openroutergo
+
+import "os"
+
+// tryLockFile does nothing on the systems without flock, the access is only
+// synchronized within the same process.
+func tryLockFile(_ *os.File, _ bool) (bool, error) {
+	return true, nil
+}
+
+// unlockFile does nothing on the systems without flock.
+func unlockFile(_ *os.File) error {
+	return nil
+}
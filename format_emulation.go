@@ -0,0 +1,101 @@
+package openroutergo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/zachczx/openroutergo/internal/jsonrepair"
+)
+
+// emulatedJSONObjectInstructions are added to the system prompt when the json_object
+// response format is emulated.
+const emulatedJSONObjectInstructions = `Respond only with a valid JSON object, without any other text before or after it and without markdown code fences.`
+
+// emulatedJSONSchemaInstructions are added to the system prompt when the json_schema
+// response format is emulated, %s is replaced with the JSON Schema.
+const emulatedJSONSchemaInstructions = `Respond only with a valid JSON object that matches the following JSON Schema, without any other text before or after it and without markdown code fences:
+
+<schema>
+%s
+</schema>`
+
+// WithResponseFormatEmulation sets when the response format is emulated with prompting,
+// so it can be used with the models that don't support it natively. Providers silently
+// ignore the response format of those models.
+//
+// When emulated, the response format is not sent in the request. Instead:
+//
+//   - The instructions to respond with a JSON object, and the JSON Schema when using
+//     json_schema, are added to the system prompt.
+//   - The JSON is extracted from the content of the response, removing markdown code
+//     fences and any text around it, and the ResponseFormatEmulated field of the
+//     response is set to true.
+//
+// Use EmulationAuto to only emulate the json_schema response format when the model or
+// any of the fallback models don't list "structured_outputs" in their supported
+// parameters, and json_object when they don't list "response_format", see the
+// ListModels method.
+//
+//   - Default: EmulationOff
+func (b *chatCompletionBuilder) WithResponseFormatEmulation(mode EmulationMode) *chatCompletionBuilder {
+	b.formatEmulation = mode
+	return b
+}
+
+// shouldEmulateResponseFormat returns true if the response format of the builder must
+// be emulated, only the json_object and json_schema formats can be emulated.
+func (b *chatCompletionBuilder) shouldEmulateResponseFormat(ctx context.Context) (bool, error) {
+	if !b.responseFormat.IsSet {
+		return false, nil
+	}
+
+	switch formatType, _ := b.responseFormat.Value["type"].(string); formatType {
+	case "json_object":
+		return b.shouldEmulate(ctx, b.formatEmulation, "response_format")
+	case "json_schema":
+		return b.shouldEmulate(ctx, b.formatEmulation, "structured_outputs")
+	default:
+		return false, nil
+	}
+}
+
+// emulatedResponseFormatInstructions returns the instructions added to the system prompt
+// for the given response format, see requestResponseFormat.
+func emulatedResponseFormatInstructions(responseFormat map[string]any) (string, error) {
+	jsonSchema, _ := responseFormat["json_schema"].(map[string]any)
+	schema, ok := jsonSchema["schema"]
+	if !ok {
+		return emulatedJSONObjectInstructions, nil
+	}
+
+	encoded, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode response format schema: %w", err)
+	}
+
+	return fmt.Sprintf(emulatedJSONSchemaInstructions, encoded), nil
+}
+
+// extractEmulatedResponseFormat keeps only the JSON of the content of the response
+// messages, the models usually wrap it in markdown code fences or add some text before
+// or after it.
+func extractEmulatedResponseFormat(response *ChatCompletionResponse) {
+	response.ResponseFormatEmulated = true
+
+	for i := range response.Choices {
+		message := &response.Choices[i].Message
+		content := strings.TrimSpace(message.Content)
+		if content == "" || json.Valid([]byte(content)) {
+			continue
+		}
+
+		// The text after the JSON value is ignored by decoding only the first value
+		var value json.RawMessage
+		decoder := json.NewDecoder(strings.NewReader(jsonrepair.Extract(content)))
+		if err := decoder.Decode(&value); err == nil {
+			message.Content = string(value)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package openroutergo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func TestEmulatedResponseFormatInstructions(t *testing.T) {
+	instructions, err := emulatedResponseFormatInstructions(map[string]any{"type": "json_object"})
+	assert.NoError(t, err)
+	assert.Equal(t, emulatedJSONObjectInstructions, instructions)
+
+	instructions, err = emulatedResponseFormatInstructions(map[string]any{
+		"type": "json_schema",
+		"json_schema": map[string]any{
+			"name": "capital",
+			"schema": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"city": map[string]any{"type": "string"}},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(instructions, "Respond only with a valid JSON object that matches"))
+	assert.True(t, strings.HasSuffix(instructions, "<schema>\n"+
+		"{\n"+
+		"  \"properties\": {\n"+
+		"    \"city\": {\n"+
+		"      \"type\": \"string\"\n"+
+		"    }\n"+
+		"  },\n"+
+		"  \"type\": \"object\"\n"+
+		"}\n"+
+		"</schema>"))
+}
+
+func TestExtractEmulatedResponseFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"Valid", `{"city":"Paris"}`, `{"city":"Paris"}`},
+		{"Spaces", "\n  {\"city\":\"Paris\"}  \n", "\n  {\"city\":\"Paris\"}  \n"},
+		{"Fenced", "```json\n{\"city\":\"Paris\"}\n```", `{"city":"Paris"}`},
+		{"FencedWithoutLanguage", "```\n[1, 2]\n```", `[1, 2]`},
+		{"Prose", "Here is the answer:\n{\"city\":\"Paris\"}\nLet me know if you need anything else.", `{"city":"Paris"}`},
+		{"ProseAndFence", "Sure!\n\n```json\n{\"city\": \"Paris\"}\n```\n\nDone.", `{"city": "Paris"}`},
+		{"NotJSON", "The capital is Paris.", "The capital is Paris."},
+		{"Incomplete", `Here it is: {"city":`, `Here it is: {"city":`},
+		{"Empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := ChatCompletionResponse{
+				Choices: []ChatCompletionResponseChoice{
+					{Message: ChatCompletionMessage{Role: RoleAssistant, Content: tt.content}},
+				},
+			}
+			extractEmulatedResponseFormat(&response)
+			assert.True(t, response.ResponseFormatEmulated)
+			assert.Equal(t, tt.expected, response.Choices[0].Message.Content)
+		})
+	}
+}
+
+func TestExecuteResponseFormatEmulation(t *testing.T) {
+	server := newFakeOpenRouter(t, textResponse("Sure! ```json\n{\"city\":\"Paris\"}\n```"))
+
+	_, resp, err := server.client.NewChatCompletion().
+		WithModel("openai/gpt-4o").
+		WithResponseFormatEmulation(EmulationAlways).
+		WithResponseFormat(map[string]any{
+			"type": "json_schema",
+			"json_schema": map[string]any{
+				"name": "capital",
+				"schema": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"city": map[string]any{"type": "string"}},
+				},
+			},
+		}).
+		WithSystemMessage("Be brief").
+		WithUserMessage("What is the capital of France?").
+		Execute()
+	assert.NoError(t, err)
+	assert.True(t, resp.ResponseFormatEmulated)
+	assert.Equal(t, `{"city":"Paris"}`, resp.Choices[0].Message.Content)
+
+	// The response format is not sent, its instructions are added to the system message
+	request := server.request(0)
+	_, hasFormat := request["response_format"]
+	assert.False(t, hasFormat)
+	messages, _ := request["messages"].([]any)
+	assert.Equal(t, 2, len(messages))
+	system, _ := messages[0].(map[string]any)
+	content, _ := system["content"].(string)
+	assert.Equal(t, any("system"), system["role"])
+	assert.True(t, strings.HasPrefix(content, "Be brief\n\nRespond only with a valid JSON object that matches"))
+	assert.True(t, strings.Contains(content, `"city"`))
+}
+
+func TestExecuteResponseFormatEmulationWithoutSystemMessage(t *testing.T) {
+	server := newFakeOpenRouter(t, textResponse(`{"city":"Paris"}`))
+
+	_, resp, err := server.client.NewChatCompletion().
+		WithModel("openai/gpt-4o").
+		WithResponseFormatEmulation(EmulationAlways).
+		WithResponseFormat(map[string]any{"type": "json_object"}).
+		WithUserMessage("What is the capital of France?").
+		Execute()
+	assert.NoError(t, err)
+	assert.True(t, resp.ResponseFormatEmulated)
+
+	messages, _ := server.request(0)["messages"].([]any)
+	assert.Equal(t, 2, len(messages))
+	system, _ := messages[0].(map[string]any)
+	assert.Equal(t, any("system"), system["role"])
+	assert.Equal(t, any(emulatedJSONObjectInstructions), system["content"])
+}
@@ -0,0 +1,369 @@
+// Package jsonrepair provides a tolerant parser that repairs the malformed JSON commonly
+// generated by language models.
+package jsonrepair
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrUnrepairable is returned when the input can't be repaired into valid JSON.
+var ErrUnrepairable = errors.New("the JSON can't be repaired")
+
+// Repair returns a valid JSON document from the given input and true if the input had
+// to be repaired.
+//
+// If the input is already valid JSON it's returned unchanged. Otherwise, it fixes the
+// following problems:
+//
+//   - Markdown code fences and text before or after the JSON value.
+//   - Trailing commas and missing commas between values.
+//   - Single quoted strings and unquoted object keys.
+//   - Unterminated strings, objects and arrays (for example, truncated outputs).
+//   - Comments, Python literals (True, False, None) and invalid numbers.
+//   - Raw new lines and control characters inside strings.
+func Repair(input string) (string, bool, error) {
+	trimmed := strings.TrimSpace(input)
+	if json.Valid([]byte(trimmed)) {
+		return input, false, nil
+	}
+
+	text := Extract(trimmed)
+	if json.Valid([]byte(text)) {
+		return text, true, nil
+	}
+
+	p := parser{input: text, pos: 0, out: &strings.Builder{}}
+	p.skipSpace()
+	if p.eof() {
+		return "", false, ErrUnrepairable
+	}
+	p.parseValue()
+
+	output := p.out.String()
+	if !json.Valid([]byte(output)) {
+		return "", false, ErrUnrepairable
+	}
+
+	return output, true, nil
+}
+
+// Extract returns the part of the text that most likely contains the JSON value, it
+// removes markdown code fences and any text before the first { or [.
+//
+// If no object or array is found, the text is returned without the code fences.
+func Extract(text string) string {
+	text = strings.TrimSpace(text)
+
+	if start := strings.Index(text, "```"); start != -1 {
+		content := text[start+3:]
+		// Skip the language of the code block, for example ```json
+		if newLine := strings.IndexByte(content, '\n'); newLine != -1 {
+			if lang := strings.TrimSpace(content[:newLine]); !strings.ContainsAny(lang, "{[\"") {
+				content = content[newLine+1:]
+			}
+		}
+		if end := strings.Index(content, "```"); end != -1 {
+			content = content[:end]
+		}
+		text = strings.TrimSpace(content)
+	}
+
+	if json.Valid([]byte(text)) {
+		return text
+	}
+
+	if start := strings.IndexAny(text, "{["); start != -1 {
+		return text[start:]
+	}
+
+	return text
+}
+
+type parser struct {
+	input string
+	pos   int
+	out   *strings.Builder
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// skipSpace skips whitespace and comments.
+func (p *parser) skipSpace() {
+	for !p.eof() {
+		c := p.peek()
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			p.pos++
+		case strings.HasPrefix(p.input[p.pos:], "//"):
+			end := strings.IndexByte(p.input[p.pos:], '\n')
+			if end == -1 {
+				p.pos = len(p.input)
+			} else {
+				p.pos += end + 1
+			}
+		case strings.HasPrefix(p.input[p.pos:], "/*"):
+			end := strings.Index(p.input[p.pos+2:], "*/")
+			if end == -1 {
+				p.pos = len(p.input)
+			} else {
+				p.pos += end + 4
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) parseValue() {
+	p.skipSpace()
+	switch c := p.peek(); {
+	case c == '{':
+		p.parseObject()
+	case c == '[':
+		p.parseArray()
+	case c == '"' || c == '\'':
+		p.writeString(p.parseString())
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		p.parseNumber()
+	case c == 0:
+		p.out.WriteString("null")
+	default:
+		p.parseWord()
+	}
+}
+
+func (p *parser) parseObject() {
+	p.pos++ // {
+	p.out.WriteByte('{')
+
+	first := true
+	for {
+		p.skipSpace()
+		for p.peek() == ',' {
+			p.pos++
+			p.skipSpace()
+		}
+		if p.eof() || p.peek() == '}' {
+			break
+		}
+		if p.peek() == ']' {
+			// Mismatched closing bracket, treat it as the end of the object
+			p.pos++
+			break
+		}
+
+		var key string
+		if c := p.peek(); c == '"' || c == '\'' {
+			key = p.parseString()
+		} else {
+			key = p.parseBareWord()
+			if key == "" {
+				// Unknown character, skip it to avoid an infinite loop
+				p.pos++
+				continue
+			}
+		}
+
+		p.skipSpace()
+		if p.peek() == ':' {
+			p.pos++
+		}
+		p.skipSpace()
+		if p.eof() || p.peek() == '}' || p.peek() == ',' {
+			// Key without value, usually a truncated output, drop it
+			continue
+		}
+
+		if !first {
+			p.out.WriteByte(',')
+		}
+		first = false
+		p.writeString(key)
+		p.out.WriteByte(':')
+		p.parseValue()
+	}
+
+	if p.peek() == '}' {
+		p.pos++
+	}
+	p.out.WriteByte('}')
+}
+
+func (p *parser) parseArray() {
+	p.pos++ // [
+	p.out.WriteByte('[')
+
+	first := true
+	for {
+		p.skipSpace()
+		for p.peek() == ',' {
+			p.pos++
+			p.skipSpace()
+		}
+		if p.eof() || p.peek() == ']' {
+			break
+		}
+		if p.peek() == '}' {
+			// Mismatched closing bracket, treat it as the end of the array
+			p.pos++
+			break
+		}
+
+		if !first {
+			p.out.WriteByte(',')
+		}
+		first = false
+
+		before := p.pos
+		p.parseValue()
+		if p.pos == before {
+			// Nothing could be parsed, skip the character to avoid an infinite loop
+			p.pos++
+		}
+	}
+
+	if p.peek() == ']' {
+		p.pos++
+	}
+	p.out.WriteByte(']')
+}
+
+// parseString parses a single or double quoted string and returns its content. If the
+// string is not terminated, it ends at the end of the input.
+func (p *parser) parseString() string {
+	quote := p.peek()
+	p.pos++
+
+	sb := strings.Builder{}
+	for !p.eof() {
+		c := p.input[p.pos]
+		if c == quote {
+			p.pos++
+			return sb.String()
+		}
+
+		if c == '\\' && p.pos+1 < len(p.input) {
+			next := p.input[p.pos+1]
+			switch next {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'u':
+				if p.pos+6 <= len(p.input) {
+					var r string
+					if err := json.Unmarshal([]byte(`"`+p.input[p.pos:p.pos+6]+`"`), &r); err == nil {
+						sb.WriteString(r)
+						p.pos += 6
+						continue
+					}
+				}
+				sb.WriteByte('u')
+			default:
+				sb.WriteByte(next)
+			}
+			p.pos += 2
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+		sb.WriteRune(r)
+		p.pos += size
+	}
+
+	return sb.String()
+}
+
+func (p *parser) parseNumber() {
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if (c >= '0' && c <= '9') || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E' {
+			p.pos++
+			continue
+		}
+		break
+	}
+
+	number := strings.TrimPrefix(p.input[start:p.pos], "+")
+	number = strings.TrimRight(number, ".eE+-")
+	if strings.HasPrefix(number, ".") {
+		number = "0" + number
+	}
+	if strings.HasPrefix(number, "-.") {
+		number = "-0" + number[1:]
+	}
+
+	if number == "" || number == "-" || !json.Valid([]byte(number)) {
+		p.out.WriteString("null")
+		return
+	}
+	p.out.WriteString(number)
+}
+
+// parseWord parses literals (true, false, null and their Python equivalents) or
+// unquoted text that is converted to a string.
+func (p *parser) parseWord() {
+	start := p.pos
+	word := p.parseBareWord()
+	switch word {
+	case "true", "True", "TRUE":
+		p.out.WriteString("true")
+	case "false", "False", "FALSE":
+		p.out.WriteString("false")
+	case "null", "None", "undefined", "NaN", "Infinity", "nil":
+		p.out.WriteString("null")
+	case "":
+		// Unknown character, skip it so the caller makes progress
+		p.pos++
+		p.out.WriteString("null")
+	default:
+		// Unquoted text, read until the next delimiter
+		for !p.eof() && !strings.ContainsRune(",}]\n", rune(p.peek())) {
+			p.pos++
+		}
+		p.writeString(strings.TrimSpace(p.input[start:p.pos]))
+	}
+}
+
+// parseBareWord parses an unquoted identifier like an object key or a literal.
+func (p *parser) parseBareWord() string {
+	start := p.pos
+	for !p.eof() {
+		r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$' || r == '-' {
+			p.pos += size
+			continue
+		}
+		break
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *parser) writeString(s string) {
+	buf := bytes.Buffer{}
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+	p.out.Write(bytes.TrimRight(buf.Bytes(), "\n"))
+}
@@ -0,0 +1,58 @@
+package jsonrepair
+
+import (
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"code fence", "```json\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"surrounding text", "Sure! Here it is: {\"a\": 1} Hope it helps", `{"a":1}`},
+		{"trailing commas", `{"a": [1, 2,], "b": 2,}`, `{"a":[1,2],"b":2}`},
+		{"single quotes", `{'a': 'it"s'}`, `{"a":"it\"s"}`},
+		{"unquoted keys", `{a: 1, b_c: true}`, `{"a":1,"b_c":true}`},
+		{"unterminated string", `{"a": "hello`, `{"a":"hello"}`},
+		{"truncated object", `{"a": {"b": [1, 2`, `{"a":{"b":[1,2]}}`},
+		{"dangling key", `{"a": 1, "b`, `{"a":1}`},
+		{"python literals", `{"a": True, "b": None, "c": False}`, `{"a":true,"b":null,"c":false}`},
+		{"comments", "{\"a\": 1, // comment\n /* other */ \"b\": 2}", `{"a":1,"b":2}`},
+		{"numbers", `[+1, .5, 2., -.5, NaN]`, `[1,0.5,2,-0.5,null]`},
+		{"raw new line in string", "{\"a\": \"line\nline\"}", `{"a":"line\nline"}`},
+		{"missing commas", `{"a": 1 "b": 2}`, `{"a":1,"b":2}`},
+		{"unquoted value", `{"a": hello world}`, `{"a":"hello world"}`},
+		{"html is not escaped", `{'a': '<b>'}`, `{"a":"<b>"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, repaired, err := Repair(tt.input)
+			assert.NoError(t, err)
+			assert.True(t, repaired)
+			assert.Equal(t, tt.expected, output)
+		})
+	}
+}
+
+func TestRepairValidInput(t *testing.T) {
+	output, repaired, err := Repair(` {"a": 1} `)
+	assert.NoError(t, err)
+	assert.False(t, repaired)
+	assert.Equal(t, ` {"a": 1} `, output)
+}
+
+func TestRepairUnrepairable(t *testing.T) {
+	_, _, err := Repair("   ")
+	assert.Error(t, ErrUnrepairable, err)
+}
+
+func TestExtract(t *testing.T) {
+	assert.Equal(t, `{"a": 1}`, Extract("```\n{\"a\": 1}\n```"))
+	assert.Equal(t, `[1]`, Extract("The list is [1]"))
+	assert.Equal(t, `hello`, Extract("```text\nhello\n```"))
+}
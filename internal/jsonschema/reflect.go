@@ -0,0 +1,335 @@
+// Package jsonschema provides utilities to work with JSON Schemas represented as
+// map[string]any, the format used by the OpenRouter API for tool parameters and
+// response formats.
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schemer can be implemented by types that want to provide their own JSON Schema
+// instead of the one generated by reflection.
+type Schemer interface {
+	JSONSchema() map[string]any
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+	schemerType    = reflect.TypeFor[Schemer]()
+)
+
+// ErrUnsupportedType is returned when a Go type can't be represented as a JSON Schema.
+var ErrUnsupportedType = errors.New("unsupported type")
+
+// FromType generates a JSON Schema for the given Go type using reflection.
+//
+// Struct fields are read following the same rules as encoding/json (json tag names,
+// "-" to skip, embedded structs are flattened). A field is required unless it's a
+// pointer or has the omitempty or omitzero json options. Pointer fields also accept
+// null.
+//
+// The jsonschema tag can be used to add more constraints to a field, using comma
+// separated options (use \, to write a comma inside a value):
+//
+//   - description=...: The description of the field.
+//   - enum=a|b|c: The allowed values of the field, or of its items if it's a slice.
+//   - minimum=N, maximum=N: The range of a number.
+//   - minLength=N, maxLength=N, pattern=...: Constraints for a string.
+//   - minItems=N, maxItems=N: The length range of an array.
+//   - format=...: The format of a string, for example "email".
+//   - required, optional: Override whether the field is required or not.
+//
+// The jsonschema_description tag can also be used to set the description.
+func FromType(t reflect.Type) (map[string]any, error) {
+	g := generator{visiting: map[reflect.Type]bool{}}
+	return g.schemaFor(t)
+}
+
+type generator struct {
+	visiting map[reflect.Type]bool
+}
+
+func (g *generator) schemaFor(t reflect.Type) (map[string]any, error) {
+	// The pointer method set includes the value methods, so a pointer to a zero value
+	// finds the implementations with both receivers. Interface types are skipped
+	// because they are only a method set, not a value with a schema.
+	if t.Kind() != reflect.Pointer && t.Kind() != reflect.Interface && reflect.PointerTo(t).Implements(schemerType) {
+		return reflect.New(t).Interface().(Schemer).JSONSchema(), nil
+	}
+
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	case rawMessageType:
+		return map[string]any{}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schemaFor(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer", "minimum": 0}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Interface:
+		return map[string]any{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}, nil
+		}
+
+		items, err := g.schemaFor(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		schema := map[string]any{"type": "array", "items": items}
+		if t.Kind() == reflect.Array {
+			schema["minItems"] = t.Len()
+			schema["maxItems"] = t.Len()
+		}
+		return schema, nil
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return nil, fmt.Errorf("%w: map key %s", ErrUnsupportedType, t.Key())
+		}
+
+		values, err := g.schemaFor(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, t)
+}
+
+func (g *generator) structSchema(t reflect.Type) (map[string]any, error) {
+	if g.visiting[t] {
+		return nil, fmt.Errorf("%w: recursive type %s", ErrUnsupportedType, t)
+	}
+	g.visiting[t] = true
+	defer delete(g.visiting, t)
+
+	properties := map[string]any{}
+	required := []string{}
+	if err := g.addFields(t, properties, &required); err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}, nil
+}
+
+func (g *generator) addFields(t reflect.Type, properties map[string]any, required *[]string) error {
+	embedded := []reflect.Type{}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		jsonTag := field.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		name, jsonOptions, _ := strings.Cut(jsonTag, ",")
+
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				embedded = append(embedded, fieldType)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, exists := properties[name]; exists {
+			continue
+		}
+
+		schema, err := g.schemaFor(field.Type)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if hasOption(jsonOptions, "string") {
+			schema = map[string]any{"type": "string"}
+		}
+		// The schema of a Schemer is owned by the type, so it's copied before adding
+		// the options of the field
+		schema = maps.Clone(schema)
+
+		isRequired := field.Type.Kind() != reflect.Pointer &&
+			!hasOption(jsonOptions, "omitempty") &&
+			!hasOption(jsonOptions, "omitzero")
+
+		if description := field.Tag.Get("jsonschema_description"); description != "" {
+			schema["description"] = description
+		}
+
+		tagRequired, err := applyTag(schema, field.Tag.Get("jsonschema"))
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if tagRequired != nil {
+			isRequired = *tagRequired
+		}
+
+		// encoding/json decodes null into a nil pointer
+		if field.Type.Kind() == reflect.Pointer && len(schema) > 0 {
+			schema = nullable(schema).(map[string]any)
+		}
+
+		properties[name] = schema
+		if isRequired {
+			*required = append(*required, name)
+		}
+	}
+
+	// Embedded structs are added last so the outer fields take precedence, the
+	// same way encoding/json does
+	for _, e := range embedded {
+		if err := g.addFields(e, properties, required); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyTag applies the options of a jsonschema tag to the schema.
+//
+// Returns a non-nil bool if the tag overrides whether the field is required or not.
+func applyTag(schema map[string]any, tag string) (*bool, error) {
+	var required *bool
+
+	for _, option := range splitTag(tag) {
+		key, value, hasValue := strings.Cut(option, "=")
+		key = strings.TrimSpace(key)
+
+		switch key {
+		case "":
+			continue
+		case "required", "optional":
+			r := key == "required"
+			required = &r
+			continue
+		}
+
+		if !hasValue {
+			return nil, fmt.Errorf("jsonschema tag option %q requires a value", key)
+		}
+
+		switch key {
+		case "description", "pattern", "format", "title":
+			schema[key] = value
+		case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("jsonschema tag option %q: %w", key, err)
+			}
+			schema[key] = n
+		case "minLength", "maxLength", "minItems", "maxItems", "minProperties", "maxProperties":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("jsonschema tag option %q: %w", key, err)
+			}
+			schema[key] = n
+		case "enum":
+			// The values of a slice are the allowed values of its items
+			target := schema
+			if items, ok := schema["items"].(map[string]any); ok && schema["type"] == "array" {
+				target = maps.Clone(items)
+				schema["items"] = target
+			}
+
+			values := []any{}
+			for _, v := range strings.Split(value, "|") {
+				parsed, err := parseEnumValue(target["type"], v)
+				if err != nil {
+					return nil, fmt.Errorf("jsonschema tag option %q: %w", key, err)
+				}
+				values = append(values, parsed)
+			}
+			target[key] = values
+		default:
+			return nil, fmt.Errorf("unknown jsonschema tag option %q", key)
+		}
+	}
+
+	return required, nil
+}
+
+// parseEnumValue converts an enum value from the tag to the type of the schema.
+func parseEnumValue(schemaType any, value string) (any, error) {
+	switch schemaType {
+	case "integer":
+		return strconv.Atoi(value)
+	case "number":
+		return strconv.ParseFloat(value, 64)
+	case "boolean":
+		return strconv.ParseBool(value)
+	}
+	return value, nil
+}
+
+// splitTag splits a tag by the commas that are not escaped with a backslash.
+func splitTag(tag string) []string {
+	parts := []string{}
+	current := strings.Builder{}
+
+	for i := 0; i < len(tag); i++ {
+		if tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',' {
+			current.WriteByte(',')
+			i++
+			continue
+		}
+		if tag[i] == ',' {
+			parts = append(parts, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteByte(tag[i])
+	}
+
+	return append(parts, current.String())
+}
+
+// hasOption returns true if the comma separated json tag options contain the option.
+func hasOption(options string, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func schemaJSON(t *testing.T, v any) string {
+	t.Helper()
+	schema, err := FromType(reflect.TypeOf(v))
+	assert.NoError(t, err)
+	b, err := json.Marshal(schema)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func TestFromTypeBasic(t *testing.T) {
+	type args struct {
+		City  string  `json:"city" jsonschema:"description=The name of the city\\, in English"`
+		Units *string `json:"units" jsonschema:"enum=celsius|fahrenheit"`
+		Days  int     `json:"days,omitempty" jsonschema:"minimum=1,maximum=7"`
+		Debug bool    `json:"-"`
+		hide  string
+	}
+
+	assert.Equal(
+		t,
+		`{"additionalProperties":false,"properties":{`+
+			`"city":{"description":"The name of the city, in English","type":"string"},`+
+			`"days":{"maximum":7,"minimum":1,"type":"integer"},`+
+			`"units":{"enum":["celsius","fahrenheit",null],"type":["string","null"]}},`+
+			`"required":["city"],"type":"object"}`,
+		schemaJSON(t, args{}),
+	)
+}
+
+func TestFromTypeNested(t *testing.T) {
+	type address struct {
+		Street string `json:"street"`
+	}
+	type embedded struct {
+		ID string `json:"id"`
+	}
+	type person struct {
+		embedded
+		Name      string            `json:"name" jsonschema:"optional"`
+		Addresses []address         `json:"addresses"`
+		Tags      map[string]int    `json:"tags"`
+		Birthday  time.Time         `json:"birthday"`
+		Extra     any               `json:"extra,omitzero" jsonschema:"required"`
+		Scores    [2]float64        `json:"scores"`
+		Raw       json.RawMessage   `json:"raw,omitempty"`
+		Labels    map[string]string `json:"labels,omitempty"`
+	}
+
+	assert.Equal(
+		t,
+		`{"additionalProperties":false,"properties":{`+
+			`"addresses":{"items":{"additionalProperties":false,"properties":{"street":{"type":"string"}},"required":["street"],"type":"object"},"type":"array"},`+
+			`"birthday":{"format":"date-time","type":"string"},`+
+			`"extra":{},`+
+			`"id":{"type":"string"},`+
+			`"labels":{"additionalProperties":{"type":"string"},"type":"object"},`+
+			`"name":{"type":"string"},`+
+			`"raw":{},`+
+			`"scores":{"items":{"type":"number"},"maxItems":2,"minItems":2,"type":"array"},`+
+			`"tags":{"additionalProperties":{"type":"integer"},"type":"object"}},`+
+			`"required":["addresses","tags","birthday","extra","scores","id"],"type":"object"}`,
+		schemaJSON(t, person{}),
+	)
+}
+
+func TestFromTypeErrors(t *testing.T) {
+	type recursive struct {
+		Children []recursive `json:"children"`
+	}
+	_, err := FromType(reflect.TypeOf(recursive{}))
+	assert.True(t, errors.Is(err, ErrUnsupportedType))
+
+	type withChan struct {
+		C chan int `json:"c"`
+	}
+	_, err = FromType(reflect.TypeOf(withChan{}))
+	assert.True(t, errors.Is(err, ErrUnsupportedType))
+
+	type badTag struct {
+		A string `json:"a" jsonschema:"unknown=1"`
+	}
+	_, err = FromType(reflect.TypeOf(badTag{}))
+	assert.NotNil(t, err)
+}
+
+type valueSchemer string
+
+func (valueSchemer) JSONSchema() map[string]any {
+	return map[string]any{"type": "string", "format": "uuid"}
+}
+
+type pointerSchemer struct {
+	Value string
+}
+
+func (*pointerSchemer) JSONSchema() map[string]any {
+	return map[string]any{"type": "string", "format": "date"}
+}
+
+func TestFromTypeSchemer(t *testing.T) {
+	type args struct {
+		ID      valueSchemer    `json:"id"`
+		Date    pointerSchemer  `json:"date"`
+		DatePtr *pointerSchemer `json:"date_ptr"`
+		Custom  Schemer         `json:"custom"`
+	}
+
+	assert.Equal(
+		t,
+		`{"additionalProperties":false,"properties":{`+
+			`"custom":{},`+
+			`"date":{"format":"date","type":"string"},`+
+			`"date_ptr":{"format":"date","type":["string","null"]},`+
+			`"id":{"format":"uuid","type":"string"}},`+
+			`"required":["id","date","custom"],"type":"object"}`,
+		schemaJSON(t, args{}),
+	)
+}
+
+// sharedSchema is returned by sharedSchemer without copying it.
+var sharedSchema = map[string]any{"type": "string"}
+
+type sharedSchemer string
+
+func (sharedSchemer) JSONSchema() map[string]any {
+	return sharedSchema
+}
+
+func TestFromTypeDoesNotModifySchemer(t *testing.T) {
+	type args struct {
+		Name sharedSchemer `json:"name" jsonschema:"description=The name,enum=a|b"`
+	}
+
+	assert.Equal(
+		t,
+		`{"additionalProperties":false,"properties":{`+
+			`"name":{"description":"The name","enum":["a","b"],"type":"string"}},`+
+			`"required":["name"],"type":"object"}`,
+		schemaJSON(t, args{}),
+	)
+	assert.Equal(t, 1, len(sharedSchema))
+}
+
+func TestFromTypeSliceEnum(t *testing.T) {
+	type args struct {
+		Units  []string        `json:"units" jsonschema:"enum=celsius|fahrenheit,minItems=1"`
+		Days   [2]int          `json:"days" jsonschema:"enum=1|2|3"`
+		Shared []sharedSchemer `json:"shared" jsonschema:"enum=a|b"`
+	}
+
+	assert.Equal(
+		t,
+		`{"additionalProperties":false,"properties":{`+
+			`"days":{"items":{"enum":[1,2,3],"type":"integer"},"maxItems":2,"minItems":2,"type":"array"},`+
+			`"shared":{"items":{"enum":["a","b"],"type":"string"},"type":"array"},`+
+			`"units":{"items":{"enum":["celsius","fahrenheit"],"type":"string"},"minItems":1,"type":"array"}},`+
+			`"required":["units","days","shared"],"type":"object"}`,
+		schemaJSON(t, args{}),
+	)
+	assert.Equal(t, 1, len(sharedSchema))
+}
+
+func TestFromTypePointerAcceptsNull(t *testing.T) {
+	type address struct {
+		Street string `json:"street"`
+	}
+	type args struct {
+		Units   *string          `json:"units" jsonschema:"enum=celsius|fahrenheit"`
+		Address *address         `json:"address"`
+		Raw     *json.RawMessage `json:"raw"`
+	}
+
+	schema, err := FromType(reflect.TypeOf(args{}))
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		`{"additionalProperties":false,"properties":{`+
+			`"address":{"additionalProperties":false,"properties":{"street":{"type":"string"}},"required":["street"],"type":["object","null"]},`+
+			`"raw":{},`+
+			`"units":{"enum":["celsius","fahrenheit",null],"type":["string","null"]}},`+
+			`"required":[],"type":"object"}`,
+		schemaJSON(t, args{}),
+	)
+
+	assert.NoError(t, ValidateJSON(schema, []byte(`{"units":null,"address":null,"raw":null}`)))
+	assert.NoError(t, ValidateJSON(schema, []byte(`{"units":"celsius","address":{"street":"Main"}}`)))
+	assert.NotNil(t, ValidateJSON(schema, []byte(`{"units":"kelvin"}`)))
+
+	// The generated schema is still accepted by Strict
+	_, err = Strict(schema)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,283 @@
+package jsonschema
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Dialect describes the subset of JSON Schema accepted by a model provider, it's used
+// by Sanitize to rewrite the schemas the provider would reject.
+type Dialect struct {
+	// InlineRefs replaces the local $ref with the referenced schema and removes the
+	// definitions. Recursive references are replaced with a generic object.
+	InlineRefs bool
+	// MergeAllOf merges the allOf subschemas into the schema that contains them.
+	MergeAllOf bool
+	// OneOfToAnyOf replaces oneOf with anyOf.
+	OneOfToAnyOf bool
+	// ConstToEnum replaces const with an enum with a single value.
+	ConstToEnum bool
+	// NullableKeyword replaces the null type, in type lists and anyOf, with the
+	// OpenAPI 3.0 "nullable": true keyword.
+	NullableKeyword bool
+	// RemoveKeywords are removed from all the schemas.
+	RemoveKeywords []string
+	// Formats are the string formats accepted, the other formats are removed. If nil,
+	// all the formats are accepted.
+	Formats []string
+}
+
+// GeminiDialect is the OpenAPI 3.0 based subset of JSON Schema accepted by the Google
+// Gemini models.
+var GeminiDialect = Dialect{
+	InlineRefs:      true,
+	MergeAllOf:      true,
+	OneOfToAnyOf:    true,
+	ConstToEnum:     true,
+	NullableKeyword: true,
+	RemoveKeywords: []string{
+		"$schema", "$id", "$comment", "additionalProperties", "patternProperties", "unevaluatedProperties",
+		"exclusiveMinimum", "exclusiveMaximum", "multipleOf", "uniqueItems", "not", "if", "then", "else",
+		"dependentRequired", "dependentSchemas", "default", "examples",
+	},
+	Formats: []string{"enum", "date-time"},
+}
+
+// DefaultDialect only removes the keywords that don't describe the value.
+var DefaultDialect = Dialect{
+	InlineRefs:      false,
+	MergeAllOf:      false,
+	OneOfToAnyOf:    false,
+	ConstToEnum:     false,
+	NullableKeyword: false,
+	RemoveKeywords:  []string{"$schema", "$id", "$comment"},
+	Formats:         nil,
+}
+
+// Sanitize returns a copy of the schema rewritten for the given dialect, the original
+// schema is not modified.
+func Sanitize(schema map[string]any, dialect Dialect) (map[string]any, error) {
+	var copied any
+	if err := normalize(schema, &copied); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	root, _ := copied.(map[string]any)
+	if root == nil {
+		return map[string]any{}, nil
+	}
+
+	s := sanitizer{dialect: dialect, root: root}
+	result, err := s.sanitize(root, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	sanitized, _ := result.(map[string]any)
+	if dialect.InlineRefs {
+		delete(sanitized, "$defs")
+		delete(sanitized, "definitions")
+	}
+	return sanitized, nil
+}
+
+type sanitizer struct {
+	dialect Dialect
+	root    map[string]any
+}
+
+// sanitize rewrites a schema node, refs are the references being inlined used to
+// detect recursion.
+func (s *sanitizer) sanitize(node any, refs []string) (any, error) {
+	schema, ok := node.(map[string]any)
+	if !ok {
+		return node, nil
+	}
+
+	if ref, ok := schema["$ref"].(string); ok && s.dialect.InlineRefs {
+		if slices.Contains(refs, ref) {
+			return map[string]any{"type": "object"}, nil
+		}
+
+		v := validator{root: s.root, problems: []string{}}
+		resolved, err := v.resolveRef(ref)
+		if err != nil {
+			return nil, err
+		}
+		inlined, err := s.sanitize(resolved, append(slices.Clone(refs), ref))
+		if err != nil {
+			return nil, err
+		}
+
+		merged := map[string]any{}
+		if m, ok := inlined.(map[string]any); ok {
+			for key, value := range m {
+				merged[key] = value
+			}
+		}
+		for key, value := range schema {
+			if key != "$ref" {
+				merged[key] = value
+			}
+		}
+		schema = merged
+	}
+
+	result := map[string]any{}
+	for key, value := range schema {
+		if slices.Contains(s.dialect.RemoveKeywords, key) {
+			continue
+		}
+
+		var err error
+		switch key {
+		case "properties", "$defs", "definitions":
+			if subs, ok := value.(map[string]any); ok {
+				sanitizedSubs := map[string]any{}
+				for name, sub := range subs {
+					if sanitizedSubs[name], err = s.sanitize(sub, refs); err != nil {
+						return nil, err
+					}
+				}
+				value = sanitizedSubs
+			}
+		case "items", "additionalProperties", "not":
+			value, err = s.sanitize(value, refs)
+		case "prefixItems", "anyOf", "oneOf", "allOf":
+			if list, ok := value.([]any); ok {
+				sanitizedList := make([]any, len(list))
+				for i, sub := range list {
+					if sanitizedList[i], err = s.sanitize(sub, refs); err != nil {
+						return nil, err
+					}
+				}
+				value = sanitizedList
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result[key] = value
+	}
+
+	if s.dialect.MergeAllOf {
+		mergeAllOf(result)
+	}
+	if oneOf, ok := result["oneOf"]; ok && s.dialect.OneOfToAnyOf {
+		delete(result, "oneOf")
+		result["anyOf"] = oneOf
+	}
+	if c, ok := result["const"]; ok && s.dialect.ConstToEnum {
+		delete(result, "const")
+		result["enum"] = []any{c}
+	}
+	if s.dialect.NullableKeyword {
+		result = useNullableKeyword(result)
+	}
+	if format, ok := result["format"].(string); ok && s.dialect.Formats != nil {
+		if !slices.Contains(s.dialect.Formats, format) {
+			delete(result, "format")
+		}
+	}
+
+	return result, nil
+}
+
+// mergeAllOf merges the allOf subschemas into the schema, the properties and required
+// lists are joined and the other keywords are only set if missing.
+func mergeAllOf(schema map[string]any) {
+	allOf, ok := schema["allOf"].([]any)
+	if !ok {
+		return
+	}
+	delete(schema, "allOf")
+
+	for _, item := range allOf {
+		sub, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		for key, value := range sub {
+			switch key {
+			case "properties":
+				properties, _ := schema["properties"].(map[string]any)
+				if properties == nil {
+					properties = map[string]any{}
+				}
+				if subProperties, ok := value.(map[string]any); ok {
+					for name, property := range subProperties {
+						properties[name] = property
+					}
+				}
+				schema["properties"] = properties
+			case "required":
+				required, _ := schema["required"].([]any)
+				if subRequired, ok := value.([]any); ok {
+					for _, name := range subRequired {
+						if !containsValue(required, name) {
+							required = append(required, name)
+						}
+					}
+				}
+				schema["required"] = required
+			default:
+				if _, exists := schema[key]; !exists {
+					schema[key] = value
+				}
+			}
+		}
+	}
+}
+
+// useNullableKeyword replaces the null type with "nullable": true.
+func useNullableKeyword(schema map[string]any) map[string]any {
+	isNullable := false
+
+	if types, ok := schema["type"].([]any); ok && containsValue(types, "null") {
+		remaining := []any{}
+		for _, t := range types {
+			if t != "null" {
+				remaining = append(remaining, t)
+			}
+		}
+		if len(remaining) == 1 {
+			schema["type"] = remaining[0]
+		} else {
+			schema["type"] = remaining
+		}
+		isNullable = true
+	}
+
+	if anyOf, ok := schema["anyOf"].([]any); ok && containsNullSchema(anyOf) {
+		remaining := []any{}
+		for _, sub := range anyOf {
+			if m, ok := sub.(map[string]any); !ok || m["type"] != "null" || len(m) != 1 {
+				remaining = append(remaining, sub)
+			}
+		}
+		delete(schema, "anyOf")
+		if len(remaining) == 0 {
+			return schema
+		}
+		if only, ok := remaining[0].(map[string]any); ok && len(remaining) == 1 {
+			for key, value := range only {
+				if _, exists := schema[key]; !exists {
+					schema[key] = value
+				}
+			}
+		} else {
+			schema["anyOf"] = remaining
+		}
+		isNullable = true
+	}
+
+	if isNullable {
+		schema["nullable"] = true
+		if enum, ok := schema["enum"].([]any); ok {
+			schema["enum"] = slices.DeleteFunc(slices.Clone(enum), func(v any) bool { return v == nil })
+		}
+	}
+
+	return schema
+}
@@ -0,0 +1,53 @@
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func TestSanitizeGemini(t *testing.T) {
+	schema := map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type":    "object",
+		"properties": map[string]any{
+			"default": map[string]any{"type": []string{"string", "null"}, "format": "email", "default": "a"},
+			"kind":    map[string]any{"oneOf": []any{map[string]any{"const": "a"}, map[string]any{"const": "b"}}},
+			"node":    map[string]any{"$ref": "#/$defs/node"},
+			"when":    map[string]any{"type": "string", "format": "date-time"},
+			"count":   map[string]any{"anyOf": []any{map[string]any{"type": "integer"}, map[string]any{"type": "null"}}},
+		},
+		"allOf": []any{
+			map[string]any{"properties": map[string]any{"extra": map[string]any{"type": "boolean"}}, "required": []string{"extra"}},
+		},
+		"required":             []string{"node"},
+		"additionalProperties": false,
+		"$defs": map[string]any{
+			"node": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"child": map[string]any{"$ref": "#/$defs/node"}},
+			},
+		},
+	}
+
+	sanitized, err := Sanitize(schema, GeminiDialect)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		`{"properties":{"count":{"nullable":true,"type":"integer"},"default":{"nullable":true,"type":"string"},`+
+			`"extra":{"type":"boolean"},"kind":{"anyOf":[{"enum":["a"]},{"enum":["b"]}]},`+
+			`"node":{"properties":{"child":{"type":"object"}},"type":"object"},`+
+			`"when":{"format":"date-time","type":"string"}},"required":["node","extra"],"type":"object"}`,
+		toJSON(t, sanitized),
+	)
+}
+
+func TestSanitizeDefault(t *testing.T) {
+	sanitized, err := Sanitize(map[string]any{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"type":       "object",
+		"properties": map[string]any{"$schema": map[string]any{"type": "string"}},
+	}, DefaultDialect)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"properties":{"$schema":{"type":"string"}},"type":"object"}`, toJSON(t, sanitized))
+}
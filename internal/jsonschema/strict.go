@@ -0,0 +1,178 @@
+package jsonschema
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrNotStrictCompatible is returned when a schema can't be rewritten for strict mode.
+var ErrNotStrictCompatible = errors.New("the schema is not compatible with strict mode")
+
+// Strict returns a copy of the schema rewritten for the OpenAI style strict mode, the
+// original schema is not modified.
+//
+// Strict mode requires every object to set additionalProperties to false and to list
+// all of its properties as required, so the optional properties are made nullable
+// instead: the model sends null when it doesn't want to set them.
+//
+// Returns ErrNotStrictCompatible if the root is not an object or an object allows
+// additional properties with a schema (a map).
+func Strict(schema map[string]any) (map[string]any, error) {
+	var copied any
+	if err := normalize(schema, &copied); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	root, _ := copied.(map[string]any)
+	if root == nil || !isObjectSchema(root) {
+		return nil, fmt.Errorf("%w: the root must be an object", ErrNotStrictCompatible)
+	}
+
+	if err := strictNode(root, "$"); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// isObjectSchema returns true if the schema describes an object.
+func isObjectSchema(schema map[string]any) bool {
+	switch t := schema["type"].(type) {
+	case string:
+		return t == "object"
+	case []any:
+		return containsValue(t, "object")
+	}
+	_, hasProperties := schema["properties"]
+	return hasProperties
+}
+
+func strictNode(node any, path string) error {
+	schema, ok := node.(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	if isObjectSchema(schema) {
+		if err := strictObject(schema, path); err != nil {
+			return err
+		}
+	}
+
+	for _, keyword := range []string{"items", "not", "additionalProperties"} {
+		if err := strictNode(schema[keyword], path+"."+keyword); err != nil {
+			return err
+		}
+	}
+	for _, keyword := range []string{"prefixItems", "anyOf", "oneOf", "allOf"} {
+		list, _ := schema[keyword].([]any)
+		for i, sub := range list {
+			if err := strictNode(sub, fmt.Sprintf("%s.%s[%d]", path, keyword, i)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, keyword := range []string{"properties", "$defs", "definitions"} {
+		subs, _ := schema[keyword].(map[string]any)
+		for name, sub := range subs {
+			if err := strictNode(sub, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func strictObject(schema map[string]any, path string) error {
+	if _, ok := schema["additionalProperties"].(map[string]any); ok {
+		return fmt.Errorf("%w: %s allows additional properties with a schema", ErrNotStrictCompatible, path)
+	}
+	schema["additionalProperties"] = false
+	if _, ok := schema["type"]; !ok {
+		schema["type"] = "object"
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	if properties == nil {
+		properties = map[string]any{}
+		schema["properties"] = properties
+	}
+
+	required := map[string]bool{}
+	if list, ok := schema["required"].([]any); ok {
+		for _, name := range list {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(properties))
+	for name, property := range properties {
+		names = append(names, name)
+		if !required[name] {
+			properties[name] = nullable(property)
+		}
+	}
+	sort.Strings(names)
+
+	requiredList := make([]any, len(names))
+	for i, name := range names {
+		requiredList[i] = name
+	}
+	schema["required"] = requiredList
+
+	return nil
+}
+
+// nullable returns the schema modified to also accept null.
+func nullable(node any) any {
+	schema, ok := node.(map[string]any)
+	if !ok {
+		return node
+	}
+
+	switch t := schema["type"].(type) {
+	case string:
+		if t != "null" {
+			schema["type"] = []any{t, "null"}
+		}
+	case []any:
+		if !containsValue(t, "null") {
+			schema["type"] = append(t, "null")
+		}
+	default:
+		if anyOf, ok := schema["anyOf"].([]any); ok && len(schema) == 1 {
+			if !containsNullSchema(anyOf) {
+				schema["anyOf"] = append(anyOf, map[string]any{"type": "null"})
+			}
+			return schema
+		}
+		return map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !containsValue(enum, nil) {
+		schema["enum"] = append(enum, nil)
+	}
+
+	return schema
+}
+
+func containsValue(list []any, value any) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsNullSchema(list []any) bool {
+	for _, item := range list {
+		if sub, ok := item.(map[string]any); ok && sub["type"] == "null" && len(sub) == 1 {
+			return true
+		}
+	}
+	return false
+}
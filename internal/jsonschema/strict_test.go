@@ -0,0 +1,65 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func toJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func TestStrict(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"city":  map[string]any{"type": "string"},
+			"units": map[string]any{"type": "string", "enum": []string{"celsius", "fahrenheit"}},
+			"place": map[string]any{"$ref": "#/$defs/place"},
+		},
+		"required": []string{"city"},
+		"$defs": map[string]any{
+			"place": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"lat": map[string]any{"type": "number"}},
+			},
+		},
+	}
+
+	strict, err := Strict(schema)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		`{"$defs":{"place":{"additionalProperties":false,"properties":{"lat":{"type":["number","null"]}},`+
+			`"required":["lat"],"type":"object"}},"additionalProperties":false,"properties":{`+
+			`"city":{"type":"string"},"place":{"anyOf":[{"$ref":"#/$defs/place"},{"type":"null"}]},`+
+			`"units":{"enum":["celsius","fahrenheit",null],"type":["string","null"]}},`+
+			`"required":["city","place","units"],"type":"object"}`,
+		toJSON(t, strict),
+	)
+
+	// The original schema is not modified
+	_, hasAdditional := schema["additionalProperties"]
+	assert.False(t, hasAdditional)
+
+	// Null is accepted for the optional properties
+	assert.NoError(t, ValidateJSON(strict, []byte(`{"city":"Paris","units":null,"place":null}`)))
+	assert.NotNil(t, ValidateJSON(strict, []byte(`{"city":null,"units":null,"place":null}`)))
+}
+
+func TestStrictNotCompatible(t *testing.T) {
+	_, err := Strict(map[string]any{"type": "array"})
+	assert.True(t, errors.Is(err, ErrNotStrictCompatible))
+
+	_, err = Strict(map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"tags": map[string]any{"type": "object", "additionalProperties": map[string]any{}}},
+	})
+	assert.True(t, errors.Is(err, ErrNotStrictCompatible))
+}
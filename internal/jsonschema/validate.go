@@ -0,0 +1,420 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationError is returned by Validate when the value does not match the schema,
+// it contains all the problems found.
+type ValidationError struct {
+	// Problems found in the value, each one prefixed with the path of the value
+	// that caused it, for example "$.city: is required".
+	Problems []string
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+// Validate checks that the value matches the JSON Schema.
+//
+// It supports a subset of JSON Schema draft 2020-12: type, enum, const, required,
+// properties, additionalProperties, items, prefixItems, minItems, maxItems, uniqueItems,
+// minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf, minLength,
+// maxLength, pattern, minProperties, maxProperties, allOf, anyOf, oneOf, not and local
+// $ref (for example "#/$defs/name"). Unknown keywords are ignored.
+//
+// The schema and the value can use any Go types that can be encoded as JSON, they are
+// normalized before the validation.
+//
+// Returns a *ValidationError if the value does not match the schema.
+func Validate(schema map[string]any, value any) error {
+	var normalizedSchema any
+	if err := normalize(schema, &normalizedSchema); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	var normalizedValue any
+	if err := normalize(value, &normalizedValue); err != nil {
+		return fmt.Errorf("invalid value: %w", err)
+	}
+
+	v := validator{root: normalizedSchema, problems: []string{}, refs: map[string]bool{}}
+	v.validate(normalizedSchema, normalizedValue, "$")
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+
+	return nil
+}
+
+// ValidateJSON is like Validate but the value is a JSON document.
+func ValidateJSON(schema map[string]any, data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return Validate(schema, value)
+}
+
+// normalize converts any value to the generic types produced by encoding/json.
+func normalize(in any, out *any) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+type validator struct {
+	root     any
+	problems []string
+	// refs are the references being resolved, with the path of the value they are
+	// applied to, to detect the cycles that never consume a value.
+	refs map[string]bool
+}
+
+func (v *validator) addf(path string, format string, args ...any) {
+	v.problems = append(v.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+// valid returns true if the value matches the schema without recording any problem.
+func (v *validator) valid(schema any, value any, path string) bool {
+	sub := validator{root: v.root, problems: []string{}, refs: v.refs}
+	sub.validate(schema, value, path)
+	return len(sub.problems) == 0
+}
+
+func (v *validator) validate(schemaValue any, value any, path string) {
+	switch s := schemaValue.(type) {
+	case bool:
+		if !s {
+			v.addf(path, "is not allowed")
+		}
+		return
+	case map[string]any:
+		v.validateSchema(s, value, path)
+	}
+}
+
+func (v *validator) validateSchema(schema map[string]any, value any, path string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved, err := v.resolveRef(ref)
+		if err != nil {
+			v.addf(path, "%v", err)
+			return
+		}
+
+		key := ref + "\x00" + path
+		if v.refs[key] {
+			v.addf(path, "circular $ref %q", ref)
+			return
+		}
+		v.refs[key] = true
+		v.validate(resolved, value, path)
+		delete(v.refs, key)
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(t, value) {
+		v.addf(path, "must be of type %s but got %s", formatType(t), typeOf(value))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.addf(path, "must be one of %s", formatJSON(enum))
+		}
+	}
+
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, value) {
+		v.addf(path, "must be %s", formatJSON(c))
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		v.validateObject(schema, val, path)
+	case []any:
+		v.validateArray(schema, val, path)
+	case string:
+		v.validateString(schema, val, path)
+	case float64:
+		v.validateNumber(schema, val, path)
+	}
+
+	if allOf, ok := schema["allOf"].([]any); ok {
+		for _, sub := range allOf {
+			v.validate(sub, value, path)
+		}
+	}
+
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if v.valid(sub, value, path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.addf(path, "must match at least one of the anyOf schemas")
+		}
+	}
+
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		matches := 0
+		for _, sub := range oneOf {
+			if v.valid(sub, value, path) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			v.addf(path, "must match exactly one of the oneOf schemas but matches %d", matches)
+		}
+	}
+
+	if not, ok := schema["not"]; ok && v.valid(not, value, path) {
+		v.addf(path, "must not match the not schema")
+	}
+}
+
+func (v *validator) validateObject(schema map[string]any, value map[string]any, path string) {
+	if required, ok := schema["required"].([]any); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, exists := value[name]; !exists {
+				v.addf(joinPath(path, name), "is required")
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	additional, hasAdditional := schema["additionalProperties"]
+
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if propertySchema, ok := properties[key]; ok {
+			v.validate(propertySchema, value[key], joinPath(path, key))
+			continue
+		}
+		if !hasAdditional {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok && !allowed {
+			v.addf(joinPath(path, key), "is not an allowed property")
+			continue
+		}
+		v.validate(additional, value[key], joinPath(path, key))
+	}
+
+	if n, ok := intKeyword(schema, "minProperties"); ok && len(value) < n {
+		v.addf(path, "must have at least %d properties", n)
+	}
+	if n, ok := intKeyword(schema, "maxProperties"); ok && len(value) > n {
+		v.addf(path, "must have at most %d properties", n)
+	}
+}
+
+func (v *validator) validateArray(schema map[string]any, value []any, path string) {
+	prefixItems, _ := schema["prefixItems"].([]any)
+	for i, item := range value {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		if i < len(prefixItems) {
+			v.validate(prefixItems[i], item, itemPath)
+			continue
+		}
+		if items, ok := schema["items"]; ok {
+			v.validate(items, item, itemPath)
+		}
+	}
+
+	if n, ok := intKeyword(schema, "minItems"); ok && len(value) < n {
+		v.addf(path, "must have at least %d items", n)
+	}
+	if n, ok := intKeyword(schema, "maxItems"); ok && len(value) > n {
+		v.addf(path, "must have at most %d items", n)
+	}
+
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := range value {
+			for j := i + 1; j < len(value); j++ {
+				if reflect.DeepEqual(value[i], value[j]) {
+					v.addf(path, "must have unique items but items %d and %d are equal", i, j)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) validateString(schema map[string]any, value string, path string) {
+	length := utf8.RuneCountInString(value)
+	if n, ok := intKeyword(schema, "minLength"); ok && length < n {
+		v.addf(path, "must have at least %d characters", n)
+	}
+	if n, ok := intKeyword(schema, "maxLength"); ok && length > n {
+		v.addf(path, "must have at most %d characters", n)
+	}
+
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			v.addf(path, "has an invalid pattern %q in the schema", pattern)
+		} else if !re.MatchString(value) {
+			v.addf(path, "must match the pattern %q", pattern)
+		}
+	}
+}
+
+func (v *validator) validateNumber(schema map[string]any, value float64, path string) {
+	if n, ok := schema["minimum"].(float64); ok && value < n {
+		v.addf(path, "must be greater than or equal to %v", n)
+	}
+	if n, ok := schema["maximum"].(float64); ok && value > n {
+		v.addf(path, "must be less than or equal to %v", n)
+	}
+	if n, ok := schema["exclusiveMinimum"].(float64); ok && value <= n {
+		v.addf(path, "must be greater than %v", n)
+	}
+	if n, ok := schema["exclusiveMaximum"].(float64); ok && value >= n {
+		v.addf(path, "must be less than %v", n)
+	}
+	if n, ok := schema["multipleOf"].(float64); ok && n > 0 {
+		quotient := value / n
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			v.addf(path, "must be a multiple of %v", n)
+		}
+	}
+}
+
+// resolveRef resolves a local reference like "#/$defs/name" against the root schema.
+func (v *validator) resolveRef(ref string) (any, error) {
+	if ref == "#" {
+		return v.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q, only local references are supported", ref)
+	}
+
+	current := v.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("can't resolve $ref %q", ref)
+		}
+		if current, ok = m[part]; !ok {
+			return nil, fmt.Errorf("can't resolve $ref %q", ref)
+		}
+	}
+
+	return current, nil
+}
+
+// matchesType returns true if the value matches the type keyword, which can be a
+// single type or a list of types.
+func matchesType(t any, value any) bool {
+	switch types := t.(type) {
+	case string:
+		return matchesSingleType(types, value)
+	case []any:
+		for _, single := range types {
+			if s, ok := single.(string); ok && matchesSingleType(s, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesSingleType(t string, value any) bool {
+	switch t {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	}
+	return false
+}
+
+func typeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
+
+func formatType(t any) string {
+	if types, ok := t.([]any); ok {
+		parts := make([]string, len(types))
+		for i, single := range types {
+			parts[i] = fmt.Sprint(single)
+		}
+		return strings.Join(parts, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func formatJSON(value any) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+func intKeyword(schema map[string]any, keyword string) (int, bool) {
+	n, ok := schema[keyword].(float64)
+	return int(n), ok
+}
+
+func joinPath(path string, key string) string {
+	return path + "." + key
+}
@@ -0,0 +1,143 @@
+package jsonschema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func problemsOf(t *testing.T, err error) string {
+	t.Helper()
+	if err == nil {
+		return ""
+	}
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	return err.Error()
+}
+
+func TestValidateObject(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"city":  map[string]any{"type": "string", "minLength": 2},
+			"units": map[string]any{"type": "string", "enum": []string{"celsius", "fahrenheit"}},
+			"days":  map[string]any{"type": "integer", "minimum": 1, "maximum": 7},
+		},
+		"required":             []string{"city"},
+		"additionalProperties": false,
+	}
+
+	assert.NoError(t, ValidateJSON(schema, []byte(`{"city":"Paris","units":"celsius","days":3}`)))
+	assert.Equal(t, "$.city: is required", problemsOf(t, ValidateJSON(schema, []byte(`{}`))))
+	assert.Equal(
+		t,
+		`$.days: must be of type integer but got number; $.extra: is not an allowed property; `+
+			`$.units: must be one of ["celsius","fahrenheit"]`,
+		problemsOf(t, ValidateJSON(schema, []byte(`{"city":"Paris","units":"kelvin","days":1.5,"extra":true}`))),
+	)
+	assert.Equal(
+		t,
+		"$.city: must have at least 2 characters; $.days: must be greater than or equal to 1",
+		problemsOf(t, ValidateJSON(schema, []byte(`{"city":"P","days":0}`))),
+	)
+	assert.Equal(t, "$: must be of type object but got array", problemsOf(t, ValidateJSON(schema, []byte(`[]`))))
+}
+
+func TestValidateArray(t *testing.T) {
+	schema := map[string]any{
+		"type":        "array",
+		"items":       map[string]any{"type": []string{"string", "null"}, "pattern": "^[a-z]+$"},
+		"minItems":    1,
+		"maxItems":    3,
+		"uniqueItems": true,
+	}
+
+	assert.NoError(t, Validate(schema, []any{"a", nil, "b"}))
+	assert.Equal(t, "$: must have at least 1 items", problemsOf(t, Validate(schema, []string{})))
+	assert.Equal(
+		t,
+		`$[1]: must match the pattern "^[a-z]+$"; $[2]: must be of type string or null but got number`,
+		problemsOf(t, Validate(schema, []any{"a", "B", 1})),
+	)
+	assert.Equal(
+		t,
+		"$: must have unique items but items 0 and 1 are equal",
+		problemsOf(t, Validate(schema, []any{"a", "a"})),
+	)
+}
+
+func TestValidateCombinators(t *testing.T) {
+	schema := map[string]any{
+		"$defs": map[string]any{
+			"positive": map[string]any{"type": "number", "exclusiveMinimum": 0},
+		},
+		"type": "object",
+		"properties": map[string]any{
+			"amount": map[string]any{"$ref": "#/$defs/positive"},
+			"id": map[string]any{
+				"anyOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "integer"}},
+			},
+			"kind": map[string]any{
+				"oneOf": []any{map[string]any{"const": "a"}, map[string]any{"const": "b"}},
+			},
+		},
+	}
+
+	assert.NoError(t, ValidateJSON(schema, []byte(`{"amount":1,"id":2,"kind":"a"}`)))
+	assert.Equal(
+		t,
+		"$.amount: must be greater than 0; $.id: must match at least one of the anyOf schemas; "+
+			"$.kind: must match exactly one of the oneOf schemas but matches 0",
+		problemsOf(t, ValidateJSON(schema, []byte(`{"amount":0,"id":true,"kind":"c"}`))),
+	)
+}
+
+func TestValidateInvalidJSON(t *testing.T) {
+	err := ValidateJSON(map[string]any{}, []byte(`{`))
+	assert.NotNil(t, err)
+	var validationErr *ValidationError
+	assert.False(t, errors.As(err, &validationErr))
+}
+
+func TestValidateRecursiveRef(t *testing.T) {
+	tree := map[string]any{
+		"$defs": map[string]any{
+			"node": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"value":    map[string]any{"type": "integer"},
+					"children": map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/node"}},
+				},
+			},
+		},
+		"$ref": "#/$defs/node",
+	}
+
+	assert.NoError(t, ValidateJSON(tree, []byte(`{"value":1,"children":[{"value":2,"children":[]}]}`)))
+	assert.Equal(
+		t,
+		"$.children[0].value: must be of type integer but got string",
+		problemsOf(t, ValidateJSON(tree, []byte(`{"value":1,"children":[{"value":"2"}]}`))),
+	)
+
+	// A cycle that never consumes a value is reported instead of recursing forever
+	cycle := map[string]any{
+		"$defs": map[string]any{
+			"a": map[string]any{"$ref": "#/$defs/b"},
+			"b": map[string]any{"anyOf": []any{map[string]any{"$ref": "#/$defs/a"}}},
+		},
+		"$ref": "#/$defs/a",
+	}
+	assert.Equal(
+		t,
+		"$: must match at least one of the anyOf schemas",
+		problemsOf(t, Validate(cycle, 1)),
+	)
+	assert.Equal(
+		t,
+		`$: circular $ref "#/$defs/a"`,
+		problemsOf(t, Validate(map[string]any{"$defs": map[string]any{"a": map[string]any{"$ref": "#/$defs/a"}}, "$ref": "#/$defs/a"}, 1)),
+	)
+}
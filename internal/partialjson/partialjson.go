@@ -0,0 +1,240 @@
+// Package partialjson completes the prefixes of a JSON document, like the content
+// streamed by a language model, so they can be decoded before the document is complete.
+package partialjson
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// frame is an object or array that is open at some point of the input.
+type frame struct {
+	object bool
+	// True if the next string of the object is a key.
+	key bool
+}
+
+// Completer completes a JSON document that is received in pieces, like the content
+// streamed by a language model. It keeps the state of the input scanned so far, so
+// each piece is scanned only once.
+type Completer struct {
+	input []byte
+	// The position of the next byte to scan.
+	pos   int
+	stack []frame
+	// The start of the string, number or literal being scanned, or -1.
+	token int
+	// True if the root value ended or the input is not valid, the rest is ignored.
+	done bool
+	// The end of the input up to which the output is valid after adding the closers.
+	safeEnd     int
+	safeClosers string
+	// The end and the suffix of the last output, to know if it changed.
+	outputEnd    int
+	outputSuffix string
+}
+
+// NewCompleter creates a completer without input.
+func NewCompleter() *Completer {
+	return &Completer{token: -1}
+}
+
+// Complete returns a valid JSON document from the given prefix of a JSON document and
+// true if it has at least one value.
+//
+// The objects and arrays that are open are closed, and the values that are not complete
+// are removed, except the strings, which are closed so their text can be shown while
+// it's generated. Numbers and literals (true, false, null) at the end of the input are
+// removed because more characters can follow them, and so are the keys without value.
+//
+// Any text before the first { or [, like markdown code fences, and after the end of the
+// root value is ignored.
+func Complete(input string) (string, bool) {
+	c := NewCompleter()
+	c.Feed(input)
+	return c.Complete()
+}
+
+// Feed adds the next piece of the input and returns true if the completed output
+// changed.
+func (c *Completer) Feed(delta string) bool {
+	if c.done {
+		return false
+	}
+	if c.input == nil {
+		start := strings.IndexAny(delta, "{[")
+		if start == -1 {
+			return false
+		}
+		c.input = []byte{}
+		delta = delta[start:]
+	}
+
+	c.input = append(c.input, delta...)
+	c.scan()
+
+	end, suffix := c.output()
+	if end == c.outputEnd && suffix == c.outputSuffix {
+		return false
+	}
+	c.outputEnd, c.outputSuffix = end, suffix
+	return true
+}
+
+// Complete returns the completed output of the input received so far, see the Complete
+// function.
+func (c *Completer) Complete() (string, bool) {
+	if c.outputEnd == 0 {
+		return "", false
+	}
+
+	output := string(c.input[:c.outputEnd]) + c.outputSuffix
+	if !json.Valid([]byte(output)) {
+		return "", false
+	}
+	return output, true
+}
+
+// scan scans the input from the last position scanned.
+func (c *Completer) scan() {
+	for !c.done && c.pos < len(c.input) {
+		if c.token != -1 {
+			if !c.scanToken() {
+				return
+			}
+			continue
+		}
+
+		switch ch := c.input[c.pos]; {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == ':':
+			c.pos++
+		case ch == ',':
+			if top := c.top(); top != nil && top.object {
+				top.key = true
+			}
+			c.pos++
+		case ch == '{' || ch == '[':
+			c.stack = append(c.stack, frame{object: ch == '{', key: ch == '{'})
+			c.pos++
+			c.markSafe(c.pos)
+		case ch == '}' || ch == ']':
+			if len(c.stack) == 0 {
+				c.done = true
+				return
+			}
+			c.stack = c.stack[:len(c.stack)-1]
+			c.pos++
+			c.markSafe(c.pos)
+			if len(c.stack) == 0 {
+				c.done = true
+			}
+		default:
+			c.token = c.pos
+		}
+	}
+}
+
+// scanToken scans the string, number or literal that starts at the token position and
+// returns false if the input ends before the token does.
+func (c *Completer) scanToken() bool {
+	if c.input[c.token] != '"' {
+		for c.pos < len(c.input) && strings.IndexByte("+-.0123456789eEabcdefghijklmnopqrstuvwxyz", c.input[c.pos]) != -1 {
+			c.pos++
+		}
+		if c.pos == len(c.input) {
+			// A value that can continue
+			return false
+		}
+		if c.pos == c.token {
+			// Unknown characters
+			c.done = true
+			return false
+		}
+		c.token = -1
+		c.markSafe(c.pos)
+		return true
+	}
+
+	if c.pos == c.token {
+		c.pos++
+	}
+	for c.pos < len(c.input) {
+		switch c.input[c.pos] {
+		case '\\':
+			// The position stays before an incomplete escape sequence
+			length := 2
+			if c.pos+1 < len(c.input) && c.input[c.pos+1] == 'u' {
+				length = 6
+			}
+			if c.pos+length > len(c.input) {
+				return false
+			}
+			c.pos += length
+		case '"':
+			c.pos++
+			c.token = -1
+			if top := c.top(); top != nil && top.object && top.key {
+				top.key = false
+			} else {
+				c.markSafe(c.pos)
+			}
+			return true
+		default:
+			c.pos++
+		}
+	}
+	return false
+}
+
+// top returns the innermost open object or array, or nil if there is none.
+func (c *Completer) top() *frame {
+	if len(c.stack) == 0 {
+		return nil
+	}
+	return &c.stack[len(c.stack)-1]
+}
+
+// closers returns the characters that close the open objects and arrays.
+func (c *Completer) closers() string {
+	closers := make([]byte, 0, len(c.stack))
+	for i := len(c.stack) - 1; i >= 0; i-- {
+		if c.stack[i].object {
+			closers = append(closers, '}')
+		} else {
+			closers = append(closers, ']')
+		}
+	}
+	return string(closers)
+}
+
+// markSafe records that the input up to end is valid after closing the open objects
+// and arrays.
+func (c *Completer) markSafe(end int) {
+	c.safeEnd = end
+	c.safeClosers = c.closers()
+}
+
+// output returns the end of the input and the suffix of the completed output.
+//
+// A string value that is not complete is closed, without any incomplete escape
+// sequence or character.
+func (c *Completer) output() (int, string) {
+	if c.done || c.token == -1 || c.input[c.token] != '"' {
+		return c.safeEnd, c.safeClosers
+	}
+	if top := c.top(); top != nil && top.object && top.key {
+		return c.safeEnd, c.safeClosers
+	}
+
+	end := c.pos
+	for i := end - 1; i > c.token && i >= end-utf8.UTFMax; i-- {
+		if utf8.RuneStart(c.input[i]) {
+			if !utf8.FullRune(c.input[i:end]) {
+				end = i
+			}
+			break
+		}
+	}
+	return end, `"` + c.closers()
+}
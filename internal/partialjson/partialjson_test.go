@@ -0,0 +1,75 @@
+package partialjson
+
+import (
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"complete object", `{"a": 1}`, `{"a": 1}`},
+		{"empty object", `{`, `{}`},
+		{"empty array", `[`, `[]`},
+		{"dangling key", `{"a": 1, "b`, `{"a": 1}`},
+		{"key without value", `{"a": 1, "b":`, `{"a": 1}`},
+		{"partial string", `{"a": "hel`, `{"a": "hel"}`},
+		{"partial string in array", `["a", "b`, `["a", "b"]`},
+		{"incomplete escape", `{"a": "line\`, `{"a": "line"}`},
+		{"incomplete unicode escape", `{"a": "x\u00`, `{"a": "x"}`},
+		{"complete escape", `{"a": "x\"y`, `{"a": "x\"y"}`},
+		{"trailing number", `{"a": 1, "b": 12`, `{"a": 1}`},
+		{"trailing literal", `[true, fal`, `[true]`},
+		{"number followed by comma", `[1, 2,`, `[1, 2]`},
+		{"nested", `{"a": [{"b": 1}, {"c": "x`, `{"a": [{"b": 1}, {"c": "x"}]}`},
+		{"nested empty", `{"a": {"b": [`, `{"a": {"b": []}}`},
+		{"code fence", "```json\n{\"a\": \"b", `{"a": "b"}`},
+		{"text after root", `{"a": 1} done`, `{"a": 1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, ok := Complete(tt.input)
+			assert.True(t, ok)
+			assert.Equal(t, tt.expected, output)
+		})
+	}
+}
+
+func TestCompleteNoValue(t *testing.T) {
+	for _, input := range []string{"", "Sure", "```json\n", `123`} {
+		_, ok := Complete(input)
+		assert.False(t, ok)
+	}
+}
+
+func TestCompleteEveryPrefix(t *testing.T) {
+	input := `{"name": "Ada \"L\" é", "tags": ["a", "b"], "n": -1.5e3, "ok": true, "x": null, "o": {"p": []}}`
+	for i := 1; i <= len(input); i++ {
+		_, ok := Complete(input[:i])
+		assert.True(t, ok)
+	}
+}
+
+func TestCompleterFeed(t *testing.T) {
+	input := "```json\n" + `{"name": "Ada \"L\" é é", "tags": ["a", "b"], "n": -1.5e3, "ok": true, "o": {"p": []}} done`
+
+	// Feeding the input byte by byte returns the same output as completing each prefix,
+	// and reports every change of the output
+	c := NewCompleter()
+	last := ""
+	for i := 1; i <= len(input); i++ {
+		changed := c.Feed(input[i-1 : i])
+		output, _ := c.Complete()
+		expected, _ := Complete(input[:i])
+		assert.Equal(t, expected, output)
+		if expected != last {
+			assert.True(t, changed)
+		}
+		last = expected
+	}
+}
@@ -0,0 +1,77 @@
+package openroutergo
+
+import (
+	"fmt"
+
+	"github.com/zachczx/openroutergo/internal/jsonrepair"
+)
+
+// RepairJSON returns a valid JSON document from the JSON generated by a model and true
+// if it had to be repaired.
+//
+// Smaller models frequently generate malformed JSON. If the input is already valid JSON
+// it's returned unchanged, otherwise the following problems are fixed:
+//
+//   - Markdown code fences and text before or after the JSON value.
+//   - Trailing commas and missing commas between values.
+//   - Single quoted strings and unquoted object keys.
+//   - Unterminated strings, objects and arrays (for example, truncated outputs).
+//   - Comments, Python literals (True, False, None) and invalid numbers.
+//   - Raw new lines and control characters inside strings.
+//
+// Returns ErrUnrepairableJSON if the input can't be repaired.
+func RepairJSON(input string) (string, bool, error) {
+	output, repaired, err := jsonrepair.Repair(input)
+	if err != nil {
+		return "", false, fmt.Errorf("%w: %w", ErrUnrepairableJSON, err)
+	}
+	return output, repaired, nil
+}
+
+// WithJSONRepair sets whether the JSON generated by the model should be automatically
+// repaired when it's malformed, see RepairJSON for the problems that are fixed.
+//
+// When enabled, the following values are repaired in the response before it's returned
+// and added to the conversation:
+//
+//   - The arguments of the tool calls, the ArgumentsRepaired field of the function is
+//     set to true when they are repaired.
+//   - The message content when a JSON response format is set with WithResponseFormat,
+//     the ContentRepaired field of the message is set to true when it's repaired.
+//
+// Values that can't be repaired are returned unchanged.
+//
+//   - Default: false
+func (b *chatCompletionBuilder) WithJSONRepair(enabled bool) *chatCompletionBuilder {
+	b.jsonRepair = enabled
+	return b
+}
+
+// repairResponse repairs the JSON values generated by the model in the response, see
+// WithJSONRepair.
+func (b *chatCompletionBuilder) repairResponse(response *ChatCompletionResponse) {
+	repairContent := false
+	if b.responseFormat.IsSet {
+		formatType, _ := b.responseFormat.Value["type"].(string)
+		repairContent = formatType == "json_object" || formatType == "json_schema"
+	}
+
+	for i := range response.Choices {
+		message := &response.Choices[i].Message
+
+		for j := range message.ToolCalls {
+			function := &message.ToolCalls[j].Function
+			if output, repaired, err := jsonrepair.Repair(function.Arguments); err == nil && repaired {
+				function.Arguments = output
+				function.ArgumentsRepaired = true
+			}
+		}
+
+		if repairContent && message.Content != "" {
+			if output, repaired, err := jsonrepair.Repair(message.Content); err == nil && repaired {
+				message.Content = output
+				message.ContentRepaired = true
+			}
+		}
+	}
+}
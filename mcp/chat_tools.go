@@ -0,0 +1,433 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/zachczx/openroutergo"
+	"github.com/zachczx/openroutergo/internal/optional"
+)
+
+const (
+	// ChatToolName is the name of the tool that sends a conversation to a model.
+	ChatToolName = "chat"
+	// ListModelsToolName is the name of the tool that lists the available models.
+	ListModelsToolName = "list_models"
+
+	defaultListModelsLimit = 50
+)
+
+// chatToolsBuilder is a chainable builder for the OpenRouter chat tools.
+type chatToolsBuilder struct {
+	client             *openroutergo.Client
+	allowedModels      []openroutergo.ModelID
+	defaultModel       optional.String
+	maxPromptPrice     optional.Float64
+	maxCompletionPrice optional.Float64
+	maxTokens          optional.Int
+	budget             optional.Float64
+	toolNamePrefix     string
+}
+
+// NewChatTools starts the creation of the tools that let MCP hosts use OpenRouter
+// models through the given client:
+//
+//   - chat: sends a conversation to a model and returns its response.
+//   - list_models: lists the models that can be used with the chat tool.
+//
+// Use them with the NewServer function to delegate questions to other models from any
+// MCP host:
+//
+//	tools, err := mcp.NewChatTools(client).
+//		WithAllowedModels("openai/gpt-4o-mini", "anthropic/claude-3.5-haiku").
+//		WithBudget(5).
+//		Create()
+//	if err != nil {
+//		return err
+//	}
+//
+//	err = mcp.NewServer("openroutergo", "1.0.0").WithTools(tools...).Create().ServeStdio(ctx)
+func NewChatTools(client *openroutergo.Client) *chatToolsBuilder {
+	return &chatToolsBuilder{
+		client:             client,
+		allowedModels:      []openroutergo.ModelID{},
+		defaultModel:       optional.String{IsSet: false},
+		maxPromptPrice:     optional.Float64{IsSet: false},
+		maxCompletionPrice: optional.Float64{IsSet: false},
+		maxTokens:          optional.Int{IsSet: false},
+		budget:             optional.Float64{IsSet: false},
+		toolNamePrefix:     "",
+	}
+}
+
+// WithAllowedModels restricts the models that can be used with the chat tool.
+//
+// If not set, all the models available on OpenRouter can be used.
+func (b *chatToolsBuilder) WithAllowedModels(models ...openroutergo.ModelID) *chatToolsBuilder {
+	b.allowedModels = append(b.allowedModels, models...)
+	return b
+}
+
+// WithDefaultModel sets the model used when the host doesn't choose one.
+//
+// If not set, the host must always choose the model.
+func (b *chatToolsBuilder) WithDefaultModel(model openroutergo.ModelID) *chatToolsBuilder {
+	b.defaultModel = optional.String{IsSet: true, Value: string(model)}
+	return b
+}
+
+// WithMaxPrice sets the maximum price accepted for each chat request in USD per million
+// prompt and completion tokens, see the WithMaxPrice method of the chat completion.
+//
+// Models above the price are also hidden from the list_models tool.
+func (b *chatToolsBuilder) WithMaxPrice(maxPromptPrice float64, maxCompletionPrice float64) *chatToolsBuilder {
+	b.maxPromptPrice = optional.Float64{IsSet: true, Value: maxPromptPrice}
+	b.maxCompletionPrice = optional.Float64{IsSet: true, Value: maxCompletionPrice}
+	return b
+}
+
+// WithMaxTokens limits the number of tokens generated by each chat request, the host
+// can ask for less tokens but not for more.
+func (b *chatToolsBuilder) WithMaxTokens(maxTokens int) *chatToolsBuilder {
+	b.maxTokens = optional.Int{IsSet: true, Value: maxTokens}
+	return b
+}
+
+// WithBudget sets the total amount in USD that can be spent by the chat tool, once it's
+// exhausted all the chat requests fail.
+//
+// The cost of each request is calculated from the usage and the pricing of the model
+// that answered it. Before sending a request its maximum cost is reserved, and it's
+// rejected if it doesn't fit in the remaining budget. Requests to models whose price is
+// unknown fail with ErrUnknownCost.
+func (b *chatToolsBuilder) WithBudget(budget float64) *chatToolsBuilder {
+	b.budget = optional.Float64{IsSet: true, Value: budget}
+	return b
+}
+
+// WithToolNamePrefix adds a prefix to the names of the tools, this avoids name
+// collisions with the tools of other servers.
+func (b *chatToolsBuilder) WithToolNamePrefix(prefix string) *chatToolsBuilder {
+	b.toolNamePrefix = prefix
+	return b
+}
+
+// Create builds and returns the chat and list_models tools.
+func (b *chatToolsBuilder) Create() ([]openroutergo.Tool, error) {
+	if b.client == nil {
+		return nil, ErrClientRequired
+	}
+	for _, model := range b.allowedModels {
+		if err := model.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if b.defaultModel.IsSet {
+		if err := b.checkAllowed(openroutergo.ModelID(b.defaultModel.Value)); err != nil {
+			return nil, err
+		}
+	}
+
+	state := &chatToolsState{
+		config:   *b,
+		mu:       sync.Mutex{},
+		spent:    0,
+		reserved: 0,
+	}
+
+	chatTool, err := openroutergo.NewTool(
+		b.toolNamePrefix+ChatToolName,
+		"Sends a conversation to another AI model through OpenRouter and returns its response. "+
+			"Use it to delegate questions or get a second opinion.",
+		state.chat,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Show the allowed models in the schema so the host doesn't have to list them
+	if len(b.allowedModels) > 0 {
+		if properties, ok := chatTool.Definition.Parameters["properties"].(map[string]any); ok {
+			if model, ok := properties["model"].(map[string]any); ok {
+				model["enum"] = b.allowedModels
+			}
+		}
+	}
+
+	listModelsTool, err := openroutergo.NewTool(
+		b.toolNamePrefix+ListModelsToolName,
+		"Lists the AI models that can be used with the "+b.toolNamePrefix+ChatToolName+" tool, "+
+			"with their context length and prices.",
+		state.listModels,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return []openroutergo.Tool{chatTool, listModelsTool}, nil
+}
+
+// checkAllowed returns an error if the model is not in the allowed models.
+func (b *chatToolsBuilder) checkAllowed(model openroutergo.ModelID) error {
+	if len(b.allowedModels) == 0 || slices.Contains(b.allowedModels, model) {
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrModelNotAllowed, model)
+}
+
+// chatToolsState is the state shared by the chat tools.
+type chatToolsState struct {
+	config chatToolsBuilder
+
+	mu    sync.Mutex
+	spent float64
+	// reserved is the maximum cost of the requests that are running
+	reserved float64
+}
+
+// modelInfo returns the model with the given ID from the models catalog of the client,
+// or ErrUnknownCost if it can't be found.
+func (s *chatToolsState) modelInfo(ctx context.Context, id openroutergo.ModelID) (openroutergo.Model, error) {
+	info, err := s.config.client.GetModel(ctx, id)
+	if errors.Is(err, openroutergo.ErrModelNotFound) {
+		return openroutergo.Model{}, fmt.Errorf("%w of the model %q: it's not in the catalog", ErrUnknownCost, id)
+	}
+	if err != nil {
+		return openroutergo.Model{}, fmt.Errorf("%w of the model %q: %w", ErrUnknownCost, id, err)
+	}
+	return info, nil
+}
+
+type chatArgs struct {
+	Model       string        `json:"model,omitempty" jsonschema:"description=The ID of the model\\, for example openai/gpt-4o-mini"`
+	Messages    []chatMessage `json:"messages" jsonschema:"minItems=1,description=The conversation to send to the model"`
+	Temperature *float64      `json:"temperature" jsonschema:"minimum=0,maximum=2"`
+	MaxTokens   *int          `json:"max_tokens" jsonschema:"minimum=1,description=The maximum number of tokens to generate"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role" jsonschema:"enum=system|user|assistant"`
+	Content string `json:"content"`
+}
+
+type chatResult struct {
+	Model            string   `json:"model"`
+	Content          string   `json:"content"`
+	FinishReason     string   `json:"finish_reason"`
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	Cost             float64  `json:"cost"`
+	BudgetRemaining  *float64 `json:"budget_remaining,omitempty"`
+}
+
+func (s *chatToolsState) chat(ctx context.Context, args chatArgs) (chatResult, error) {
+	model := openroutergo.ModelID(args.Model)
+	if model == "" {
+		if !s.config.defaultModel.IsSet {
+			return chatResult{}, fmt.Errorf("the model is required")
+		}
+		model = openroutergo.ModelID(s.config.defaultModel.Value)
+	}
+	if err := s.config.checkAllowed(model); err != nil {
+		return chatResult{}, err
+	}
+
+	completion := s.config.client.NewChatCompletion().WithContext(ctx).WithModelID(model)
+	for _, message := range args.Messages {
+		switch message.Role {
+		case "system":
+			completion.WithSystemMessage(message.Content)
+		case "assistant":
+			completion.WithAssistantMessage(message.Content)
+		default:
+			completion.WithUserMessage(message.Content)
+		}
+	}
+	if args.Temperature != nil {
+		completion.WithTemperature(*args.Temperature)
+	}
+
+	maxTokens := optional.Int{IsSet: false}
+	if args.MaxTokens != nil {
+		maxTokens = optional.Int{IsSet: true, Value: *args.MaxTokens}
+	}
+	if s.config.maxTokens.IsSet && (!maxTokens.IsSet || maxTokens.Value > s.config.maxTokens.Value) {
+		maxTokens = s.config.maxTokens
+	}
+	if maxTokens.IsSet {
+		completion.WithMaxTokens(maxTokens.Value)
+	}
+	if s.config.maxPromptPrice.IsSet && s.config.maxCompletionPrice.IsSet {
+		completion.WithMaxPrice(s.config.maxPromptPrice.Value, s.config.maxCompletionPrice.Value)
+	}
+
+	reservation, err := s.reserve(ctx, model, completion.EstimatePromptTokens(), maxTokens)
+	if err != nil {
+		return chatResult{}, err
+	}
+
+	_, response, err := completion.Execute()
+	if err != nil {
+		s.settle(reservation, 0)
+		return chatResult{}, err
+	}
+
+	// Without a budget the cost is informative, it's 0 if the price is unknown
+	cost, err := s.cost(ctx, response, model)
+	if err != nil && s.config.budget.IsSet {
+		// The budget can't be enforced without the cost, so it's considered exhausted
+		s.settle(reservation, s.config.budget.Value)
+		return chatResult{}, err
+	}
+	remaining := s.settle(reservation, cost)
+
+	if len(response.Choices) == 0 {
+		return chatResult{}, fmt.Errorf("the model returned no choices")
+	}
+
+	result := chatResult{
+		Model:            response.Model,
+		Content:          response.Choices[0].Message.Content,
+		FinishReason:     response.Choices[0].FinishReason.String(),
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+		Cost:             cost,
+		BudgetRemaining:  nil,
+	}
+	if s.config.budget.IsSet {
+		result.BudgetRemaining = &remaining
+	}
+
+	return result, nil
+}
+
+// reserve checks that the budget is not exhausted and reserves the maximum cost of the
+// request until it's settled, so concurrent requests can't exceed the budget.
+//
+// The maximum cost is estimated from the prompt tokens, the maximum tokens of the
+// completion and the pricing of the model. Requests to models without a known price
+// are rejected when there is a budget.
+func (s *chatToolsState) reserve(ctx context.Context, model openroutergo.ModelID, promptTokens int, maxTokens optional.Int) (float64, error) {
+	if !s.config.budget.IsSet {
+		return 0, nil
+	}
+
+	// The catalog can be requested, so the lock is only held for the budget arithmetic
+	info, err := s.modelInfo(ctx, model)
+	if err != nil {
+		return 0, err
+	}
+
+	completionTokens := info.ContextLength - promptTokens
+	if info.TopProvider.MaxCompletionTokens > 0 {
+		completionTokens = info.TopProvider.MaxCompletionTokens
+	}
+	if maxTokens.IsSet {
+		completionTokens = maxTokens.Value
+	}
+	reservation := info.Pricing.Cost(openroutergo.ChatCompletionResponseUsage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: max(completionTokens, 0),
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.spent+s.reserved+reservation > s.config.budget.Value {
+		return 0, fmt.Errorf(
+			"%w: $%.4f of $%.4f spent and $%.4f reserved by running requests",
+			ErrBudgetExhausted, s.spent, s.config.budget.Value, s.reserved,
+		)
+	}
+	s.reserved += reservation
+	return reservation, nil
+}
+
+// settle releases the reservation of a request and adds its cost to the spent amount,
+// it returns the remaining budget.
+func (s *chatToolsState) settle(reservation float64, cost float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reserved -= reservation
+	s.spent += cost
+	return max(s.config.budget.Value-s.spent, 0)
+}
+
+// cost returns the cost of the request from the usage and the pricing of the model that
+// answered it, which can be different from the requested model, for example, with
+// fallbacks.
+func (s *chatToolsState) cost(ctx context.Context, response openroutergo.ChatCompletionResponse, requested openroutergo.ModelID) (float64, error) {
+	model := openroutergo.ModelID(response.Model)
+	if model == "" {
+		model = requested
+	}
+
+	info, err := s.modelInfo(ctx, model)
+	if err != nil {
+		return 0, err
+	}
+	return info.Pricing.Cost(response.Usage), nil
+}
+
+type listModelsArgs struct {
+	Query string `json:"query,omitempty" jsonschema:"description=Only list the models whose ID or name contain this text"`
+	Limit int    `json:"limit,omitempty" jsonschema:"minimum=1,description=The maximum number of models to list (default 50)"`
+}
+
+type listModelsResult struct {
+	ID              string  `json:"id"`
+	Name            string  `json:"name"`
+	ContextLength   int     `json:"context_length"`
+	PromptPrice     float64 `json:"prompt_price_per_million"`
+	CompletionPrice float64 `json:"completion_price_per_million"`
+}
+
+func (s *chatToolsState) listModels(ctx context.Context, args listModelsArgs) ([]listModelsResult, error) {
+	models, err := s.config.client.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultListModelsLimit
+	}
+	query := strings.ToLower(args.Query)
+
+	results := []listModelsResult{}
+	for _, model := range models {
+		if len(results) >= limit {
+			break
+		}
+		if s.config.checkAllowed(model.ID) != nil {
+			continue
+		}
+		if query != "" &&
+			!strings.Contains(strings.ToLower(string(model.ID)), query) &&
+			!strings.Contains(strings.ToLower(model.Name), query) {
+			continue
+		}
+
+		promptPrice := model.Pricing.PromptPrice() * 1_000_000
+		completionPrice := model.Pricing.CompletionPrice() * 1_000_000
+		if s.config.maxPromptPrice.IsSet && promptPrice > s.config.maxPromptPrice.Value {
+			continue
+		}
+		if s.config.maxCompletionPrice.IsSet && completionPrice > s.config.maxCompletionPrice.Value {
+			continue
+		}
+
+		results = append(results, listModelsResult{
+			ID:              string(model.ID),
+			Name:            model.Name,
+			ContextLength:   model.ContextLength,
+			PromptPrice:     promptPrice,
+			CompletionPrice: completionPrice,
+		})
+	}
+
+	return results, nil
+}
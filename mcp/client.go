@@ -0,0 +1,378 @@
+// Package mcp connects OpenRouter models with the Model Context Protocol.
+//
+//   - https://modelcontextprotocol.io/specification/2025-06-18
+//
+// The Client connects to an MCP server, using the stdio or the streamable HTTP
+// transport, and exposes its tools as [openroutergo.Tool] values so they can be used
+// by any model that supports tools:
+//
+//	client, err := mcp.NewClient().
+//		WithStdioCommand("npx", "-y", "@modelcontextprotocol/server-everything").
+//		Connect(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	defer client.Close()
+//
+//	tools, err := client.Tools(ctx)
+//	if err != nil {
+//		return err
+//	}
+//
+//	result, err := completion.
+//		WithToolRegistry(openroutergo.NewToolRegistry(tools...)).
+//		RunWithTools(ctx)
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// ProtocolVersion is the latest version of the Model Context Protocol supported by
+// this package.
+const ProtocolVersion = "2025-06-18"
+
+// supportedProtocolVersions are the versions of the protocol that this package can
+// speak, the first one is preferred.
+var supportedProtocolVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// Implementation describes the name and version of an MCP client or server.
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// transport sends and receives JSON-RPC messages to and from the other side of the
+// connection.
+type transport interface {
+	start(ctx context.Context) error
+	send(ctx context.Context, message jsonRPCMessage) error
+	// receive returns the channel of incoming messages, it's closed when the
+	// connection is closed.
+	receive() <-chan jsonRPCMessage
+	close() error
+}
+
+// Client is a connection with an MCP server.
+//
+// Create it using the NewClient function, it's safe for concurrent use.
+type Client struct {
+	transport      transport
+	toolNamePrefix string
+
+	nextID    atomic.Int64
+	mu        sync.Mutex
+	pending   map[string]chan jsonRPCMessage
+	done      chan struct{}
+	closeOnce sync.Once
+
+	protocolVersion string
+	serverInfo      Implementation
+	instructions    string
+}
+
+// clientBuilder is a chainable builder for the MCP client.
+type clientBuilder struct {
+	clientInfo     Implementation
+	toolNamePrefix string
+
+	// stdio transport options
+	command string
+	args    []string
+	env     []string
+	stderr  io.Writer
+
+	// streamable HTTP transport options
+	url        string
+	httpClient *http.Client
+	headers    http.Header
+}
+
+// NewClient starts the creation of a new MCP client, a transport must be chosen using
+// the WithStdioCommand or WithStreamableHTTP methods.
+func NewClient() *clientBuilder {
+	return &clientBuilder{
+		clientInfo:     Implementation{Name: "openroutergo", Version: "1.0.0"},
+		toolNamePrefix: "",
+		command:        "",
+		args:           []string{},
+		env:            []string{},
+		stderr:         nil,
+		url:            "",
+		httpClient:     http.DefaultClient,
+		headers:        http.Header{},
+	}
+}
+
+// WithStdioCommand uses the stdio transport, the server is started as a subprocess
+// running the given command and is stopped when the client is closed.
+func (b *clientBuilder) WithStdioCommand(command string, args ...string) *clientBuilder {
+	b.command = command
+	b.args = args
+	b.url = ""
+	return b
+}
+
+// WithEnv adds environment variables ("KEY=value") to the server subprocess, it
+// inherits the environment of the current process. Only used by the stdio transport.
+func (b *clientBuilder) WithEnv(env ...string) *clientBuilder {
+	b.env = append(b.env, env...)
+	return b
+}
+
+// WithStderr sets where the stderr of the server subprocess is written, usually logs.
+// Only used by the stdio transport.
+//
+// If not set, the stderr is discarded.
+func (b *clientBuilder) WithStderr(stderr io.Writer) *clientBuilder {
+	b.stderr = stderr
+	return b
+}
+
+// WithStreamableHTTP uses the streamable HTTP transport to connect to the server
+// endpoint at the given URL.
+func (b *clientBuilder) WithStreamableHTTP(url string) *clientBuilder {
+	b.url = url
+	b.command = ""
+	return b
+}
+
+// WithHTTPClient sets a custom HTTP client for the streamable HTTP transport.
+//
+// If not set, the default HTTP client will be used.
+func (b *clientBuilder) WithHTTPClient(httpClient *http.Client) *clientBuilder {
+	b.httpClient = httpClient
+	return b
+}
+
+// WithHeader adds a header to all the requests of the streamable HTTP transport, for
+// example, an Authorization header.
+func (b *clientBuilder) WithHeader(key string, value string) *clientBuilder {
+	b.headers.Add(key, value)
+	return b
+}
+
+// WithClientInfo sets the name and version of the client sent to the server.
+//
+// If not set, "openroutergo" is used.
+func (b *clientBuilder) WithClientInfo(name string, version string) *clientBuilder {
+	b.clientInfo = Implementation{Name: name, Version: version}
+	return b
+}
+
+// WithToolNamePrefix adds a prefix to the names of the tools returned by the Tools
+// method, this avoids name collisions when the tools of several servers are used in
+// the same conversation. The prefix is removed before calling the server.
+func (b *clientBuilder) WithToolNamePrefix(prefix string) *clientBuilder {
+	b.toolNamePrefix = prefix
+	return b
+}
+
+// Connect starts the transport and initializes the MCP session with the server.
+func (b *clientBuilder) Connect(ctx context.Context) (*Client, error) {
+	var t transport
+	switch {
+	case b.command != "":
+		t = newStdioTransport(b.command, b.args, b.env, b.stderr)
+	case b.url != "":
+		t = newHTTPTransport(b.url, b.httpClient, b.headers)
+	default:
+		return nil, ErrTransportRequired
+	}
+
+	if err := t.start(ctx); err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		transport:       t,
+		toolNamePrefix:  b.toolNamePrefix,
+		nextID:          atomic.Int64{},
+		mu:              sync.Mutex{},
+		pending:         map[string]chan jsonRPCMessage{},
+		done:            make(chan struct{}),
+		closeOnce:       sync.Once{},
+		protocolVersion: "",
+		serverInfo:      Implementation{},
+		instructions:    "",
+	}
+	go c.readLoop()
+
+	if err := c.initialize(ctx, b.clientInfo); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// initialize runs the initialization handshake with the server.
+func (c *Client) initialize(ctx context.Context, clientInfo Implementation) error {
+	params := map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      clientInfo,
+	}
+
+	var result struct {
+		ProtocolVersion string         `json:"protocolVersion"`
+		ServerInfo      Implementation `json:"serverInfo"`
+		Instructions    string         `json:"instructions"`
+	}
+	if err := c.call(ctx, "initialize", params, &result); err != nil {
+		return fmt.Errorf("mcp: failed to initialize the session: %w", err)
+	}
+
+	if !slices.Contains(supportedProtocolVersions, result.ProtocolVersion) {
+		return fmt.Errorf("%w: %q", ErrUnsupportedProtocolVersion, result.ProtocolVersion)
+	}
+
+	c.protocolVersion = result.ProtocolVersion
+	c.serverInfo = result.ServerInfo
+	c.instructions = result.Instructions
+	if t, ok := c.transport.(*httpTransport); ok {
+		t.setProtocolVersion(result.ProtocolVersion)
+	}
+
+	return c.notify(ctx, "notifications/initialized", nil)
+}
+
+// ServerInfo returns the name and version of the server.
+func (c *Client) ServerInfo() Implementation {
+	return c.serverInfo
+}
+
+// ProtocolVersion returns the version of the protocol negotiated with the server.
+func (c *Client) ProtocolVersion() string {
+	return c.protocolVersion
+}
+
+// Instructions returns the instructions sent by the server describing how to use it,
+// they can be added to the system prompt.
+func (c *Client) Instructions() string {
+	return c.instructions
+}
+
+// Ping checks that the server is still responding.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.call(ctx, "ping", nil, nil)
+}
+
+// Close closes the connection with the server, for the stdio transport it also stops
+// the server subprocess.
+func (c *Client) Close() error {
+	err := c.transport.close()
+	c.closeOnce.Do(func() { close(c.done) })
+	return err
+}
+
+// readLoop dispatches the incoming messages until the connection is closed.
+func (c *Client) readLoop() {
+	defer c.closeOnce.Do(func() { close(c.done) })
+
+	for message := range c.transport.receive() {
+		switch {
+		case message.isRequest():
+			go c.handleRequest(message)
+		case message.isNotification():
+			// Notifications like logs or list changes are not used
+		default:
+			c.mu.Lock()
+			ch, ok := c.pending[string(message.ID)]
+			delete(c.pending, string(message.ID))
+			c.mu.Unlock()
+			if ok {
+				ch <- message
+			}
+		}
+	}
+}
+
+// handleRequest answers the requests sent by the server, only ping is supported.
+func (c *Client) handleRequest(request jsonRPCMessage) {
+	response := newErrorResponse(request.ID, CodeMethodNotFound, "method not found: "+request.Method)
+	if request.Method == "ping" {
+		response, _ = newResponse(request.ID, map[string]any{})
+	}
+	_ = c.transport.send(context.Background(), response)
+}
+
+// call sends a request to the server and decodes the result into result, if not nil.
+func (c *Client) call(ctx context.Context, method string, params any, result any) error {
+	id := strconv.FormatInt(c.nextID.Add(1), 10)
+	request := jsonRPCMessage{JSONRPC: jsonRPCVersion, ID: json.RawMessage(id), Method: method}
+	if params != nil {
+		encoded, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("mcp: failed to encode the params: %w", err)
+		}
+		request.Params = encoded
+	}
+
+	ch := make(chan jsonRPCMessage, 1)
+	c.mu.Lock()
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.transport.send(ctx, request); err != nil {
+		return err
+	}
+
+	var response jsonRPCMessage
+	select {
+	case response = <-ch:
+	case <-c.done:
+		// The response may have been delivered right before the connection was closed
+		select {
+		case response = <-ch:
+		default:
+			return ErrClosed
+		}
+	case <-ctx.Done():
+		c.cancelRequest(id, ctx.Err())
+		return ctx.Err()
+	}
+
+	if response.Error != nil {
+		return response.Error
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("mcp: failed to decode the result of %s: %w", method, err)
+	}
+	return nil
+}
+
+// cancelRequest tells the server that the result of a request is no longer needed.
+func (c *Client) cancelRequest(id string, reason error) {
+	params := map[string]any{"requestId": json.RawMessage(id), "reason": reason.Error()}
+	_ = c.notify(context.Background(), "notifications/cancelled", params)
+}
+
+// notify sends a notification to the server.
+func (c *Client) notify(ctx context.Context, method string, params any) error {
+	notification := jsonRPCMessage{JSONRPC: jsonRPCVersion, Method: method}
+	if params != nil {
+		encoded, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("mcp: failed to encode the params: %w", err)
+		}
+		notification.Params = encoded
+	}
+	return c.transport.send(ctx, notification)
+}
@@ -0,0 +1,289 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zachczx/openroutergo"
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+// TestMain runs the fake MCP server when the test binary is started by the stdio
+// transport.
+func TestMain(m *testing.M) {
+	switch os.Getenv("MCP_FAKE_SERVER") {
+	case "1":
+		runFakeStdioServer()
+		os.Exit(0)
+	case "with_child":
+		// Like npx, it starts a child process that inherits the stdout
+		child := exec.Command(os.Args[0])
+		child.Env = append(os.Environ(), "MCP_FAKE_SERVER=child")
+		child.Stdout = os.Stdout
+		if err := child.Start(); err != nil {
+			os.Exit(1)
+		}
+		runFakeStdioServer()
+		os.Exit(0)
+	case "child":
+		time.Sleep(30 * time.Second)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func runFakeStdioServer() {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		var request jsonRPCMessage
+		if err := json.Unmarshal(line, &request); err != nil {
+			continue
+		}
+		if response, ok := handleFakeRequest(request); ok {
+			data, _ := json.Marshal(response)
+			_, _ = os.Stdout.Write(append(data, '\n'))
+		}
+	}
+}
+
+// handleFakeRequest implements a small MCP server with two pages of tools: echo and
+// fail.
+func handleFakeRequest(request jsonRPCMessage) (jsonRPCMessage, bool) {
+	if request.isNotification() {
+		return jsonRPCMessage{}, false
+	}
+
+	var params struct {
+		Cursor    string          `json:"cursor"`
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	_ = json.Unmarshal(request.Params, &params)
+
+	var result any
+	switch request.Method {
+	case "initialize":
+		result = map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "fake", "version": "0.1.0"},
+			"instructions":    "Use the echo tool.",
+		}
+	case "ping":
+		result = map[string]any{}
+	case "tools/list":
+		if params.Cursor == "" {
+			result = map[string]any{
+				"tools": []any{map[string]any{
+					"name":        "echo",
+					"description": "Echoes the text",
+					"inputSchema": map[string]any{
+						"type":       "object",
+						"properties": map[string]any{"text": map[string]any{"type": "string"}},
+						"required":   []string{"text"},
+					},
+				}},
+				"nextCursor": "page-2",
+			}
+		} else {
+			result = map[string]any{
+				"tools": []any{map[string]any{"name": "fail", "title": "Always fails"}},
+			}
+		}
+	case "tools/call":
+		switch params.Name {
+		case "echo":
+			var args struct {
+				Text string `json:"text"`
+			}
+			_ = json.Unmarshal(params.Arguments, &args)
+			result = map[string]any{"content": []any{map[string]any{"type": "text", "text": "echo: " + args.Text}}}
+		case "fail":
+			result = map[string]any{
+				"content": []any{map[string]any{"type": "text", "text": "something went wrong"}},
+				"isError": true,
+			}
+		default:
+			return newErrorResponse(request.ID, CodeInvalidParams, "unknown tool "+params.Name), true
+		}
+	default:
+		return newErrorResponse(request.ID, CodeMethodNotFound, "method not found"), true
+	}
+
+	response, _ := newResponse(request.ID, result)
+	return response, true
+}
+
+func testClient(t *testing.T, client *Client) {
+	t.Helper()
+	ctx := context.Background()
+
+	assert.Equal(t, "fake", client.ServerInfo().Name)
+	assert.Equal(t, ProtocolVersion, client.ProtocolVersion())
+	assert.Equal(t, "Use the echo tool.", client.Instructions())
+	assert.NoError(t, client.Ping(ctx))
+
+	mcpTools, err := client.ListTools(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(mcpTools))
+	assert.Equal(t, "echo", mcpTools[0].Name)
+	assert.Equal(t, "fail", mcpTools[1].Name)
+
+	result, err := client.CallTool(ctx, "echo", json.RawMessage(`{"text":"hi"}`))
+	assert.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Equal(t, "echo: hi", result.Text())
+
+	_, err = client.CallTool(ctx, "missing", nil)
+	var rpcErr *RPCError
+	assert.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+
+	tools, err := client.Tools(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(tools))
+	assert.Equal(t, "fake_echo", tools[0].Name())
+	assert.Equal(t, "Always fails", tools[1].Definition.Description)
+	assert.Equal(t, "object", tools[1].Definition.Parameters["type"])
+
+	content, err := tools[0].Call(ctx, toolCall("fake_echo", `{"text":"hello"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "echo: hello", content)
+
+	_, err = tools[1].Call(ctx, toolCall("fake_fail", ""))
+	assert.True(t, errors.Is(err, ErrToolError))
+	assert.True(t, strings.Contains(err.Error(), "something went wrong"))
+}
+
+func toolCall(name string, arguments string) openroutergo.ChatCompletionMessageToolCall {
+	return openroutergo.ChatCompletionMessageToolCall{
+		ID:       "call_1",
+		Type:     "function",
+		Function: openroutergo.ChatCompletionMessageToolCallFunction{Name: name, Arguments: arguments},
+	}
+}
+
+func TestClientStdio(t *testing.T) {
+	client, err := NewClient().
+		WithStdioCommand(os.Args[0]).
+		WithEnv("MCP_FAKE_SERVER=1").
+		WithToolNamePrefix("fake_").
+		Connect(context.Background())
+	assert.NoError(t, err)
+
+	testClient(t, client)
+
+	assert.NoError(t, client.Close())
+	assert.True(t, errors.Is(client.Ping(context.Background()), ErrClosed))
+
+	// Closing again doesn't wait for the process again
+	assert.NoError(t, client.Close())
+}
+
+func TestClientStdioChildKeepsStdoutOpen(t *testing.T) {
+	previous := stdioCloseTimeout
+	stdioCloseTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { stdioCloseTimeout = previous })
+
+	client, err := NewClient().
+		WithStdioCommand(os.Args[0]).
+		WithEnv("MCP_FAKE_SERVER=with_child").
+		Connect(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, client.Ping(context.Background()))
+
+	done := make(chan error, 1)
+	go func() { done <- client.Close() }()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close is blocked by the stdout held by the child of the server")
+	}
+}
+
+func TestClientStreamableHTTP(t *testing.T) {
+	mu := sync.Mutex{}
+	deleted := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			mu.Lock()
+			deleted = r.Header.Get(headerSessionID) == "session-1"
+			mu.Unlock()
+			return
+		}
+
+		var request jsonRPCMessage
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if request.Method == "initialize" {
+			w.Header().Set(headerSessionID, "session-1")
+		} else if r.Header.Get(headerSessionID) != "session-1" ||
+			r.Header.Get(headerProtocolVersion) != ProtocolVersion {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		response, ok := handleFakeRequest(request)
+		if !ok {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		data, _ := json.Marshal(response)
+		if request.Method == "tools/call" {
+			// Answer the tool calls with server sent events
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprintf(w, ": comment\n\nevent: message\ndata: %s\n\n", data)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	_, err := NewClient().WithStreamableHTTP(server.URL).Connect(context.Background())
+	assert.NotNil(t, err)
+
+	client, err := NewClient().
+		WithStreamableHTTP(server.URL).
+		WithHeader("Authorization", "Bearer token").
+		WithToolNamePrefix("fake_").
+		Connect(context.Background())
+	assert.NoError(t, err)
+
+	testClient(t, client)
+
+	assert.NoError(t, client.Close())
+	mu.Lock()
+	assert.True(t, deleted)
+	mu.Unlock()
+}
+
+func TestClientWithoutTransport(t *testing.T) {
+	_, err := NewClient().Connect(context.Background())
+	assert.Error(t, ErrTransportRequired, err)
+}
@@ -0,0 +1,36 @@
+package mcp
+
+import "errors"
+
+var (
+	// ErrTransportRequired is returned when a client is connected without choosing a
+	// transport.
+	ErrTransportRequired = errors.New("mcp: a transport is required")
+
+	// ErrClosed is returned when the connection with the server is closed.
+	ErrClosed = errors.New("mcp: the connection is closed")
+
+	// ErrUnsupportedProtocolVersion is returned when the server uses a version of the
+	// protocol that is not supported.
+	ErrUnsupportedProtocolVersion = errors.New("mcp: unsupported protocol version")
+
+	// ErrToolError is returned when a tool call fails on the server, the error message
+	// contains the text returned by the tool.
+	ErrToolError = errors.New("mcp: the tool returned an error")
+
+	// ErrClientRequired is returned when the chat tools are created without an
+	// OpenRouter client.
+	ErrClientRequired = errors.New("mcp: the OpenRouter client is required")
+
+	// ErrModelNotAllowed is returned when the chat tool is called with a model that is
+	// not in the allowed models.
+	ErrModelNotAllowed = errors.New("mcp: the model is not allowed")
+
+	// ErrBudgetExhausted is returned when the chat tool is called after the budget has
+	// been spent.
+	ErrBudgetExhausted = errors.New("mcp: the budget is exhausted")
+
+	// ErrUnknownCost is returned by the chat tool when a budget is set and the cost of
+	// a request can't be calculated because the price of the model is unknown.
+	ErrUnknownCost = errors.New("mcp: unknown cost")
+)
@@ -0,0 +1,93 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const jsonRPCVersion = "2.0"
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// RPCError is a JSON-RPC error returned by the other side of the connection.
+type RPCError struct {
+	// The error code, see the Code* constants for the standard ones.
+	Code int `json:"code"`
+	// A short description of the error.
+	Message string `json:"message"`
+	// Additional information about the error.
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp: JSON-RPC error %d: %s", e.Code, e.Message)
+}
+
+// jsonRPCMessage is any JSON-RPC 2.0 message: a request (method and id), a
+// notification (method without id) or a response (id and result or error).
+type jsonRPCMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+func (m jsonRPCMessage) isRequest() bool {
+	return m.Method != "" && len(m.ID) > 0
+}
+
+func (m jsonRPCMessage) isNotification() bool {
+	return m.Method != "" && len(m.ID) == 0
+}
+
+// newResponse creates the response for the request with the given id, the result is
+// encoded as JSON.
+func newResponse(id json.RawMessage, result any) (jsonRPCMessage, error) {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return jsonRPCMessage{}, err
+	}
+	return jsonRPCMessage{JSONRPC: jsonRPCVersion, ID: id, Result: encoded}, nil
+}
+
+// newErrorResponse creates an error response for the request with the given id.
+func newErrorResponse(id json.RawMessage, code int, message string) jsonRPCMessage {
+	return jsonRPCMessage{
+		JSONRPC: jsonRPCVersion,
+		ID:      id,
+		Error:   &RPCError{Code: code, Message: message},
+	}
+}
+
+// decodeMessages decodes a single JSON-RPC message or a batch of messages.
+func decodeMessages(data []byte) ([]jsonRPCMessage, error) {
+	for _, c := range data {
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			continue
+		}
+		if c == '[' {
+			var batch []jsonRPCMessage
+			if err := json.Unmarshal(data, &batch); err != nil {
+				return nil, err
+			}
+			return batch, nil
+		}
+		break
+	}
+
+	var message jsonRPCMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, err
+	}
+	return []jsonRPCMessage{message}, nil
+}
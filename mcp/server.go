@@ -0,0 +1,320 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/zachczx/openroutergo"
+)
+
+// Server is an MCP server that exposes [openroutergo.Tool] values to MCP hosts, like
+// IDE agents, using the stdio transport.
+//
+// Create it using the NewServer function, see also the NewChatTools function to expose
+// OpenRouter models as tools.
+type Server struct {
+	info         Implementation
+	instructions string
+	registry     *openroutergo.ToolRegistry
+}
+
+// serverBuilder is a chainable builder for the MCP server.
+type serverBuilder struct {
+	server *Server
+}
+
+// NewServer starts the creation of a new MCP server with the given name and version,
+// they are sent to the hosts that connect to it.
+func NewServer(name string, version string) *serverBuilder {
+	return &serverBuilder{
+		server: &Server{
+			info:         Implementation{Name: name, Version: version},
+			instructions: "",
+			registry:     openroutergo.NewToolRegistry(),
+		},
+	}
+}
+
+// WithTools adds tools to the server.
+//
+// The arguments are validated by the Call method of the tool before running it, and
+// the per-tool timeout is applied. Approve functions are not used because the MCP
+// host is responsible for asking the user for approval.
+func (b *serverBuilder) WithTools(tools ...openroutergo.Tool) *serverBuilder {
+	b.server.registry.Register(tools...)
+	return b
+}
+
+// WithInstructions sets the instructions sent to the hosts describing how to use the
+// server.
+func (b *serverBuilder) WithInstructions(instructions string) *serverBuilder {
+	b.server.instructions = instructions
+	return b
+}
+
+// Create builds and returns the MCP server.
+func (b *serverBuilder) Create() *Server {
+	return b.server
+}
+
+// ServeStdio serves a single MCP host connected to the stdin and stdout of the
+// current process, see the Serve method.
+func (s *Server) ServeStdio(ctx context.Context) error {
+	return s.Serve(ctx, os.Stdin, os.Stdout)
+}
+
+// Serve reads newline delimited JSON-RPC messages from r and writes the responses to
+// w until r is closed or the context is canceled.
+//
+// Tool calls run concurrently and are canceled when the host sends a cancellation
+// notification. It waits for the running tool calls before returning.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	conn := &serverConn{
+		server:  s,
+		writer:  w,
+		writeMu: sync.Mutex{},
+		mu:      sync.Mutex{},
+		running: map[string]context.CancelFunc{},
+		wg:      sync.WaitGroup{},
+	}
+	defer conn.wg.Wait()
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(strings.TrimSpace(string(line))) > 0 {
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("mcp: failed to read the request: %w", err)
+		case line := <-lines:
+			messages, err := decodeMessages(line)
+			if err != nil {
+				conn.write(newErrorResponse(json.RawMessage("null"), CodeParseError, "parse error"))
+				continue
+			}
+			for _, message := range messages {
+				conn.handle(ctx, message)
+			}
+		}
+	}
+}
+
+// serverConn is a connection with an MCP host.
+type serverConn struct {
+	server *Server
+
+	writer  io.Writer
+	writeMu sync.Mutex
+
+	// running are the cancel functions of the tool calls in progress by request id
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func (c *serverConn) write(message jsonRPCMessage) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, _ = c.writer.Write(append(data, '\n'))
+}
+
+func (c *serverConn) respond(id json.RawMessage, result any) {
+	response, err := newResponse(id, result)
+	if err != nil {
+		response = newErrorResponse(id, CodeInternalError, err.Error())
+	}
+	c.write(response)
+}
+
+func (c *serverConn) handle(ctx context.Context, message jsonRPCMessage) {
+	if message.isNotification() {
+		if message.Method == "notifications/cancelled" {
+			var params struct {
+				RequestID json.RawMessage `json:"requestId"`
+			}
+			if err := json.Unmarshal(message.Params, &params); err == nil {
+				c.mu.Lock()
+				if cancel, ok := c.running[string(params.RequestID)]; ok {
+					delete(c.running, string(params.RequestID))
+					cancel()
+				}
+				c.mu.Unlock()
+			}
+		}
+		return
+	}
+
+	if !message.isRequest() {
+		// Responses are not expected because the server never sends requests
+		return
+	}
+
+	switch message.Method {
+	case "initialize":
+		c.respond(message.ID, c.server.initializeResult(message.Params))
+	case "ping":
+		c.respond(message.ID, map[string]any{})
+	case "tools/list":
+		c.respond(message.ID, map[string]any{"tools": c.server.listTools()})
+	case "tools/call":
+		c.startToolCall(ctx, message)
+	default:
+		c.write(newErrorResponse(message.ID, CodeMethodNotFound, "method not found: "+message.Method))
+	}
+}
+
+func (c *serverConn) startToolCall(ctx context.Context, request jsonRPCMessage) {
+	var params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(request.Params, &params); err != nil {
+		c.write(newErrorResponse(request.ID, CodeInvalidParams, "invalid params: "+err.Error()))
+		return
+	}
+
+	tool, ok := c.server.registry.Get(params.Name)
+	if !ok {
+		c.write(newErrorResponse(request.ID, CodeInvalidParams, "unknown tool: "+params.Name))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	id := string(request.ID)
+	c.mu.Lock()
+	c.running[id] = cancel
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer cancel()
+
+		result := callServerTool(ctx, tool, id, string(params.Arguments))
+
+		c.mu.Lock()
+		_, stillRunning := c.running[id]
+		delete(c.running, id)
+		c.mu.Unlock()
+
+		// Canceled tool calls are not answered, the host is no longer waiting for them
+		if stillRunning {
+			c.respond(request.ID, result)
+		}
+	}()
+}
+
+// callServerTool runs a tool call from an MCP host and converts the result, errors are
+// returned as results with IsError set so the model can read them.
+func callServerTool(ctx context.Context, tool openroutergo.Tool, id string, arguments string) (result CallToolResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = errorResult(fmt.Errorf("%w: %v", openroutergo.ErrToolPanicked, r))
+		}
+	}()
+
+	if tool.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, tool.Timeout)
+		defer cancel()
+	}
+
+	content, err := tool.Call(ctx, openroutergo.ChatCompletionMessageToolCall{
+		ID:   id,
+		Type: "function",
+		Function: openroutergo.ChatCompletionMessageToolCallFunction{
+			Name:      tool.Name(),
+			Arguments: arguments,
+		},
+	})
+	if err != nil {
+		return errorResult(err)
+	}
+
+	return CallToolResult{Content: []Content{{Type: "text", Text: content}}}
+}
+
+func errorResult(err error) CallToolResult {
+	return CallToolResult{Content: []Content{{Type: "text", Text: "Error: " + err.Error()}}, IsError: true}
+}
+
+// initializeResult negotiates the protocol version, the version requested by the
+// host is used if it's supported.
+func (s *Server) initializeResult(params json.RawMessage) map[string]any {
+	var request struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	_ = json.Unmarshal(params, &request)
+
+	version := ProtocolVersion
+	if slices.Contains(supportedProtocolVersions, request.ProtocolVersion) {
+		version = request.ProtocolVersion
+	}
+
+	result := map[string]any{
+		"protocolVersion": version,
+		"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+		"serverInfo":      s.info,
+	}
+	if s.instructions != "" {
+		result["instructions"] = s.instructions
+	}
+	return result
+}
+
+// listTools converts the tools of the server into MCP tools.
+func (s *Server) listTools() []Tool {
+	tools := []Tool{}
+	for _, tool := range s.registry.Tools() {
+		inputSchema := tool.Definition.Parameters
+		if inputSchema == nil {
+			inputSchema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		tools = append(tools, Tool{
+			Name:         tool.Name(),
+			Title:        "",
+			Description:  tool.Definition.Description,
+			InputSchema:  inputSchema,
+			OutputSchema: nil,
+		})
+	}
+	return tools
+}
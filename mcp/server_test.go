@@ -0,0 +1,321 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zachczx/openroutergo"
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+// serve sends the requests to the server and returns the responses by id.
+func serve(t *testing.T, server *Server, requests ...string) map[string]jsonRPCMessage {
+	t.Helper()
+
+	output := bytes.Buffer{}
+	err := server.Serve(context.Background(), strings.NewReader(strings.Join(requests, "\n")+"\n"), &output)
+	assert.NoError(t, err)
+
+	responses := map[string]jsonRPCMessage{}
+	scanner := bufio.NewScanner(&output)
+	for scanner.Scan() {
+		var response jsonRPCMessage
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &response))
+		responses[string(response.ID)] = response
+	}
+	return responses
+}
+
+func decodeResult[T any](t *testing.T, message jsonRPCMessage) T {
+	t.Helper()
+	var result T
+	assert.True(t, message.Error == nil)
+	assert.NoError(t, json.Unmarshal(message.Result, &result))
+	return result
+}
+
+func TestServer(t *testing.T) {
+	type greetArgs struct {
+		Name string `json:"name"`
+	}
+	greetTool, err := openroutergo.NewTool("greet", "Greets someone",
+		func(_ context.Context, args greetArgs) (string, error) {
+			return "Hello " + args.Name, nil
+		},
+	)
+	assert.NoError(t, err)
+
+	failTool := openroutergo.NewRawTool(
+		openroutergo.ChatCompletionTool{Name: "fail", Description: "Always fails", Parameters: nil},
+		func(_ context.Context, _ string) (string, error) {
+			return "", errors.New("boom")
+		},
+	)
+
+	server := NewServer("test", "0.1.0").WithTools(greetTool, failTool).WithInstructions("Be nice.").Create()
+	responses := serve(t, server,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"greet","arguments":{"name":"Ada"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"greet","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"fail"}}`,
+		`{"jsonrpc":"2.0","id":"six","method":"tools/call","params":{"name":"missing"}}`,
+		`{"jsonrpc":"2.0","id":7,"method":"resources/list"}`,
+		`not json`,
+	)
+	assert.Equal(t, 8, len(responses))
+
+	initialize := decodeResult[struct {
+		ProtocolVersion string         `json:"protocolVersion"`
+		ServerInfo      Implementation `json:"serverInfo"`
+		Instructions    string         `json:"instructions"`
+	}](t, responses["1"])
+	assert.Equal(t, "2025-03-26", initialize.ProtocolVersion)
+	assert.Equal(t, "test", initialize.ServerInfo.Name)
+	assert.Equal(t, "Be nice.", initialize.Instructions)
+
+	list := decodeResult[struct {
+		Tools []Tool `json:"tools"`
+	}](t, responses["2"])
+	assert.Equal(t, 2, len(list.Tools))
+	assert.Equal(t, "greet", list.Tools[0].Name)
+	assert.Equal(t, "object", list.Tools[1].InputSchema["type"])
+
+	greet := decodeResult[CallToolResult](t, responses["3"])
+	assert.False(t, greet.IsError)
+	assert.Equal(t, "Hello Ada", greet.Text())
+
+	invalid := decodeResult[CallToolResult](t, responses["4"])
+	assert.True(t, invalid.IsError)
+	assert.True(t, strings.Contains(invalid.Text(), "$.name: is required"))
+
+	fail := decodeResult[CallToolResult](t, responses["5"])
+	assert.True(t, fail.IsError)
+	assert.Equal(t, "Error: boom", fail.Text())
+
+	assert.Equal(t, CodeInvalidParams, responses[`"six"`].Error.Code)
+	assert.Equal(t, CodeMethodNotFound, responses["7"].Error.Code)
+	assert.Equal(t, CodeParseError, responses["null"].Error.Code)
+}
+
+func TestServerCancelToolCall(t *testing.T) {
+	started := make(chan struct{})
+	slowTool := openroutergo.NewRawTool(
+		openroutergo.ChatCompletionTool{Name: "slow", Description: "", Parameters: nil},
+		func(ctx context.Context, _ string) (string, error) {
+			close(started)
+			<-ctx.Done()
+			return "", ctx.Err()
+		},
+	)
+	server := NewServer("test", "0.1.0").WithTools(slowTool).Create()
+
+	reader, writer := io.Pipe()
+	output := &lockedBuffer{mu: sync.Mutex{}, buf: bytes.Buffer{}}
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(context.Background(), reader, output) }()
+
+	_, _ = writer.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"slow"}}` + "\n"))
+	<-started
+	_, _ = writer.Write([]byte(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1}}` + "\n"))
+	_, _ = writer.Write([]byte(`{"jsonrpc":"2.0","id":2,"method":"ping"}` + "\n"))
+	_ = writer.Close()
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":2,"result":{}}`, strings.TrimSpace(output.String()))
+}
+
+// fakeOpenRouter returns a server that implements the models and chat completions
+// endpoints of OpenRouter, it records the last chat completion request.
+func fakeOpenRouter(t *testing.T, lastRequest *map[string]any) *openroutergo.Client {
+	t.Helper()
+	mu := sync.Mutex{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/models":
+			_, _ = w.Write([]byte(`{"data":[
+				{"id":"cheap/model","name":"Cheap","context_length":8000,
+				 "pricing":{"prompt":"0.000001","completion":"0.000002","request":"0"}},
+				{"id":"expensive/model","name":"Expensive","context_length":200000,
+				 "pricing":{"prompt":"0.00003","completion":"0.00006","request":"0"}}
+			]}`))
+		case "/chat/completions":
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			mu.Lock()
+			*lastRequest = body
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"id":"1","model":"cheap/model","choices":[
+				{"finish_reason":"stop","message":{"role":"assistant","content":"Paris"}}
+			],"usage":{"prompt_tokens":1000,"completion_tokens":500,"total_tokens":1500}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := openroutergo.NewClient().WithBaseURL(server.URL).WithAPIKey("key").Create()
+	assert.NoError(t, err)
+	return client
+}
+
+func callTool(t *testing.T, tool openroutergo.Tool, arguments string) (string, error) {
+	t.Helper()
+	return tool.Call(context.Background(), toolCall(tool.Name(), arguments))
+}
+
+func TestChatTools(t *testing.T) {
+	lastRequest := map[string]any{}
+	client := fakeOpenRouter(t, &lastRequest)
+
+	tools, err := NewChatTools(client).
+		WithAllowedModels("cheap/model", "expensive/model").
+		WithDefaultModel("cheap/model").
+		WithMaxTokens(100).
+		WithMaxPrice(10, 20).
+		WithBudget(0.003).
+		Create()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(tools))
+	chat, listModels := tools[0], tools[1]
+
+	content, err := callTool(t, chat, `{"messages":[{"role":"user","content":"Capital of France?"}],"max_tokens":500}`)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`{"model":"cheap/model","content":"Paris","finish_reason":"stop","prompt_tokens":1000,`+
+			`"completion_tokens":500,"cost":0.002,"budget_remaining":0.001}`,
+		content,
+	)
+	assert.Equal(t, "cheap/model", lastRequest["model"].(string))
+	assert.Equal(t, float64(100), lastRequest["max_tokens"].(float64))
+
+	// The allowed models are part of the schema
+	_, err = callTool(t, chat, `{"model":"other/model","messages":[{"role":"user","content":"Hi"}]}`)
+	assert.True(t, errors.Is(err, openroutergo.ErrInvalidToolArguments))
+
+	_, err = callTool(t, chat, `{"messages":[{"role":"user","content":"Hi"}]}`)
+	assert.NoError(t, err)
+	_, err = callTool(t, chat, `{"messages":[{"role":"user","content":"Hi"}]}`)
+	assert.True(t, errors.Is(err, ErrBudgetExhausted))
+
+	content, err = callTool(t, listModels, `{}`)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`[{"id":"cheap/model","name":"Cheap","context_length":8000,`+
+			`"prompt_price_per_million":1,"completion_price_per_million":2}]`,
+		content,
+	)
+
+	_, err = NewChatTools(client).WithAllowedModels("cheap/model").WithDefaultModel("other/model").Create()
+	assert.True(t, errors.Is(err, ErrModelNotAllowed))
+}
+
+func TestChatToolsBudget(t *testing.T) {
+	mu := sync.Mutex{}
+	answeredBy := "cheap/model"
+	release := make(chan struct{})
+	close(release)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/models":
+			_, _ = w.Write([]byte(`{"data":[
+				{"id":"cheap/model","context_length":8000,"pricing":{"prompt":"0.000001","completion":"0.000002"}},
+				{"id":"expensive/model","context_length":8000,"pricing":{"prompt":"0.00003","completion":"0.00006"}}
+			]}`))
+		case "/chat/completions":
+			mu.Lock()
+			model, wait := answeredBy, release
+			mu.Unlock()
+			<-wait
+			_, _ = w.Write([]byte(`{"id":"1","model":"` + model + `","choices":[
+				{"finish_reason":"stop","message":{"role":"assistant","content":"Paris"}}
+			],"usage":{"prompt_tokens":1000,"completion_tokens":500,"total_tokens":1500}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	client, err := openroutergo.NewClient().WithBaseURL(server.URL).WithAPIKey("key").Create()
+	assert.NoError(t, err)
+
+	newChat := func(budget float64) openroutergo.Tool {
+		tools, err := NewChatTools(client).WithDefaultModel("cheap/model").WithMaxTokens(1000).WithBudget(budget).Create()
+		assert.NoError(t, err)
+		return tools[0]
+	}
+	message := `{"messages":[{"role":"user","content":"Capital of France?"}]}`
+
+	// The cost is calculated with the price of the model that answered
+	mu.Lock()
+	answeredBy = "expensive/model"
+	mu.Unlock()
+	chat := newChat(0.1)
+	content, err := callTool(t, chat, message)
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(content, `"model":"expensive/model"`))
+	assert.True(t, strings.HasSuffix(content, `"budget_remaining":0.04}`))
+
+	// The variants of a model are priced like the model
+	_, err = callTool(t, newChat(0.1), `{"model":"cheap/model:nitro","messages":[{"role":"user","content":"Hi"}]}`)
+	assert.NoError(t, err)
+
+	// The requests to models without a known price are rejected
+	_, err = callTool(t, chat, `{"model":"unknown/model","messages":[{"role":"user","content":"Hi"}]}`)
+	assert.True(t, errors.Is(err, ErrUnknownCost))
+
+	// The budget is exhausted when the cost of a response is unknown
+	mu.Lock()
+	answeredBy = "unknown/model"
+	mu.Unlock()
+	_, err = callTool(t, chat, message)
+	assert.True(t, errors.Is(err, ErrUnknownCost))
+	_, err = callTool(t, chat, message)
+	assert.True(t, errors.Is(err, ErrBudgetExhausted))
+
+	// The running requests reserve their maximum cost (about $0.002 each)
+	mu.Lock()
+	answeredBy = "cheap/model"
+	release = make(chan struct{})
+	mu.Unlock()
+	chat = newChat(0.005)
+	errs := make(chan error, 3)
+	for range 3 {
+		go func() {
+			_, err := callTool(t, chat, message)
+			errs <- err
+		}()
+	}
+	assert.True(t, errors.Is(<-errs, ErrBudgetExhausted))
+	close(release)
+	assert.NoError(t, <-errs)
+	assert.NoError(t, <-errs)
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
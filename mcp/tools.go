@@ -0,0 +1,188 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/zachczx/openroutergo"
+)
+
+// Tool is a tool provided by an MCP server.
+type Tool struct {
+	// The name of the tool, unique in the server.
+	Name string `json:"name"`
+	// A human readable name for the tool.
+	Title string `json:"title,omitempty"`
+	// The description of the tool for the model.
+	Description string `json:"description,omitempty"`
+	// The JSON Schema of the arguments of the tool.
+	InputSchema map[string]any `json:"inputSchema"`
+	// The JSON Schema of the structured content returned by the tool, if any.
+	OutputSchema map[string]any `json:"outputSchema,omitempty"`
+}
+
+// Content is an item of the content returned by a tool call.
+type Content struct {
+	// The type of the content: text, image, audio, resource or resource_link.
+	Type string `json:"type"`
+	// The text of a text content.
+	Text string `json:"text,omitempty"`
+	// The base64 encoded data of an image or audio content.
+	Data string `json:"data,omitempty"`
+	// The MIME type of an image, audio or resource content.
+	MimeType string `json:"mimeType,omitempty"`
+	// The URI of a resource link.
+	URI string `json:"uri,omitempty"`
+	// The embedded resource of a resource content.
+	Resource *ResourceContents `json:"resource,omitempty"`
+}
+
+// ResourceContents is the content of a resource embedded in a tool result.
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	// The content of a text resource.
+	Text string `json:"text,omitempty"`
+	// The base64 encoded content of a binary resource.
+	Blob string `json:"blob,omitempty"`
+}
+
+// CallToolResult is the result of a tool call.
+type CallToolResult struct {
+	// The content returned by the tool.
+	Content []Content `json:"content"`
+	// The structured content returned by the tool, a JSON object that matches its
+	// output schema.
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	// IsError is true if the tool failed, the content describes the error.
+	IsError bool `json:"isError,omitempty"`
+}
+
+// Text returns the content of the result as text, so it can be sent back to the model.
+//
+// Text contents and text resources are joined with new lines, other contents are
+// described by their type. If there is no content, the structured content is used.
+func (r CallToolResult) Text() string {
+	parts := make([]string, 0, len(r.Content))
+	for _, content := range r.Content {
+		switch {
+		case content.Type == "text":
+			parts = append(parts, content.Text)
+		case content.Resource != nil && content.Resource.Text != "":
+			parts = append(parts, content.Resource.Text)
+		case content.URI != "":
+			parts = append(parts, fmt.Sprintf("[%s: %s]", content.Type, content.URI))
+		case content.MimeType != "":
+			parts = append(parts, fmt.Sprintf("[%s: %s]", content.Type, content.MimeType))
+		default:
+			parts = append(parts, fmt.Sprintf("[%s]", content.Type))
+		}
+	}
+
+	if len(parts) == 0 && len(r.StructuredContent) > 0 {
+		return string(r.StructuredContent)
+	}
+
+	return strings.Join(parts, "\n")
+}
+
+// ListTools returns all the tools provided by the server, it follows the pagination
+// of the server until all the tools are listed.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	tools := []Tool{}
+	cursor := ""
+
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+
+		var result struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", params, &result); err != nil {
+			return nil, fmt.Errorf("mcp: failed to list the tools: %w", err)
+		}
+
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallTool calls a tool of the server with the given arguments (a JSON object).
+//
+// A tool that fails returns a result with IsError set to true and no error, errors are
+// only returned when the call can't be made.
+func (c *Client) CallTool(ctx context.Context, name string, arguments json.RawMessage) (CallToolResult, error) {
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+
+	params := map[string]any{"name": name, "arguments": arguments}
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", params, &result); err != nil {
+		return CallToolResult{}, fmt.Errorf("mcp: failed to call the tool %q: %w", name, err)
+	}
+
+	return result, nil
+}
+
+// Tools lists the tools of the server and converts them into [openroutergo.Tool]
+// values, the calls made by the model are routed to the server.
+//
+// The text of the result is sent back to the model, see CallToolResult.Text. If the
+// tool fails, the handler returns ErrToolError with the text of the result.
+func (c *Client) Tools(ctx context.Context) ([]openroutergo.Tool, error) {
+	mcpTools, err := c.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tools := make([]openroutergo.Tool, 0, len(mcpTools))
+	for _, mcpTool := range mcpTools {
+		tools = append(tools, c.convertTool(mcpTool))
+	}
+
+	return tools, nil
+}
+
+// convertTool creates an [openroutergo.Tool] that calls the given MCP tool.
+func (c *Client) convertTool(mcpTool Tool) openroutergo.Tool {
+	parameters := mcpTool.InputSchema
+	if parameters == nil {
+		parameters = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+
+	description := mcpTool.Description
+	if description == "" {
+		description = mcpTool.Title
+	}
+
+	definition := openroutergo.ChatCompletionTool{
+		Name:        c.toolNamePrefix + mcpTool.Name,
+		Description: description,
+		Parameters:  parameters,
+	}
+
+	return openroutergo.NewRawTool(definition, func(ctx context.Context, arguments string) (string, error) {
+		if strings.TrimSpace(arguments) == "" {
+			arguments = "{}"
+		}
+
+		result, err := c.CallTool(ctx, mcpTool.Name, json.RawMessage(arguments))
+		if err != nil {
+			return "", err
+		}
+		if result.IsError {
+			return "", fmt.Errorf("%w: %s", ErrToolError, result.Text())
+		}
+		return result.Text(), nil
+	})
+}
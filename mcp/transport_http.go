@@ -0,0 +1,249 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	headerSessionID       = "Mcp-Session-Id"
+	headerProtocolVersion = "MCP-Protocol-Version"
+)
+
+// httpTransport implements the streamable HTTP transport: every message is sent in a
+// POST request and the server answers with a JSON document or a stream of server sent
+// events.
+type httpTransport struct {
+	url        string
+	httpClient *http.Client
+	headers    http.Header
+
+	mu              sync.Mutex
+	sessionID       string
+	protocolVersion string
+	closed          bool
+	inFlight        sync.WaitGroup
+	incoming        chan jsonRPCMessage
+	// closeCtx is canceled when the transport is closed to abort the requests in flight
+	closeCtx    context.Context
+	closeCancel context.CancelFunc
+}
+
+func newHTTPTransport(url string, httpClient *http.Client, headers http.Header) *httpTransport {
+	closeCtx, closeCancel := context.WithCancel(context.Background())
+	return &httpTransport{
+		url:             url,
+		httpClient:      httpClient,
+		headers:         headers,
+		mu:              sync.Mutex{},
+		sessionID:       "",
+		protocolVersion: "",
+		closed:          false,
+		inFlight:        sync.WaitGroup{},
+		incoming:        make(chan jsonRPCMessage, 16),
+		closeCtx:        closeCtx,
+		closeCancel:     closeCancel,
+	}
+}
+
+func (t *httpTransport) start(_ context.Context) error {
+	return nil
+}
+
+// setProtocolVersion sets the protocol version negotiated during the initialization,
+// it's sent in all the following requests.
+func (t *httpTransport) setProtocolVersion(version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.protocolVersion = version
+}
+
+func (t *httpTransport) newRequest(ctx context.Context, method string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range t.headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessionID != "" {
+		req.Header.Set(headerSessionID, t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set(headerProtocolVersion, t.protocolVersion)
+	}
+
+	return req, nil
+}
+
+func (t *httpTransport) send(ctx context.Context, message jsonRPCMessage) error {
+	// The incoming channel can't be closed while a response is being delivered
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return ErrClosed
+	}
+	t.inFlight.Add(1)
+	t.mu.Unlock()
+	defer t.inFlight.Done()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(t.closeCtx, cancel)
+	defer stop()
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := t.newRequest(ctx, http.MethodPost, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	res, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("mcp: failed to send the request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if sessionID := res.Header.Get(headerSessionID); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+	}
+
+	if res.StatusCode == http.StatusAccepted || res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		resBody, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return fmt.Errorf(
+			"mcp: the server responded with status %d: %s", res.StatusCode, strings.TrimSpace(string(resBody)),
+		)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		return t.readEvents(res.Body)
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("mcp: failed to read the response: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	messages, err := decodeMessages(data)
+	if err != nil {
+		return fmt.Errorf("mcp: failed to decode the response: %w", err)
+	}
+	return t.deliver(messages)
+}
+
+// readEvents reads a stream of server sent events, the data of each event is a
+// JSON-RPC message.
+func (t *httpTransport) readEvents(body io.Reader) error {
+	reader := bufio.NewReader(body)
+	data := strings.Builder{}
+
+	flush := func() error {
+		if data.Len() == 0 {
+			return nil
+		}
+		messages, err := decodeMessages([]byte(data.String()))
+		data.Reset()
+		if err != nil {
+			return fmt.Errorf("mcp: failed to decode the event: %w", err)
+		}
+		return t.deliver(messages)
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		trimmed := strings.TrimRight(line, "\r\n")
+
+		switch {
+		case trimmed == "":
+			if flushErr := flush(); flushErr != nil {
+				return flushErr
+			}
+		case strings.HasPrefix(trimmed, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(trimmed, "data:"), " "))
+		}
+
+		if err == io.EOF {
+			return flush()
+		}
+		if err != nil {
+			return fmt.Errorf("mcp: failed to read the event stream: %w", err)
+		}
+	}
+}
+
+func (t *httpTransport) deliver(messages []jsonRPCMessage) error {
+	for _, message := range messages {
+		select {
+		case t.incoming <- message:
+		case <-t.closeCtx.Done():
+			return ErrClosed
+		}
+	}
+	return nil
+}
+
+func (t *httpTransport) receive() <-chan jsonRPCMessage {
+	return t.incoming
+}
+
+// close terminates the session on the server, if any.
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	sessionID := t.sessionID
+	t.mu.Unlock()
+
+	t.closeCancel()
+	t.inFlight.Wait()
+	close(t.incoming)
+
+	if sessionID == "" {
+		return nil
+	}
+
+	req, err := t.newRequest(context.Background(), http.MethodDelete, nil)
+	if err != nil {
+		return err
+	}
+	res, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil
+	}
+	_ = res.Body.Close()
+	return nil
+}
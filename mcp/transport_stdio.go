@@ -0,0 +1,164 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// stdioCloseTimeout is the time the server process has to exit after its stdin is
+// closed before it's killed, and the time its output has to be closed after that
+// before the pipes are closed, because a child of the server (for example, when it's
+// started with npx) can keep them open. It's a variable so the tests can shorten it.
+var stdioCloseTimeout = 5 * time.Second
+
+// stdioTransport runs the MCP server as a subprocess and exchanges newline delimited
+// JSON-RPC messages through its stdin and stdout.
+type stdioTransport struct {
+	command string
+	args    []string
+	env     []string
+	stderr  io.Writer
+
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	stdout   io.ReadCloser
+	writeMu  sync.Mutex
+	incoming chan jsonRPCMessage
+	readDone chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func newStdioTransport(command string, args []string, env []string, stderr io.Writer) *stdioTransport {
+	return &stdioTransport{
+		command:  command,
+		args:     args,
+		env:      env,
+		stderr:   stderr,
+		cmd:      nil,
+		stdin:    nil,
+		stdout:   nil,
+		writeMu:  sync.Mutex{},
+		incoming: make(chan jsonRPCMessage, 16),
+		readDone: make(chan struct{}),
+
+		closeOnce: sync.Once{},
+		closeErr:  nil,
+	}
+}
+
+func (t *stdioTransport) start(_ context.Context) error {
+	// The process must outlive the context used to connect, so it's not bound to it
+	t.cmd = exec.Command(t.command, t.args...)
+	t.cmd.Env = append(os.Environ(), t.env...)
+	t.cmd.Stderr = t.stderr
+	// Wait doesn't block on the stderr copy when a child of the server keeps it open
+	t.cmd.WaitDelay = stdioCloseTimeout
+
+	stdin, err := t.cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("mcp: failed to open the server stdin: %w", err)
+	}
+	stdout, err := t.cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("mcp: failed to open the server stdout: %w", err)
+	}
+	if err := t.cmd.Start(); err != nil {
+		return fmt.Errorf("mcp: failed to start the server: %w", err)
+	}
+	t.stdin = stdin
+	t.stdout = stdout
+
+	go t.readLoop(stdout)
+	return nil
+}
+
+func (t *stdioTransport) readLoop(stdout io.Reader) {
+	defer close(t.readDone)
+	defer close(t.incoming)
+
+	reader := bufio.NewReader(stdout)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if messages, decodeErr := decodeMessages(line); decodeErr == nil {
+				for _, message := range messages {
+					t.incoming <- message
+				}
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (t *stdioTransport) send(_ context.Context, message jsonRPCMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if _, err := t.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("%w: %w", ErrClosed, err)
+	}
+	return nil
+}
+
+func (t *stdioTransport) receive() <-chan jsonRPCMessage {
+	return t.incoming
+}
+
+// close closes the stdin of the server so it can exit gracefully, if it doesn't exit
+// in time it's killed.
+//
+// It's safe to call it more than once, the next calls return the error of the first one.
+func (t *stdioTransport) close() error {
+	t.closeOnce.Do(func() {
+		t.closeErr = t.stop()
+	})
+	return t.closeErr
+}
+
+// stop stops the server process, it must be called only once.
+func (t *stdioTransport) stop() error {
+	if t.cmd == nil || t.cmd.Process == nil {
+		return nil
+	}
+
+	_ = t.stdin.Close()
+
+	// Wait must be called after all the reads from stdout are completed
+	select {
+	case <-t.readDone:
+	case <-time.After(stdioCloseTimeout):
+		_ = t.cmd.Process.Kill()
+
+		// Killing the server doesn't close the stdout held by its children
+		select {
+		case <-t.readDone:
+		case <-time.After(stdioCloseTimeout):
+			_ = t.stdout.Close()
+			<-t.readDone
+		}
+	}
+
+	err := t.cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+package openroutergo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/orsinium-labs/enum"
+)
+
+// modelIDRegex matches the "author/slug[:variant]" format of a model ID, the parts can
+// use any character except whitespace and the separators.
+var modelIDRegex = regexp.MustCompile(`^[^\s/:]+/[^\s:]+(?::[^\s:]+)?$`)
+
+// ModelVariant is an enum for the variant suffixes that can be appended to a model ID
+// to change how OpenRouter routes the request, for example "openai/gpt-4o:nitro".
+//
+//   - Docs: https://openrouter.ai/docs/features/model-routing
+//   - Shortcuts: https://openrouter.ai/docs/features/provider-routing#nitro-shortcut
+type ModelVariant enum.Member[string]
+
+// String returns the raw value of the variant.
+func (mv ModelVariant) String() string {
+	return mv.Value
+}
+
+var (
+	// VariantFree routes the request to the free version of the model, which has
+	// lower rate limits.
+	VariantFree = ModelVariant{"free"}
+	// VariantNitro routes the request to the providers with the highest throughput.
+	VariantNitro = ModelVariant{"nitro"}
+	// VariantFloor routes the request to the providers with the lowest price.
+	VariantFloor = ModelVariant{"floor"}
+	// VariantOnline enables web search results for the request.
+	VariantOnline = ModelVariant{"online"}
+	// VariantThinking enables the reasoning mode of the model.
+	VariantThinking = ModelVariant{"thinking"}
+	// VariantExtended routes the request to the version of the model with an extended
+	// context length.
+	VariantExtended = ModelVariant{"extended"}
+	// VariantBeta routes the request to the unmoderated version of the model.
+	VariantBeta = ModelVariant{"beta"}
+
+	// ModelVariants contains all the model variants known by this library.
+	ModelVariants = enum.New(
+		VariantFree,
+		VariantNitro,
+		VariantFloor,
+		VariantOnline,
+		VariantThinking,
+		VariantExtended,
+		VariantBeta,
+	)
+)
+
+// ModelID is the identifier of a model in OpenRouter, it has the format
+// "author/slug" with an optional ":variant" suffix, for example "openai/gpt-4o" or
+// "meta-llama/llama-3.3-70b-instruct:free".
+//
+// Untyped string constants can be used directly where a ModelID is expected. The model
+// ID is validated when the chat completion request is executed.
+//
+// You can search for models here: https://openrouter.ai/models
+type ModelID string
+
+// NewModelID creates a new model ID from its author and slug, for example
+// NewModelID("openai", "gpt-4o").
+func NewModelID(author string, slug string) ModelID {
+	return ModelID(author + "/" + slug)
+}
+
+// ParseModelID parses and validates a model ID.
+//
+// Returns ErrInvalidModelID if the model ID does not have the "author/slug[:variant]"
+// format, see the Validate method.
+func ParseModelID(modelID string) (ModelID, error) {
+	m := ModelID(modelID)
+	if err := m.Validate(); err != nil {
+		return "", err
+	}
+	return m, nil
+}
+
+// String returns the model ID as a string.
+func (m ModelID) String() string {
+	return string(m)
+}
+
+// Author returns the author part of the model ID, for example "openai" for
+// "openai/gpt-4o:nitro".
+func (m ModelID) Author() string {
+	author, _, _ := strings.Cut(m.withoutSuffix(), "/")
+	return author
+}
+
+// Slug returns the slug part of the model ID without the variant, for example "gpt-4o"
+// for "openai/gpt-4o:nitro".
+func (m ModelID) Slug() string {
+	_, slug, _ := strings.Cut(m.withoutSuffix(), "/")
+	return slug
+}
+
+// Variant returns the variant of the model ID, for example VariantNitro for
+// "openai/gpt-4o:nitro".
+//
+// If the model ID has no variant, the returned variant has an empty value, use the
+// HasVariant method to check it.
+func (m ModelID) Variant() ModelVariant {
+	_, variant, _ := strings.Cut(string(m), ":")
+	return ModelVariant{Value: variant}
+}
+
+// HasVariant returns true if the model ID has a variant suffix.
+func (m ModelID) HasVariant() bool {
+	return strings.Contains(string(m), ":")
+}
+
+// WithVariant returns a copy of the model ID with the given variant, replacing the
+// current variant if any.
+//
+// Example:
+//
+//	model := openroutergo.ModelID("openai/gpt-4o").WithVariant(openroutergo.VariantNitro)
+//	fmt.Println(model) // openai/gpt-4o:nitro
+func (m ModelID) WithVariant(variant ModelVariant) ModelID {
+	if variant.Value == "" {
+		return m.WithoutVariant()
+	}
+	return ModelID(m.withoutSuffix() + ":" + variant.Value)
+}
+
+// WithoutVariant returns a copy of the model ID without the variant suffix.
+func (m ModelID) WithoutVariant() ModelID {
+	return ModelID(m.withoutSuffix())
+}
+
+// Validate checks that the model ID has the "author/slug[:variant]" format.
+//
+// The variant is not required to be one of the [ModelVariants], so the variants added
+// to OpenRouter can be used before this library knows them, use HasKnownVariant to
+// check it.
+func (m ModelID) Validate() error {
+	if !modelIDRegex.MatchString(string(m)) {
+		return fmt.Errorf("%w: %q must have the format author/slug[:variant]", ErrInvalidModelID, string(m))
+	}
+	return nil
+}
+
+// HasKnownVariant returns true if the model ID has no variant or if the variant is one
+// of the [ModelVariants].
+func (m ModelID) HasKnownVariant() bool {
+	return !m.HasVariant() || ModelVariants.Contains(m.Variant())
+}
+
+// withoutSuffix returns the model ID without the ":variant" suffix.
+func (m ModelID) withoutSuffix() string {
+	base, _, _ := strings.Cut(string(m), ":")
+	return base
+}
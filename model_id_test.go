@@ -0,0 +1,87 @@
+package openroutergo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func TestModelIDParts(t *testing.T) {
+	tests := []struct {
+		id         ModelID
+		author     string
+		slug       string
+		variant    string
+		hasVariant bool
+	}{
+		{"openai/gpt-4o", "openai", "gpt-4o", "", false},
+		{"openai/gpt-4o:nitro", "openai", "gpt-4o", "nitro", true},
+		{"meta-llama/llama-3.3-70b-instruct:free", "meta-llama", "llama-3.3-70b-instruct", "free", true},
+		{"openai/gpt-4o:", "openai", "gpt-4o", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.id), func(t *testing.T) {
+			assert.Equal(t, tt.author, tt.id.Author())
+			assert.Equal(t, tt.slug, tt.id.Slug())
+			assert.Equal(t, tt.variant, tt.id.Variant().Value)
+			assert.Equal(t, tt.hasVariant, tt.id.HasVariant())
+		})
+	}
+}
+
+func TestModelIDVariants(t *testing.T) {
+	model := NewModelID("openai", "gpt-4o")
+	assert.Equal(t, ModelID("openai/gpt-4o"), model)
+	assert.Equal(t, ModelID("openai/gpt-4o:nitro"), model.WithVariant(VariantNitro))
+	assert.Equal(t, ModelID("openai/gpt-4o:free"), model.WithVariant(VariantNitro).WithVariant(VariantFree))
+	assert.Equal(t, ModelID("openai/gpt-4o"), model.WithVariant(VariantNitro).WithoutVariant())
+	assert.Equal(t, ModelID("openai/gpt-4o"), model.WithVariant(ModelVariant{}))
+
+	assert.True(t, model.HasKnownVariant())
+	assert.True(t, model.WithVariant(VariantThinking).HasKnownVariant())
+	assert.False(t, ModelID("openai/gpt-4o:preview").HasKnownVariant())
+}
+
+func TestModelIDValidate(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"openai/gpt-4o", true},
+		{"openai/gpt-4o:nitro", true},
+		{"anthropic/claude-3.5-sonnet:beta", true},
+		// Unknown variants are allowed so new variants can be used
+		{"openai/gpt-4o:preview", true},
+		{"qwen/qwen-2.5-72b-instruct@v2", true},
+		{"~anthropic/claude-sonnet-latest", true},
+		{"author/nested/slug", true},
+		{"", false},
+		{"gpt-4o", false},
+		{"/gpt-4o", false},
+		{"openai/", false},
+		{"openai/gpt 4o", false},
+		{"openai/gpt-4o:", false},
+		{"openai/gpt-4o:nitro:free", false},
+		{"open:ai/gpt-4o", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			err := ModelID(tt.id).Validate()
+			assert.Equal(t, tt.valid, err == nil)
+			if !tt.valid {
+				assert.True(t, errors.Is(err, ErrInvalidModelID))
+			}
+
+			parsed, err := ParseModelID(tt.id)
+			if tt.valid {
+				assert.NoError(t, err)
+				assert.Equal(t, ModelID(tt.id), parsed)
+			} else {
+				assert.True(t, errors.Is(err, ErrInvalidModelID))
+			}
+		})
+	}
+}
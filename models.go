@@ -0,0 +1,214 @@
+package openroutergo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Model is a model available on OpenRouter.
+//
+//   - Docs: https://openrouter.ai/docs/api-reference/list-available-models
+type Model struct {
+	// The ID of the model, use it with the WithModel method.
+	ID ModelID `json:"id"`
+	// The human readable name of the model.
+	Name string `json:"name"`
+	// The Unix timestamp of when the model was added to OpenRouter.
+	Created int64 `json:"created"`
+	// The description of the model.
+	Description string `json:"description"`
+	// The maximum number of tokens of the prompt and completion.
+	ContextLength int `json:"context_length"`
+	// The input and output modalities of the model.
+	Architecture ModelArchitecture `json:"architecture"`
+	// The limits of the main provider of the model.
+	TopProvider ModelTopProvider `json:"top_provider"`
+	// The prices of the model in USD.
+	Pricing ModelPricing `json:"pricing"`
+	// The request parameters supported by the model, for example, "tools",
+	// "response_format" or "structured_outputs".
+	SupportedParameters []string `json:"supported_parameters"`
+}
+
+// SupportsParameter returns true if the model supports the given request parameter,
+// for example, "tools".
+func (m Model) SupportsParameter(parameter string) bool {
+	return slices.Contains(m.SupportedParameters, parameter)
+}
+
+// ModelArchitecture describes the modalities supported by a model.
+type ModelArchitecture struct {
+	// The modality of the model, for example, "text+image->text".
+	Modality string `json:"modality"`
+	// The input modalities, for example, "text" or "image".
+	InputModalities []string `json:"input_modalities"`
+	// The output modalities, for example, "text".
+	OutputModalities []string `json:"output_modalities"`
+	// The tokenizer used by the model, for example, "GPT" or "Claude".
+	Tokenizer string `json:"tokenizer"`
+}
+
+// ModelTopProvider describes the limits of the main provider of a model.
+type ModelTopProvider struct {
+	// The maximum number of tokens of the prompt and completion.
+	ContextLength int `json:"context_length"`
+	// The maximum number of tokens of the completion.
+	MaxCompletionTokens int `json:"max_completion_tokens"`
+	// Whether the provider moderates the content.
+	IsModerated bool `json:"is_moderated"`
+}
+
+// ModelPricing are the prices of a model in USD, they are strings to keep the
+// precision of the API.
+type ModelPricing struct {
+	// The price per prompt token.
+	Prompt string `json:"prompt"`
+	// The price per completion token.
+	Completion string `json:"completion"`
+	// The fixed price per request.
+	Request string `json:"request"`
+	// The price per input image.
+	Image string `json:"image"`
+}
+
+// PromptPrice returns the price per prompt token, or 0 if it's unknown.
+func (p ModelPricing) PromptPrice() float64 {
+	return parsePrice(p.Prompt)
+}
+
+// CompletionPrice returns the price per completion token, or 0 if it's unknown.
+func (p ModelPricing) CompletionPrice() float64 {
+	return parsePrice(p.Completion)
+}
+
+// RequestPrice returns the fixed price per request, or 0 if it's unknown.
+func (p ModelPricing) RequestPrice() float64 {
+	return parsePrice(p.Request)
+}
+
+// Cost returns the cost in USD of a request with the given usage.
+func (p ModelPricing) Cost(usage ChatCompletionResponseUsage) float64 {
+	return p.RequestPrice() +
+		float64(usage.PromptTokens)*p.PromptPrice() +
+		float64(usage.CompletionTokens)*p.CompletionPrice()
+}
+
+func parsePrice(price string) float64 {
+	value, err := strconv.ParseFloat(price, 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+	return value
+}
+
+// modelCatalogTTL is the time the list of models is cached.
+const modelCatalogTTL = time.Hour
+
+// modelCatalog caches the list of models available on OpenRouter, it's used to know
+// the capabilities of the models.
+type modelCatalog struct {
+	mu        sync.Mutex
+	models    []Model
+	fetchedAt time.Time
+}
+
+// ListModels returns all the models available on OpenRouter.
+//
+// The list is also cached for one hour and used by the methods that need to know the
+// capabilities of a model, like GetModel.
+//
+//   - Docs: https://openrouter.ai/docs/api-reference/list-available-models
+func (c *Client) ListModels(ctx context.Context) ([]Model, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errorResponse errorResponse
+		if err := json.Unmarshal(bodyBytes, &errorResponse); err != nil {
+			return nil, &APIError{StatusCode: resp.StatusCode, Message: "", Metadata: nil}
+		}
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    errorResponse.Error.Message,
+			Metadata:   errorResponse.Error.Metadata,
+		}
+	}
+
+	var response struct {
+		Data []Model `json:"data"`
+	}
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if c.catalog != nil {
+		c.catalog.mu.Lock()
+		c.catalog.models = response.Data
+		c.catalog.fetchedAt = time.Now()
+		c.catalog.mu.Unlock()
+	}
+
+	return response.Data, nil
+}
+
+// cachedModels returns the cached list of models, it's requested again when the
+// cache is older than one hour.
+func (c *Client) cachedModels(ctx context.Context) ([]Model, error) {
+	if c.catalog != nil {
+		c.catalog.mu.Lock()
+		models, fetchedAt := c.catalog.models, c.catalog.fetchedAt
+		c.catalog.mu.Unlock()
+
+		if models != nil && time.Since(fetchedAt) < modelCatalogTTL {
+			return models, nil
+		}
+	}
+
+	return c.ListModels(ctx)
+}
+
+// GetModel returns the model with the given ID from the list of models available on
+// OpenRouter, the variant of the ID is ignored. The list of models is cached for one
+// hour.
+//
+// Returns ErrModelNotFound if the model is not available.
+func (c *Client) GetModel(ctx context.Context, id ModelID) (Model, error) {
+	models, err := c.cachedModels(ctx)
+	if err != nil {
+		return Model{}, err
+	}
+
+	// Some variants, like :free, are listed as separate models
+	for _, model := range models {
+		if model.ID == id {
+			return model, nil
+		}
+	}
+	for _, model := range models {
+		if model.ID == id.WithoutVariant() {
+			return model, nil
+		}
+	}
+
+	return Model{}, fmt.Errorf("%w: %q", ErrModelNotFound, id)
+}
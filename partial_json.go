@@ -0,0 +1,90 @@
+package openroutergo
+
+import (
+	"strings"
+
+	"github.com/zachczx/openroutergo/internal/partialjson"
+)
+
+// PartialJSONParser incrementally decodes the JSON content of a structured output while
+// the model generates it, so the values can be used before the generation finishes.
+//
+// It decodes the content the same way as ExecuteInto, so types that are not structs
+// are expected to be wrapped in an object with a single "value" property.
+//
+// Example:
+//
+//	parser := openroutergo.NewPartialJSONParser[Entities]()
+//	for delta := range deltas {
+//		entities, err := parser.Feed(delta)
+//		if err != nil {
+//			// handle error
+//		}
+//		render(entities)
+//	}
+type PartialJSONParser[T any] struct {
+	content   strings.Builder
+	completer *partialjson.Completer
+	value     T
+	err       error
+	wrapped   bool
+}
+
+// NewPartialJSONParser creates a new parser for the JSON content of a structured output
+// of type T.
+func NewPartialJSONParser[T any]() *PartialJSONParser[T] {
+	var zero T
+	return &PartialJSONParser[T]{
+		content:   strings.Builder{},
+		completer: partialjson.NewCompleter(),
+		value:     zero,
+		err:       nil,
+		wrapped:   isStructuredOutputWrapped[T](),
+	}
+}
+
+// Feed adds a delta of the content generated by the model and returns the most complete
+// value that can be decoded from the content received so far.
+//
+// The objects and arrays that are still open are closed, the strings that are still
+// being generated are included with the text received so far, and the other values
+// and keys that are not complete are left out until they are. Any text before the JSON,
+// like markdown code fences, is ignored.
+//
+// If the content so far can't be decoded into T, it returns the last decoded value and
+// a *StructuredOutputError.
+//
+// Only the delta is scanned, and the content is decoded again only when the delta
+// completes more of the value.
+func (p *PartialJSONParser[T]) Feed(delta string) (T, error) {
+	p.content.WriteString(delta)
+
+	if !p.completer.Feed(delta) {
+		return p.value, p.err
+	}
+
+	completed, ok := p.completer.Complete()
+	if !ok {
+		p.err = nil
+		return p.value, nil
+	}
+
+	value, err := unmarshalStructuredOutput[T]([]byte(completed), p.wrapped)
+	if err != nil {
+		p.err = &StructuredOutputError{Content: p.content.String(), Err: err}
+		return p.value, p.err
+	}
+
+	p.value, p.err = value, nil
+	return value, nil
+}
+
+// Value returns the last value decoded by Feed.
+func (p *PartialJSONParser[T]) Value() T {
+	return p.value
+}
+
+// Content returns all the content received so far.
+func (p *PartialJSONParser[T]) Content() string {
+	return p.content.String()
+}
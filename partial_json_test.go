@@ -0,0 +1,51 @@
+package openroutergo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func TestPartialJSONParser(t *testing.T) {
+	type person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	tests := []struct {
+		delta    string
+		expected person
+	}{
+		{"```json\n{\"na", person{}},
+		{"me\": \"Ad", person{Name: "Ad"}},
+		{"a\", \"age\": 3", person{Name: "Ada"}},
+		{"6}", person{Name: "Ada", Age: 36}},
+		{"\n```", person{Name: "Ada", Age: 36}},
+	}
+
+	parser := NewPartialJSONParser[person]()
+	for _, tt := range tests {
+		value, err := parser.Feed(tt.delta)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, value)
+	}
+	assert.Equal(t, person{Name: "Ada", Age: 36}, parser.Value())
+}
+
+func TestPartialJSONParserError(t *testing.T) {
+	parser := NewPartialJSONParser[[]int]()
+
+	value, err := parser.Feed(`{"value": [1, 2, "th`)
+	var structuredErr *StructuredOutputError
+	assert.True(t, errors.As(err, &structuredErr))
+	assert.Equal(t, 0, len(value))
+
+	// The error is returned again while the delta doesn't change the completed content
+	_, err = parser.Feed(` `)
+	assert.True(t, errors.As(err, &structuredErr))
+
+	value, err = parser.Feed(`ree", 4]}`)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(value))
+}
@@ -0,0 +1,255 @@
+// Package schema provides a typed, fluent builder for the JSON Schemas used by the tool
+// parameters and the json_schema response format.
+//
+// A Schema is a map[string]any, so it can be passed anywhere a schema is accepted:
+//
+//		tool := openroutergo.ChatCompletionTool{
+//			Name:        "getWeather",
+//			Description: "Get the weather of a city",
+//			Parameters: schema.Object().
+//				Prop("city", schema.String().Desc("The name of the city")).
+//				Prop("unit", schema.Enum("celsius", "fahrenheit")).
+//				Required("city"),
+//		}
+//
+//	  - JSON Schema reference: https://json-schema.org/understanding-json-schema/reference
+package schema
+
+import "slices"
+
+// Schema is a JSON Schema, the methods set a keyword and return the same schema so they
+// can be chained.
+type Schema map[string]any
+
+// String returns a schema for a string.
+func String() Schema {
+	return Schema{"type": "string"}
+}
+
+// Integer returns a schema for an integer number.
+func Integer() Schema {
+	return Schema{"type": "integer"}
+}
+
+// Number returns a schema for a number.
+func Number() Schema {
+	return Schema{"type": "number"}
+}
+
+// Boolean returns a schema for a boolean.
+func Boolean() Schema {
+	return Schema{"type": "boolean"}
+}
+
+// Null returns a schema for null.
+func Null() Schema {
+	return Schema{"type": "null"}
+}
+
+// Any returns a schema that accepts any value.
+func Any() Schema {
+	return Schema{}
+}
+
+// Object returns a schema for an object without properties, use Prop and Required to
+// add them.
+func Object() Schema {
+	return Schema{"type": "object", "properties": map[string]any{}}
+}
+
+// Array returns a schema for an array with the given schema for its items.
+func Array(items Schema) Schema {
+	return Schema{"type": "array", "items": map[string]any(items)}
+}
+
+// Enum returns a schema that only accepts the given values, use Enum[any] for values of
+// different types.
+func Enum[T any](values ...T) Schema {
+	return Schema{}.Enum(anySlice(values)...)
+}
+
+// Const returns a schema that only accepts the given value.
+func Const(value any) Schema {
+	return Schema{"const": value}
+}
+
+// AnyOf returns a schema that accepts the values valid against any of the given schemas.
+func AnyOf(schemas ...Schema) Schema {
+	return Schema{"anyOf": schemaSlice(schemas)}
+}
+
+// Ref returns a schema that references the definition with the given name, see Def.
+func Ref(name string) Schema {
+	return Schema{"$ref": "#/$defs/" + name}
+}
+
+// Desc sets the description of the schema.
+func (s Schema) Desc(description string) Schema {
+	s["description"] = description
+	return s
+}
+
+// Title sets the title of the schema.
+func (s Schema) Title(title string) Schema {
+	s["title"] = title
+	return s
+}
+
+// Default sets the default value of the schema.
+func (s Schema) Default(value any) Schema {
+	s["default"] = value
+	return s
+}
+
+// Enum sets the values accepted by the schema.
+func (s Schema) Enum(values ...any) Schema {
+	s["enum"] = values
+	return s
+}
+
+// Format sets the format of a string schema, for example "email" or "date-time".
+func (s Schema) Format(format string) Schema {
+	s["format"] = format
+	return s
+}
+
+// Pattern sets the regular expression a string must match.
+func (s Schema) Pattern(pattern string) Schema {
+	s["pattern"] = pattern
+	return s
+}
+
+// MinLength sets the minimum length of a string.
+func (s Schema) MinLength(minLength int) Schema {
+	s["minLength"] = minLength
+	return s
+}
+
+// MaxLength sets the maximum length of a string.
+func (s Schema) MaxLength(maxLength int) Schema {
+	s["maxLength"] = maxLength
+	return s
+}
+
+// Minimum sets the minimum value of a number.
+func (s Schema) Minimum(minimum float64) Schema {
+	s["minimum"] = minimum
+	return s
+}
+
+// Maximum sets the maximum value of a number.
+func (s Schema) Maximum(maximum float64) Schema {
+	s["maximum"] = maximum
+	return s
+}
+
+// MinItems sets the minimum length of an array.
+func (s Schema) MinItems(minItems int) Schema {
+	s["minItems"] = minItems
+	return s
+}
+
+// MaxItems sets the maximum length of an array.
+func (s Schema) MaxItems(maxItems int) Schema {
+	s["maxItems"] = maxItems
+	return s
+}
+
+// Prop adds a property to an object schema.
+func (s Schema) Prop(name string, property Schema) Schema {
+	properties, ok := s["properties"].(map[string]any)
+	if !ok {
+		properties = map[string]any{}
+		s["properties"] = properties
+	}
+	properties[name] = map[string]any(property)
+	return s
+}
+
+// Required adds the given properties to the required properties of an object schema.
+func (s Schema) Required(names ...string) Schema {
+	required, _ := s["required"].([]string)
+	for _, name := range names {
+		if !slices.Contains(required, name) {
+			required = append(required, name)
+		}
+	}
+	s["required"] = required
+	return s
+}
+
+// AdditionalProperties sets whether an object schema accepts properties that are not
+// defined with Prop.
+func (s Schema) AdditionalProperties(allowed bool) Schema {
+	s["additionalProperties"] = allowed
+	return s
+}
+
+// Nullable makes the schema also accept null.
+func (s Schema) Nullable() Schema {
+	switch schemaType := s["type"].(type) {
+	case string:
+		if schemaType != "null" {
+			s["type"] = []any{schemaType, "null"}
+		}
+	case []any:
+		if !slices.Contains(schemaType, any("null")) {
+			s["type"] = append(schemaType, "null")
+		}
+	default:
+		nullable := Schema{}
+		for key, value := range s {
+			nullable[key] = value
+			delete(s, key)
+		}
+		s["anyOf"] = schemaSlice([]Schema{nullable, Null()})
+	}
+
+	if enum, ok := s["enum"].([]any); ok && !slices.Contains(enum, nil) {
+		s["enum"] = append(enum, nil)
+	}
+	return s
+}
+
+// Def adds a definition that can be referenced with Ref, usually to the root schema.
+func (s Schema) Def(name string, definition Schema) Schema {
+	defs, ok := s["$defs"].(map[string]any)
+	if !ok {
+		defs = map[string]any{}
+		s["$defs"] = defs
+	}
+	defs[name] = map[string]any(definition)
+	return s
+}
+
+// ResponseFormat returns the json_schema response format that can be passed to
+// WithResponseFormat, with the given name and strict mode enabled.
+func (s Schema) ResponseFormat(name string) map[string]any {
+	return map[string]any{
+		"type": "json_schema",
+		"json_schema": map[string]any{
+			"name":   name,
+			"strict": true,
+			"schema": map[string]any(s),
+		},
+	}
+}
+
+// anySlice converts the values to a []any.
+func anySlice[T any](values []T) []any {
+	result := make([]any, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
+
+// schemaSlice converts the schemas to a []any of map[string]any, the representation of
+// the schemas decoded from JSON.
+func schemaSlice(schemas []Schema) []any {
+	result := make([]any, len(schemas))
+	for i, schema := range schemas {
+		result[i] = map[string]any(schema)
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func marshal(t *testing.T, s Schema) string {
+	t.Helper()
+	b, err := json.Marshal(s)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func TestObject(t *testing.T) {
+	s := Object().
+		Prop("city", String().Desc("The name of the city")).
+		Prop("unit", Enum("celsius", "fahrenheit")).
+		Prop("days", Integer().Minimum(1).Maximum(7)).
+		Prop("tags", Array(String()).MinItems(1)).
+		Required("city", "unit").
+		Required("city").
+		AdditionalProperties(false)
+
+	assert.Equal(
+		t,
+		`{"additionalProperties":false,"properties":{"city":{"description":"The name of the city","type":"string"},`+
+			`"days":{"maximum":7,"minimum":1,"type":"integer"},"tags":{"items":{"type":"string"},"minItems":1,"type":"array"},`+
+			`"unit":{"enum":["celsius","fahrenheit"]}},"required":["city","unit"],"type":"object"}`,
+		marshal(t, s),
+	)
+}
+
+func TestNullable(t *testing.T) {
+	assert.Equal(t, `{"type":["string","null"]}`, marshal(t, String().Nullable()))
+	assert.Equal(t, `{"type":["string","null"]}`, marshal(t, String().Nullable().Nullable()))
+	assert.Equal(t, `{"enum":["a",null],"type":["string","null"]}`, marshal(t, String().Enum("a").Nullable()))
+	assert.Equal(t, `{"anyOf":[{"$ref":"#/$defs/node"},{"type":"null"}]}`, marshal(t, Ref("node").Nullable()))
+}
+
+func TestAnyOfAndDefs(t *testing.T) {
+	s := Object().
+		Def("node", Object().Prop("children", Array(Ref("node")))).
+		Prop("root", Ref("node")).
+		Prop("id", AnyOf(String(), Integer())).
+		Prop("kind", Const("tree"))
+
+	assert.Equal(
+		t,
+		`{"$defs":{"node":{"properties":{"children":{"items":{"$ref":"#/$defs/node"},"type":"array"}},"type":"object"}},`+
+			`"properties":{"id":{"anyOf":[{"type":"string"},{"type":"integer"}]},"kind":{"const":"tree"},`+
+			`"root":{"$ref":"#/$defs/node"}},"type":"object"}`,
+		marshal(t, s),
+	)
+}
+
+func TestAssignableToMap(t *testing.T) {
+	var parameters map[string]any = Object().Prop("a", String())
+
+	properties, ok := parameters["properties"].(map[string]any)
+	assert.True(t, ok)
+	_, ok = properties["a"].(map[string]any)
+	assert.True(t, ok)
+}
+
+func TestResponseFormat(t *testing.T) {
+	format := Object().Prop("a", Boolean()).Required("a").ResponseFormat("answer")
+	b, err := json.Marshal(format)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		`{"json_schema":{"name":"answer","schema":{"properties":{"a":{"type":"boolean"}},"required":["a"],"type":"object"},`+
+			`"strict":true},"type":"json_schema"}`,
+		string(b),
+	)
+}
@@ -0,0 +1,170 @@
+package openroutergo
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/zachczx/openroutergo/internal/jsonschema"
+)
+
+// StrictSchema returns a copy of the JSON Schema rewritten for the OpenAI style strict
+// mode, the original schema is not modified.
+//
+// Strict mode requires every object to set additionalProperties to false and to list
+// all of its properties as required, so the optional properties are made nullable
+// instead: the model sends null when it doesn't want to set them.
+//
+// The schemas of the tools with the Strict field set and of the json_schema response
+// formats with strict set to true are rewritten automatically before sending them.
+//
+// Returns ErrStrictSchema if the root of the schema is not an object or an object
+// allows additional properties with a schema (a map).
+func StrictSchema(schema map[string]any) (map[string]any, error) {
+	strict, err := jsonschema.Strict(schema)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrStrictSchema, err)
+	}
+	return strict, nil
+}
+
+// SanitizeSchema returns a copy of the JSON Schema adapted to the subset of JSON Schema
+// accepted by the provider of the model, the original schema is not modified.
+//
+// For example, Google Gemini models reject $ref, oneOf, const and most string formats,
+// so the references are inlined, oneOf becomes anyOf, const becomes an enum and the
+// unsupported formats are removed. The keywords that don't describe the value, like
+// $schema, are removed for every model.
+//
+// See the WithSchemaSanitization method to sanitize the schemas before sending them.
+func SanitizeSchema(schema map[string]any, model ModelID) (map[string]any, error) {
+	sanitized, err := jsonschema.Sanitize(schema, schemaDialect(model))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sanitize the schema for %q: %w", model, err)
+	}
+	return sanitized, nil
+}
+
+// schemaDialect returns the subset of JSON Schema accepted by the model family.
+func schemaDialect(model ModelID) jsonschema.Dialect {
+	switch model.Author() {
+	case "google":
+		return jsonschema.GeminiDialect
+	default:
+		return jsonschema.DefaultDialect
+	}
+}
+
+// WithSchemaSanitization sets whether the tool parameters and the json_schema response
+// format are adapted to the model before sending them, see SanitizeSchema.
+//
+// When fallback models are set, the schemas are adapted to all of them so the same
+// request works with any of the models.
+//
+// If not set, the schemas are sent as they are.
+func (b *chatCompletionBuilder) WithSchemaSanitization(enabled bool) *chatCompletionBuilder {
+	b.sanitizeSchemas = enabled
+	return b
+}
+
+// sanitizeSchema adapts the schema to the models of the request, if enabled.
+func (b *chatCompletionBuilder) sanitizeSchema(schema map[string]any) (map[string]any, error) {
+	if !b.sanitizeSchemas || schema == nil {
+		return schema, nil
+	}
+
+	models := b.requestModels()
+	if len(models) == 0 {
+		// The default model of the account is used, its family is unknown
+		models = append(models, ModelID(""))
+	}
+
+	// Each dialect is applied once, the result is accepted by all the models
+	applied := []string{}
+	for _, model := range models {
+		if slices.Contains(applied, model.Author()) {
+			continue
+		}
+		applied = append(applied, model.Author())
+
+		var err error
+		if schema, err = SanitizeSchema(schema, model); err != nil {
+			return nil, err
+		}
+	}
+
+	return schema, nil
+}
+
+// prepareTools rewrites the parameters of the strict tools and sanitizes them for the
+// models of the request.
+func (b *chatCompletionBuilder) prepareTools(tools []chatCompletionToolFunction) ([]chatCompletionToolFunction, error) {
+	prepared := make([]chatCompletionToolFunction, 0, len(tools))
+	for _, tool := range tools {
+		parameters := tool.Function.Parameters
+
+		if tool.Function.Strict && parameters != nil {
+			var err error
+			if parameters, err = StrictSchema(parameters); err != nil {
+				return nil, fmt.Errorf("invalid parameters for tool %q: %w", tool.Function.Name, err)
+			}
+		}
+
+		parameters, err := b.sanitizeSchema(parameters)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameters for tool %q: %w", tool.Function.Name, err)
+		}
+
+		tool.Function.Parameters = parameters
+		prepared = append(prepared, tool)
+	}
+	return prepared, nil
+}
+
+// requestResponseFormat returns the response format to send, the schema of a
+// json_schema response format in strict mode is rewritten for strict mode and
+// sanitized for the models of the request.
+//
+// Like for the tools, it returns ErrStrictSchema if the schema can't be rewritten for
+// strict mode, set strict to false to send it as it is.
+func (b *chatCompletionBuilder) requestResponseFormat() (map[string]any, error) {
+	responseFormat := b.responseFormat.Value
+	if formatType, _ := responseFormat["type"].(string); formatType != "json_schema" {
+		return responseFormat, nil
+	}
+
+	jsonSchema, ok := responseFormat["json_schema"].(map[string]any)
+	if !ok {
+		return responseFormat, nil
+	}
+	schema, ok := jsonSchema["schema"].(map[string]any)
+	if !ok {
+		return responseFormat, nil
+	}
+
+	if strict, _ := jsonSchema["strict"].(bool); strict {
+		var err error
+		if schema, err = StrictSchema(schema); err != nil {
+			return nil, fmt.Errorf("invalid response format schema: %w", err)
+		}
+	}
+
+	schema, err := b.sanitizeSchema(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response format schema: %w", err)
+	}
+
+	// Copy the maps so the response format of the builder is not modified
+	preparedJSONSchema := map[string]any{}
+	for key, value := range jsonSchema {
+		preparedJSONSchema[key] = value
+	}
+	preparedJSONSchema["schema"] = schema
+
+	prepared := map[string]any{}
+	for key, value := range responseFormat {
+		prepared[key] = value
+	}
+	prepared["json_schema"] = preparedJSONSchema
+
+	return prepared, nil
+}
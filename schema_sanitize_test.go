@@ -0,0 +1,158 @@
+package openroutergo
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func toJSON(t *testing.T, value any) string {
+	t.Helper()
+	data, err := json.Marshal(value)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func sanitizeTestSchema() map[string]any {
+	return map[string]any{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"type":       "object",
+		"properties": map[string]any{"kind": map[string]any{"const": "a"}},
+	}
+}
+
+func TestSanitizeSchemaModels(t *testing.T) {
+	tests := []struct {
+		name      string
+		builder   func(b *chatCompletionBuilder) *chatCompletionBuilder
+		sanitized string
+	}{
+		{
+			"Disabled",
+			func(b *chatCompletionBuilder) *chatCompletionBuilder {
+				return b.WithModel("google/gemini-2.0-flash-001")
+			},
+			`{"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"kind":{"const":"a"}},"type":"object"}`,
+		},
+		{
+			"DefaultModel",
+			func(b *chatCompletionBuilder) *chatCompletionBuilder {
+				return b.WithSchemaSanitization(true)
+			},
+			`{"properties":{"kind":{"const":"a"}},"type":"object"}`,
+		},
+		{
+			"Gemini",
+			func(b *chatCompletionBuilder) *chatCompletionBuilder {
+				return b.WithSchemaSanitization(true).WithModel("google/gemini-2.0-flash-001")
+			},
+			`{"properties":{"kind":{"enum":["a"]}},"type":"object"}`,
+		},
+		{
+			// The schema is accepted by every model of the request
+			"GeminiFallback",
+			func(b *chatCompletionBuilder) *chatCompletionBuilder {
+				return b.WithSchemaSanitization(true).
+					WithModel("openai/gpt-4o").
+					WithModelFallback("google/gemini-2.0-flash-001")
+			},
+			`{"properties":{"kind":{"enum":["a"]}},"type":"object"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.builder(newTestClient(t, "http://localhost").NewChatCompletion())
+			schema := sanitizeTestSchema()
+
+			sanitized, err := b.sanitizeSchema(schema)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.sanitized, toJSON(t, sanitized))
+
+			// The original schema is not modified
+			assert.Equal(t, toJSON(t, sanitizeTestSchema()), toJSON(t, schema))
+		})
+	}
+}
+
+func TestPrepareTools(t *testing.T) {
+	mapSchema := map[string]any{
+		"type":                 "object",
+		"additionalProperties": map[string]any{"type": "string"},
+	}
+
+	tests := []struct {
+		name       string
+		tool       ChatCompletionTool
+		parameters string
+		err        error
+	}{
+		{
+			"NotStrict",
+			ChatCompletionTool{Name: "tool", Description: "", Parameters: sanitizeTestSchema(), Strict: false},
+			`{"properties":{"kind":{"enum":["a"]}},"type":"object"}`,
+			nil,
+		},
+		{
+			"Strict",
+			ChatCompletionTool{Name: "tool", Description: "", Parameters: sanitizeTestSchema(), Strict: true},
+			`{"properties":{"kind":{"enum":["a"],"nullable":true}},"required":["kind"],"type":"object"}`,
+			nil,
+		},
+		{
+			"StrictMap",
+			ChatCompletionTool{Name: "tool", Description: "", Parameters: mapSchema, Strict: true},
+			"",
+			ErrStrictSchema,
+		},
+		{
+			"WithoutParameters",
+			ChatCompletionTool{Name: "tool", Description: "", Parameters: nil, Strict: true},
+			"null",
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestClient(t, "http://localhost").
+				NewChatCompletion().
+				WithSchemaSanitization(true).
+				WithModel("google/gemini-2.0-flash-001").
+				WithTool(tt.tool)
+
+			tools, err := b.prepareTools(b.requestTools())
+			if tt.err != nil {
+				assert.True(t, errors.Is(err, tt.err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.parameters, toJSON(t, tools[0].Function.Parameters))
+		})
+	}
+}
+
+func TestRequestResponseFormatStrict(t *testing.T) {
+	responseFormat := func(strict bool) map[string]any {
+		return map[string]any{
+			"type": "json_schema",
+			"json_schema": map[string]any{
+				"name":   "result",
+				"strict": strict,
+				"schema": map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+			},
+		}
+	}
+
+	// Like the strict tools, a strict schema that can't be rewritten is an error
+	b := newTestClient(t, "http://localhost").NewChatCompletion().WithResponseFormat(responseFormat(true))
+	_, err := b.requestResponseFormat()
+	assert.True(t, errors.Is(err, ErrStrictSchema))
+
+	b = newTestClient(t, "http://localhost").NewChatCompletion().WithResponseFormat(responseFormat(false))
+	prepared, err := b.requestResponseFormat()
+	assert.NoError(t, err)
+	assert.Equal(t, toJSON(t, responseFormat(false)), toJSON(t, prepared))
+}
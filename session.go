@@ -0,0 +1,188 @@
+package openroutergo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+// Session is a conversation persisted in a ConversationStore, it loads the history
+// before each request and saves it after, so multi-turn chat applications (for example,
+// stateless HTTP handlers) don't have to do it around Execute.
+//
+// The first request saves the whole conversation, the next ones only append the new
+// messages, so requests to the same conversation running concurrently don't overwrite
+// each other (although their messages can be interleaved). The whole conversation is
+// saved again when its options or its stored messages were changed.
+//
+// Example:
+//
+//	session := client.
+//		NewSession(store, conversationID).
+//		WithTemplate(client.NewChatCompletion().WithModel("...").WithSystemMessage("..."))
+//
+//	resp, err := session.Send(ctx, "What is the capital of France?")
+type Session struct {
+	client   *Client
+	store    ConversationStore
+	id       string
+	template *chatCompletionBuilder
+}
+
+// NewSession creates a session for the conversation with the given ID in the store.
+func (c *Client) NewSession(store ConversationStore, id string) *Session {
+	return &Session{
+		client:   c,
+		store:    store,
+		id:       id,
+		template: c.NewChatCompletion(),
+	}
+}
+
+// WithTemplate sets the builder used to start the conversation when it's not in the
+// store yet, for example, with the model and the system message.
+//
+// The tool registry of the template is also set on the conversations loaded from the
+// store because it can't be persisted.
+func (s *Session) WithTemplate(template *chatCompletionBuilder) *Session {
+	s.template = template.Clone()
+	return s
+}
+
+// ID returns the ID of the conversation.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Completion returns the builder with the conversation loaded from the store, or a clone
+// of the template if the conversation doesn't exist yet.
+//
+// Add messages or change the options of the builder and pass it to Execute to save it.
+func (s *Session) Completion(ctx context.Context) (*chatCompletionBuilder, error) {
+	snapshot, err := s.store.Load(ctx, s.id)
+	if errors.Is(err, ErrConversationNotFound) {
+		return s.template.Clone().WithContext(ctx), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	completion, err := s.client.RestoreChatCompletion(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	completion.toolRegistry = s.template.toolRegistry
+	completion.stored = newStoredConversation(s.id, completion.Snapshot())
+	return completion.WithContext(ctx), nil
+}
+
+// Execute executes the chat completion request of the given builder, usually returned by
+// Completion, and saves the conversation with the response to the store.
+//
+// If the builder was loaded from the store and only messages were added since then,
+// the new messages are appended to the stored conversation, otherwise the whole
+// conversation is saved.
+func (s *Session) Execute(ctx context.Context, completion *chatCompletionBuilder) (ChatCompletionResponse, error) {
+	_, resp, err := completion.execute(ctx)
+	if err != nil {
+		return ChatCompletionResponse{}, err
+	}
+
+	snapshot := completion.Snapshot()
+	if err := s.persist(ctx, completion.stored, snapshot); err != nil {
+		return resp, err
+	}
+	completion.stored = newStoredConversation(s.id, snapshot)
+	return resp, nil
+}
+
+// persist appends the messages added to the stored conversation or, if it was not
+// loaded from the store or its options changed, saves the whole conversation.
+func (s *Session) persist(ctx context.Context, stored *storedConversation, snapshot ChatCompletionSnapshot) error {
+	if stored.canAppend(s.id, snapshot) {
+		added := snapshot.Messages[stored.messages:]
+		if len(added) == 0 {
+			return nil
+		}
+
+		err := s.store.Append(ctx, s.id, added...)
+		if !errors.Is(err, ErrConversationNotFound) {
+			return err
+		}
+	}
+
+	return s.store.Save(ctx, s.id, snapshot)
+}
+
+// storedConversation is the state of a conversation when it was loaded from or saved
+// to the store, to know which messages were added since then.
+type storedConversation struct {
+	id       string
+	header   ChatCompletionSnapshot
+	messages int
+	// The hash of the stored messages, nil if they can't be encoded.
+	hash []byte
+}
+
+// newStoredConversation returns the stored state of the snapshot.
+func newStoredConversation(id string, snapshot ChatCompletionSnapshot) *storedConversation {
+	messages := len(snapshot.Messages)
+	hash := hashMessages(snapshot.Messages)
+	snapshot.Messages = nil
+	return &storedConversation{id: id, header: snapshot, messages: messages, hash: hash}
+}
+
+// canAppend returns true if the snapshot is the stored conversation with new messages,
+// the stored messages must not have been modified or removed.
+func (c *storedConversation) canAppend(id string, snapshot ChatCompletionSnapshot) bool {
+	if c == nil || c.id != id || c.hash == nil || len(snapshot.Messages) < c.messages {
+		return false
+	}
+	if !bytes.Equal(c.hash, hashMessages(snapshot.Messages[:c.messages])) {
+		return false
+	}
+	snapshot.Messages = nil
+	return reflect.DeepEqual(c.header, snapshot)
+}
+
+// hashMessages returns the SHA-256 hash of the JSON encoding of the messages, or nil if
+// they can't be encoded.
+func hashMessages(messages []ChatCompletionMessage) []byte {
+	h := sha256.New()
+	if err := json.NewEncoder(h).Encode(messages); err != nil {
+		return nil
+	}
+	return h.Sum(nil)
+}
+
+// Send loads the conversation, adds the user message, executes the request and saves
+// the conversation with the response to the store.
+func (s *Session) Send(ctx context.Context, message string) (ChatCompletionResponse, error) {
+	completion, err := s.Completion(ctx)
+	if err != nil {
+		return ChatCompletionResponse{}, err
+	}
+
+	return s.Execute(ctx, completion.WithUserMessage(message))
+}
+
+// Messages returns the messages of the conversation saved in the store, or nil if it
+// doesn't exist yet.
+func (s *Session) Messages(ctx context.Context) ([]ChatCompletionMessage, error) {
+	snapshot, err := s.store.Load(ctx, s.id)
+	if errors.Is(err, ErrConversationNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Messages, nil
+}
+
+// Delete removes the conversation from the store.
+func (s *Session) Delete(ctx context.Context) error {
+	return s.store.Delete(ctx, s.id)
+}
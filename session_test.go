@@ -0,0 +1,100 @@
+package openroutergo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+// recordingConversationStore records the calls that modify the wrapped store.
+type recordingConversationStore struct {
+	ConversationStore
+	calls []string
+}
+
+func (s *recordingConversationStore) Save(ctx context.Context, id string, snapshot ChatCompletionSnapshot) error {
+	s.calls = append(s.calls, "save:"+roles(snapshot.Messages))
+	return s.ConversationStore.Save(ctx, id, snapshot)
+}
+
+func (s *recordingConversationStore) Append(ctx context.Context, id string, messages ...ChatCompletionMessage) error {
+	s.calls = append(s.calls, "append:"+roles(messages))
+	return s.ConversationStore.Append(ctx, id, messages...)
+}
+
+func TestSessionAppendsNewMessages(t *testing.T) {
+	server := newFakeOpenRouter(t, textResponse("Paris"), textResponse("Berlin"), textResponse("Rome"))
+	fileStore, err := NewFileConversationStore(t.TempDir())
+	assert.NoError(t, err)
+	store := &recordingConversationStore{ConversationStore: fileStore, calls: nil}
+
+	ctx := context.Background()
+	session := server.client.
+		NewSession(store, "conversation").
+		WithTemplate(server.client.NewChatCompletion().WithModel("openai/gpt-4o").WithSystemMessage("Be brief"))
+
+	// The first turn saves the conversation and the next ones append the new messages
+	_, err = session.Send(ctx, "What is the capital of France?")
+	assert.NoError(t, err)
+	_, err = session.Send(ctx, "And of Germany?")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(store.calls))
+	assert.Equal(t, "save:system,user,assistant", store.calls[0])
+	assert.Equal(t, "append:user,assistant", store.calls[1])
+
+	// Changing the options of the conversation saves it again
+	completion, err := session.Completion(ctx)
+	assert.NoError(t, err)
+	_, err = session.Execute(ctx, completion.WithTemperature(0.5).WithUserMessage("And of Italy?"))
+	assert.NoError(t, err)
+	assert.Equal(t, "save:system,user,assistant,user,assistant,user,assistant", store.calls[2])
+
+	messages, err := session.Messages(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "system,user,assistant,user,assistant,user,assistant", roles(messages))
+	assert.Equal(t, "Berlin", messages[4].Content)
+}
+
+func TestSessionAppendsDeletedConversation(t *testing.T) {
+	server := newFakeOpenRouter(t, textResponse("Paris"), textResponse("Berlin"))
+	store := &recordingConversationStore{ConversationStore: NewMemoryConversationStore(), calls: nil}
+
+	ctx := context.Background()
+	session := server.client.NewSession(store, "conversation")
+	_, err := session.Send(ctx, "What is the capital of France?")
+	assert.NoError(t, err)
+
+	// The conversation is saved again if it was deleted while the request was running
+	completion, err := session.Completion(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, session.Delete(ctx))
+	_, err = session.Execute(ctx, completion.WithUserMessage("And of Germany?"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(store.calls))
+	assert.Equal(t, "append:user,assistant", store.calls[1])
+	assert.Equal(t, "save:user,assistant,user,assistant", store.calls[2])
+}
+
+func TestSessionSavesModifiedMessages(t *testing.T) {
+	server := newFakeOpenRouter(t, textResponse("Paris"), textResponse("Berlin"))
+	store := &recordingConversationStore{ConversationStore: NewMemoryConversationStore(), calls: nil}
+
+	ctx := context.Background()
+	session := server.client.NewSession(store, "conversation")
+	_, err := session.Send(ctx, "What is the capital of France?")
+	assert.NoError(t, err)
+
+	// A stored message is modified, so appending the new ones would keep the old one
+	completion, err := session.Completion(ctx)
+	assert.NoError(t, err)
+	completion.messages[1].Content = "Paris, of course"
+	_, err = session.Execute(ctx, completion.WithUserMessage("And of Germany?"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(store.calls))
+	assert.Equal(t, "save:user,assistant,user,assistant", store.calls[1])
+
+	messages, err := session.Messages(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "Paris, of course", messages[1].Content)
+}
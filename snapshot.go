@@ -0,0 +1,274 @@
+package openroutergo
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/zachczx/openroutergo/internal/optional"
+)
+
+// ChatCompletionSnapshotVersion is the version of the ChatCompletionSnapshot format,
+// it's increased when the format changes in a way that is not backwards compatible.
+const ChatCompletionSnapshotVersion = 1
+
+// ChatCompletionSnapshot is the serializable state of a chat completion builder, so a
+// conversation can be stored (for example, in a database) and restored later onto any
+// client, see the Snapshot and RestoreChatCompletion methods.
+//
+// The nil fields are the options that are not set. The context, the client and the
+// tool registry (with the tool handlers) are not included, set them again after
+// restoring the builder.
+type ChatCompletionSnapshot struct {
+	// The version of the snapshot format, see ChatCompletionSnapshotVersion.
+	Version int `json:"version"`
+
+	Messages           []ChatCompletionMessage `json:"messages"`
+	Model              *string                 `json:"model,omitempty"`
+	FallbackModels     []string                `json:"fallback_models,omitempty"`
+	Temperature        *float64                `json:"temperature,omitempty"`
+	TopP               *float64                `json:"top_p,omitempty"`
+	TopK               *int                    `json:"top_k,omitempty"`
+	FrequencyPenalty   *float64                `json:"frequency_penalty,omitempty"`
+	PresencePenalty    *float64                `json:"presence_penalty,omitempty"`
+	RepetitionPenalty  *float64                `json:"repetition_penalty,omitempty"`
+	MinP               *float64                `json:"min_p,omitempty"`
+	TopA               *float64                `json:"top_a,omitempty"`
+	Seed               *int                    `json:"seed,omitempty"`
+	MaxTokens          *int                    `json:"max_tokens,omitempty"`
+	LogitBias          map[int]int             `json:"logit_bias,omitempty"`
+	Logprobs           *bool                   `json:"logprobs,omitempty"`
+	TopLogprobs        *int                    `json:"top_logprobs,omitempty"`
+	ResponseFormat     map[string]any          `json:"response_format,omitempty"`
+	StructuredOutputs  *bool                   `json:"structured_outputs,omitempty"`
+	Stop               []string                `json:"stop,omitempty"`
+	Prediction         *string                 `json:"prediction,omitempty"`
+	Tools              []ChatCompletionTool    `json:"tools,omitempty"`
+	ToolChoice         *string                 `json:"tool_choice,omitempty"`
+	MaxPromptPrice     *float64                `json:"max_prompt_price,omitempty"`
+	MaxCompletionPrice *float64                `json:"max_completion_price,omitempty"`
+
+	Debug                   bool          `json:"debug,omitempty"`
+	JSONRepair              bool          `json:"json_repair,omitempty"`
+	SchemaSanitization      bool          `json:"schema_sanitization,omitempty"`
+	ToolEmulation           EmulationMode `json:"tool_emulation"`
+	ResponseFormatEmulation EmulationMode `json:"response_format_emulation"`
+	StructuredRetries       int           `json:"structured_output_retries,omitempty"`
+	MaxToolIterations       int           `json:"max_tool_iterations"`
+	ToolConcurrency         int           `json:"tool_concurrency"`
+	ToolTimeout             time.Duration `json:"tool_timeout,omitempty"`
+	ToolValidationFeedback  bool          `json:"tool_validation_feedback,omitempty"`
+
+	Truncation               TruncationStrategy `json:"truncation"`
+	ContextLength            *int               `json:"context_length,omitempty"`
+	ReservedCompletionTokens *int               `json:"reserved_completion_tokens,omitempty"`
+	KeepLastMessages         int                `json:"keep_last_messages,omitempty"`
+}
+
+// Snapshot returns the serializable state of the builder, including the conversation,
+// so it can be restored later with the RestoreChatCompletion method.
+//
+// Example:
+//
+//	data, err := json.Marshal(completion.Snapshot())
+//	// store data in the database
+//
+//	var snapshot openroutergo.ChatCompletionSnapshot
+//	err = json.Unmarshal(data, &snapshot)
+//	completion, err := client.RestoreChatCompletion(snapshot)
+func (b *chatCompletionBuilder) Snapshot() ChatCompletionSnapshot {
+	tools := make([]ChatCompletionTool, 0, len(b.tools))
+	for _, tool := range b.tools {
+		tool.Function.Parameters = deepCopy(tool.Function.Parameters)
+		tools = append(tools, tool.Function)
+	}
+
+	return ChatCompletionSnapshot{
+		Version:                 ChatCompletionSnapshotVersion,
+		Messages:                slices.Clone(b.messages),
+		Model:                   snapshotValue(b.model),
+		FallbackModels:          slices.Clone(b.fallbackModels),
+		Temperature:             snapshotValue(b.temperature),
+		TopP:                    snapshotValue(b.topP),
+		TopK:                    snapshotValue(b.topK),
+		FrequencyPenalty:        snapshotValue(b.frequencyPenalty),
+		PresencePenalty:         snapshotValue(b.presencePenalty),
+		RepetitionPenalty:       snapshotValue(b.repetitionPenalty),
+		MinP:                    snapshotValue(b.minP),
+		TopA:                    snapshotValue(b.topA),
+		Seed:                    snapshotValue(b.seed),
+		MaxTokens:               snapshotValue(b.maxTokens),
+		LogitBias:               deepCopy(b.logitBias.Value),
+		Logprobs:                snapshotValue(b.logprobs),
+		TopLogprobs:             snapshotValue(b.topLogprobs),
+		ResponseFormat:          deepCopy(b.responseFormat.Value),
+		StructuredOutputs:       snapshotValue(b.structuredOutputs),
+		Stop:                    slices.Clone(b.stop),
+		Prediction:              snapshotValue(b.prediction),
+		Tools:                   tools,
+		ToolChoice:              snapshotValue(b.toolChoice),
+		MaxPromptPrice:          snapshotValue(b.maxPromptPrice),
+		MaxCompletionPrice:      snapshotValue(b.maxCompletionPrice),
+		Debug:                   b.debug,
+		JSONRepair:              b.jsonRepair,
+		SchemaSanitization:      b.sanitizeSchemas,
+		ToolEmulation:           b.toolEmulation,
+		ResponseFormatEmulation: b.formatEmulation,
+		StructuredRetries:       b.structuredRetries,
+		MaxToolIterations:       b.maxToolIterations,
+		ToolConcurrency:         b.toolConcurrency,
+		ToolTimeout:             b.toolTimeout,
+		ToolValidationFeedback:  b.toolArgsFeedback,
+
+		Truncation:               b.truncation,
+		ContextLength:            snapshotValue(b.contextLength),
+		ReservedCompletionTokens: snapshotValue(b.reservedTokens),
+		KeepLastMessages:         b.keepLastMessages,
+	}
+}
+
+// RestoreChatCompletion creates a new chat completion builder with the state of the
+// given snapshot, see the Snapshot method.
+//
+// The builder uses context.Background() and has no tool registry, set them again with
+// WithContext and WithToolRegistry if needed. The other options are restored exactly,
+// so the Snapshot method of the restored builder returns an equal snapshot.
+func (c *Client) RestoreChatCompletion(snapshot ChatCompletionSnapshot) (*chatCompletionBuilder, error) {
+	if snapshot.Version != ChatCompletionSnapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, snapshot.Version)
+	}
+
+	toolEmulation, err := restoreEmulationMode(snapshot.ToolEmulation)
+	if err != nil {
+		return nil, err
+	}
+	formatEmulation, err := restoreEmulationMode(snapshot.ResponseFormatEmulation)
+	if err != nil {
+		return nil, err
+	}
+	truncation := snapshot.Truncation
+	if truncation.Value == "" {
+		truncation = TruncationOff
+	}
+	if !TruncationStrategies.Contains(truncation) {
+		return nil, fmt.Errorf("%w: unknown truncation strategy %q", ErrInvalidSnapshot, truncation.Value)
+	}
+
+	b := c.NewChatCompletion()
+	b.debug = snapshot.Debug
+	b.messages = slices.Clone(snapshot.Messages)
+	b.model = restoreValue(snapshot.Model)
+	b.fallbackModels = slices.Clone(snapshot.FallbackModels)
+	b.temperature = restoreValue(snapshot.Temperature)
+	b.topP = restoreValue(snapshot.TopP)
+	b.topK = restoreValue(snapshot.TopK)
+	b.frequencyPenalty = restoreValue(snapshot.FrequencyPenalty)
+	b.presencePenalty = restoreValue(snapshot.PresencePenalty)
+	b.repetitionPenalty = restoreValue(snapshot.RepetitionPenalty)
+	b.minP = restoreValue(snapshot.MinP)
+	b.topA = restoreValue(snapshot.TopA)
+	b.seed = restoreValue(snapshot.Seed)
+	b.maxTokens = restoreValue(snapshot.MaxTokens)
+	b.logitBias = optional.MapIntInt{IsSet: snapshot.LogitBias != nil, Value: deepCopy(snapshot.LogitBias)}
+	b.logprobs = restoreValue(snapshot.Logprobs)
+	b.topLogprobs = restoreValue(snapshot.TopLogprobs)
+	b.responseFormat = optional.MapStringAny{
+		IsSet: snapshot.ResponseFormat != nil,
+		Value: deepCopy(snapshot.ResponseFormat),
+	}
+	b.structuredOutputs = restoreValue(snapshot.StructuredOutputs)
+	b.stop = append([]string{}, snapshot.Stop...)
+	b.prediction = restoreValue(snapshot.Prediction)
+	for _, tool := range snapshot.Tools {
+		tool.Parameters = deepCopy(tool.Parameters)
+		b.WithTool(tool)
+	}
+	b.toolChoice = restoreValue(snapshot.ToolChoice)
+	b.maxPromptPrice = restoreValue(snapshot.MaxPromptPrice)
+	b.maxCompletionPrice = restoreValue(snapshot.MaxCompletionPrice)
+	b.jsonRepair = snapshot.JSONRepair
+	b.sanitizeSchemas = snapshot.SchemaSanitization
+	b.toolEmulation = toolEmulation
+	b.formatEmulation = formatEmulation
+	b.structuredRetries = max(snapshot.StructuredRetries, 0)
+	b.maxToolIterations = snapshot.MaxToolIterations
+	b.toolConcurrency = snapshot.ToolConcurrency
+	b.toolTimeout = snapshot.ToolTimeout
+	b.toolArgsFeedback = snapshot.ToolValidationFeedback
+	b.truncation = truncation
+	b.contextLength = restoreValue(snapshot.ContextLength)
+	b.reservedTokens = restoreValue(snapshot.ReservedCompletionTokens)
+	b.keepLastMessages = snapshot.KeepLastMessages
+
+	return b, nil
+}
+
+// snapshotValue returns a pointer to the value of the optional, or nil if it's not set.
+func snapshotValue[T any](o optional.Optional[T]) *T {
+	if !o.IsSet {
+		return nil
+	}
+	value := o.Value
+	return &value
+}
+
+// restoreValue returns the optional for the given pointer, not set if it's nil.
+func restoreValue[T any](value *T) optional.Optional[T] {
+	if value == nil {
+		return optional.Optional[T]{IsSet: false}
+	}
+	return optional.Optional[T]{IsSet: true, Value: *value}
+}
+
+// deepCopy returns a copy of the value that doesn't share any map or slice with it, for
+// example, of a JSON Schema.
+func deepCopy[T any](value T) T {
+	copied, _ := deepCopyValue(reflect.ValueOf(&value).Elem()).Interface().(T)
+	return copied
+}
+
+func deepCopyValue(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), deepCopyValue(iter.Value()))
+		}
+		return copied
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := range value.Len() {
+			copied.Index(i).Set(deepCopyValue(value.Index(i)))
+		}
+		return copied
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.New(value.Type()).Elem()
+		copied.Set(deepCopyValue(value.Elem()))
+		return copied
+	default:
+		return value
+	}
+}
+
+// restoreEmulationMode validates the emulation mode of a snapshot, the empty mode is
+// EmulationOff.
+func restoreEmulationMode(mode EmulationMode) (EmulationMode, error) {
+	if mode.Value == "" {
+		return EmulationOff, nil
+	}
+	if !EmulationModes.Contains(mode) {
+		return EmulationOff, fmt.Errorf("%w: unknown emulation mode %q", ErrInvalidSnapshot, mode.Value)
+	}
+	return mode, nil
+}
@@ -0,0 +1,149 @@
+package openroutergo
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func newTestClient(t *testing.T, baseURL string) *Client {
+	t.Helper()
+	client, err := NewClient().WithBaseURL(baseURL).WithAPIKey("key").Create()
+	assert.NoError(t, err)
+	return client
+}
+
+// snapshotJSON returns the JSON of the snapshot of the builder, to compare snapshots.
+func snapshotJSON(t *testing.T, b *chatCompletionBuilder) string {
+	t.Helper()
+	data, err := json.Marshal(b.Snapshot())
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	client := newTestClient(t, "http://localhost")
+
+	tests := []struct {
+		name    string
+		builder *chatCompletionBuilder
+	}{
+		{"Defaults", client.NewChatCompletion()},
+		{
+			"AllOptions",
+			client.NewChatCompletion().
+				WithModel("openai/gpt-4o").
+				WithModelFallback("anthropic/claude-3.5-sonnet").
+				WithSystemMessage("You are helpful.").
+				WithUserMessage("Hi", "alice").
+				WithAssistantMessage("Hello!").
+				WithTemperature(0.5).
+				WithTopP(0.9).
+				WithTopK(40).
+				WithFrequencyPenalty(0.1).
+				WithPresencePenalty(0.2).
+				WithRepetitionPenalty(1.1).
+				WithMinP(0.05).
+				WithTopA(0.3).
+				WithSeed(42).
+				WithMaxTokens(256).
+				WithLogitBias(map[int]int{50256: -100}).
+				WithLogprobs(true).
+				WithTopLogprobs(3).
+				WithResponseFormat(map[string]any{
+					"type":        "json_schema",
+					"json_schema": map[string]any{"name": "answer", "schema": map[string]any{"type": "object"}},
+				}).
+				WithStructuredOutputs(true).
+				WithStop([]string{"END"}).
+				WithPrediction("Hello").
+				WithTool(ChatCompletionTool{
+					Name:       "get_weather",
+					Parameters: map[string]any{"type": "object", "required": []any{"city"}},
+				}).
+				WithToolChoice("auto").
+				WithMaxPrice(1, 2).
+				WithDebug(true).
+				WithJSONRepair(true).
+				WithSchemaSanitization(true).
+				WithToolEmulation(EmulationAuto).
+				WithResponseFormatEmulation(EmulationAlways).
+				WithStructuredOutputRetries(2).
+				WithMaxToolIterations(5).
+				WithToolConcurrency(0).
+				WithToolTimeout(time.Second).
+				WithToolValidationFeedback(true).
+				WithTruncation(TruncationKeepLast).
+				WithContextLength(8000).
+				WithReservedCompletionTokens(512).
+				WithKeepLastMessages(3),
+		},
+		{"NoToolConcurrencyLimit", client.NewChatCompletion().WithToolConcurrency(-1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := snapshotJSON(t, tt.builder)
+
+			var snapshot ChatCompletionSnapshot
+			assert.NoError(t, json.Unmarshal([]byte(expected), &snapshot))
+			restored, err := client.RestoreChatCompletion(snapshot)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, snapshotJSON(t, restored))
+
+			// Restoring the snapshot directly, without JSON, gives the same result
+			restored, err = client.RestoreChatCompletion(tt.builder.Snapshot())
+			assert.NoError(t, err)
+			assert.Equal(t, expected, snapshotJSON(t, restored))
+		})
+	}
+}
+
+func TestSnapshotDeepCopy(t *testing.T) {
+	client := newTestClient(t, "http://localhost")
+	parameters := map[string]any{"type": "object", "properties": map[string]any{}}
+	builder := client.NewChatCompletion().
+		WithLogitBias(map[int]int{1: 1}).
+		WithResponseFormat(map[string]any{"type": "json_schema", "json_schema": map[string]any{"strict": true}}).
+		WithTool(ChatCompletionTool{Name: "tool", Parameters: parameters})
+	expected := snapshotJSON(t, builder)
+
+	// Changing the snapshot doesn't change the builder
+	snapshot := builder.Snapshot()
+	snapshot.LogitBias[1] = 2
+	snapshot.ResponseFormat["json_schema"].(map[string]any)["strict"] = false
+	snapshot.Tools[0].Parameters["properties"].(map[string]any)["city"] = map[string]any{}
+	assert.Equal(t, expected, snapshotJSON(t, builder))
+
+	// Changing the snapshot after restoring it doesn't change the restored builder
+	snapshot = builder.Snapshot()
+	restored, err := client.RestoreChatCompletion(snapshot)
+	assert.NoError(t, err)
+	snapshot.ResponseFormat["type"] = "json_object"
+	snapshot.Tools[0].Parameters["type"] = "array"
+	assert.Equal(t, expected, snapshotJSON(t, restored))
+}
+
+func TestRestoreChatCompletionInvalid(t *testing.T) {
+	client := newTestClient(t, "http://localhost")
+
+	tests := []struct {
+		name     string
+		snapshot ChatCompletionSnapshot
+	}{
+		{"Version", ChatCompletionSnapshot{Version: 2}},
+		{"ToolEmulation", ChatCompletionSnapshot{Version: 1, ToolEmulation: EmulationMode{"sometimes"}}},
+		{"ResponseFormatEmulation", ChatCompletionSnapshot{Version: 1, ResponseFormatEmulation: EmulationMode{"x"}}},
+		{"Truncation", ChatCompletionSnapshot{Version: 1, Truncation: TruncationStrategy{"middle"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.RestoreChatCompletion(tt.snapshot)
+			assert.True(t, errors.Is(err, ErrInvalidSnapshot))
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package openroutergo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/zachczx/openroutergo/internal/jsonrepair"
+	"github.com/zachczx/openroutergo/internal/jsonschema"
+)
+
+// structuredOutputWrapperKey is the property used to wrap the types that are not
+// objects, because the root of a response format schema must be an object.
+const structuredOutputWrapperKey = "value"
+
+// structuredOutputRetryMessage is the user message sent to the model when its response
+// is not a valid structured output, see WithStructuredOutputRetries.
+const structuredOutputRetryMessage = "Your previous response is not valid: %v. " +
+	"Respond again with only the JSON object that matches the required schema, " +
+	"without any other text."
+
+// structuredOutputNameRegex matches the characters not allowed in the name of a
+// json_schema response format.
+var structuredOutputNameRegex = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// StructuredOutputError is returned by ExecuteInto when the content generated by the
+// model can't be decoded into the target type or doesn't match its schema.
+//
+// It matches ErrInvalidStructuredOutput when using errors.Is.
+type StructuredOutputError struct {
+	// The raw content generated by the model.
+	Content string
+	// The decoding or validation error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("%s: %v", ErrInvalidStructuredOutput, e.Err)
+}
+
+// Unwrap returns ErrInvalidStructuredOutput and the decoding or validation error.
+func (e *StructuredOutputError) Unwrap() []error {
+	return []error{ErrInvalidStructuredOutput, e.Err}
+}
+
+// structuredOutput is the response format and the decoder derived from a Go type.
+type structuredOutput[T any] struct {
+	schema  map[string]any
+	wrapped bool
+	strict  bool
+}
+
+// newStructuredOutput derives the JSON Schema of T, the types that are not structs are
+// wrapped in an object with a single "value" property.
+func newStructuredOutput[T any]() (structuredOutput[T], error) {
+	t := structuredOutputType[T]()
+	schema, err := jsonschema.FromType(t)
+	if err != nil {
+		return structuredOutput[T]{}, fmt.Errorf("%w: %w", ErrInvalidStructuredOutputType, err)
+	}
+
+	wrapped := isStructuredOutputWrapped[T]()
+	if wrapped {
+		schema = map[string]any{
+			"type":                 "object",
+			"properties":           map[string]any{structuredOutputWrapperKey: schema},
+			"required":             []string{structuredOutputWrapperKey},
+			"additionalProperties": false,
+		}
+	}
+
+	// Types that can't be represented in strict mode, like maps, use the normal mode
+	strict := true
+	if strictSchema, err := jsonschema.Strict(schema); err == nil {
+		schema = strictSchema
+	} else {
+		strict = false
+	}
+
+	return structuredOutput[T]{schema: schema, wrapped: wrapped, strict: strict}, nil
+}
+
+// responseFormat returns the json_schema response format for T.
+func (so structuredOutput[T]) responseFormat() map[string]any {
+	t := structuredOutputType[T]()
+	name := structuredOutputNameRegex.ReplaceAllString(t.Name(), "_")
+	if name == "" || name == "_" {
+		name = "response"
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	return map[string]any{
+		"type": "json_schema",
+		"json_schema": map[string]any{
+			"name":   name,
+			"strict": so.strict,
+			"schema": so.schema,
+		},
+	}
+}
+
+// structuredOutputType returns the type of T without pointers.
+func structuredOutputType[T any]() reflect.Type {
+	t := reflect.TypeFor[T]()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// isStructuredOutputWrapped returns true if T is wrapped in an object with a single
+// "value" property because it's not a struct.
+func isStructuredOutputWrapped[T any]() bool {
+	return structuredOutputType[T]().Kind() != reflect.Struct
+}
+
+// unmarshalStructuredOutput decodes the JSON into T, unwrapping it if needed. A wrapper
+// without the value property decodes to the zero value.
+func unmarshalStructuredOutput[T any](data []byte, wrapped bool) (T, error) {
+	var value T
+	if !wrapped {
+		err := json.Unmarshal(data, &value)
+		return value, err
+	}
+
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return value, err
+	}
+	if raw, ok := wrapper[structuredOutputWrapperKey]; ok {
+		err := json.Unmarshal(raw, &value)
+		return value, err
+	}
+	return value, nil
+}
+
+// decode validates the content against the schema and decodes it into T.
+func (so structuredOutput[T]) decode(content string) (T, error) {
+	var zero T
+
+	data := strings.TrimSpace(content)
+	if !json.Valid([]byte(data)) {
+		// Some models wrap the JSON in markdown code fences or add some text
+		data = jsonrepair.Extract(data)
+	}
+
+	if err := jsonschema.ValidateJSON(so.schema, []byte(data)); err != nil {
+		return zero, &StructuredOutputError{Content: content, Err: err}
+	}
+
+	value, err := unmarshalStructuredOutput[T]([]byte(data), so.wrapped)
+	if err != nil {
+		return zero, &StructuredOutputError{Content: content, Err: err}
+	}
+
+	if validator, ok := any(value).(toolArgumentsValidator); ok {
+		if err := validator.Validate(); err != nil {
+			return zero, &StructuredOutputError{Content: content, Err: err}
+		}
+	} else if validator, ok := any(&value).(toolArgumentsValidator); ok {
+		if err := validator.Validate(); err != nil {
+			return zero, &StructuredOutputError{Content: content, Err: err}
+		}
+	}
+
+	return value, nil
+}
+
+// decodeResponse decodes the content of the first choice of the response into T.
+func (so structuredOutput[T]) decodeResponse(response ChatCompletionResponse) (T, error) {
+	var zero T
+	if !response.HasChoices() {
+		return zero, &StructuredOutputError{Content: "", Err: errors.New("the response has no choices")}
+	}
+
+	message := response.Choices[0].Message
+	if message.HasRefusal() {
+		return zero, &StructuredOutputError{
+			Content: message.Content,
+			Err:     fmt.Errorf("the model refused to answer: %s", message.Refusal),
+		}
+	}
+
+	return so.decode(message.Content)
+}
+
+// ExecuteInto executes the chat completion request and decodes the response into a
+// value of type T.
+//
+// The JSON Schema of the response is derived from T using the same rules as
+// ToolFromStruct and sent with the json_schema response format in strict mode, the
+// optional fields can be null. Types that are not structs, like slices, are wrapped in
+// an object because the root of the schema must be an object.
+//
+// The request is made on a clone of the builder, so the response format is only used
+// for this request. The response is added to the conversation like with Execute, but
+// only if it's valid: when an error is returned the conversation is not modified.
+// Use WithStructuredOutputRetries to ask the model to fix invalid responses.
+//
+// The content is validated against the schema before decoding it, and if T (or *T) has
+// a `Validate() error` method, it's called. Returns a *StructuredOutputError with the
+// raw content if the content can't be decoded or is not valid.
+//
+// Example:
+//
+//	type Capital struct {
+//		Country string `json:"country"`
+//		Capital string `json:"capital"`
+//	}
+//
+//	capital, _, err := openroutergo.ExecuteInto[Capital](
+//		client.NewChatCompletion().WithModel("...").WithUserMessage("What is the capital of France?"),
+//	)
+func ExecuteInto[T any](builder *chatCompletionBuilder) (T, ChatCompletionResponse, error) {
+	var zero T
+
+	output, err := newStructuredOutput[T]()
+	if err != nil {
+		return zero, ChatCompletionResponse{}, err
+	}
+
+	attempt := builder.Clone().WithResponseFormat(output.responseFormat())
+	_, response, err := attempt.execute(builder.ctx)
+	if err != nil {
+		return zero, response, err
+	}
+
+	value, err := output.decodeResponse(response)
+	for retry := 0; err != nil && retry < builder.structuredRetries; retry++ {
+		var outputErr *StructuredOutputError
+		if !errors.As(err, &outputErr) || !response.HasChoices() || response.Choices[0].Message.HasRefusal() {
+			break
+		}
+
+		// The invalid response is already in the attempt conversation, so we only need
+		// to explain the error to the model
+		attempt.WithUserMessage(fmt.Sprintf(structuredOutputRetryMessage, outputErr.Err))
+		_, response, err = attempt.execute(builder.ctx)
+		if err != nil {
+			return zero, response, err
+		}
+		value, err = output.decodeResponse(response)
+	}
+
+	if err != nil {
+		return zero, response, err
+	}
+
+	// Only the valid response is added to the conversation, the failed attempts and
+	// the error explanations are discarded
+	for _, choice := range response.Choices {
+		builder.WithMessage(choice.Message)
+	}
+
+	return value, response, nil
+}
+
+// WithStructuredOutputRetries sets how many times ExecuteInto asks the model to fix its
+// response when it can't be decoded or doesn't match the schema, the default is 0.
+//
+// On each retry, the invalid response and a user message explaining the error are sent
+// to the model. The retries are made on a clone of the builder, so only the last
+// response is added to the conversation.
+func (b *chatCompletionBuilder) WithStructuredOutputRetries(retries int) *chatCompletionBuilder {
+	b.structuredRetries = max(retries, 0)
+	return b
+}
@@ -0,0 +1,294 @@
+package openroutergo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+// structuredOutputServer is a fake OpenRouter server that answers the chat completion
+// requests with the given bodies, in order.
+type structuredOutputServer struct {
+	mu       sync.Mutex
+	client   *Client
+	bodies   []string
+	requests []map[string]any
+}
+
+func newStructuredOutputServer(t *testing.T, bodies ...string) *structuredOutputServer {
+	t.Helper()
+	s := &structuredOutputServer{mu: sync.Mutex{}, client: nil, bodies: bodies, requests: []map[string]any{}}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		body := map[string]any{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		s.requests = append(s.requests, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(s.bodies) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":{"code":500,"message":"no more responses"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(s.bodies[0]))
+		s.bodies = s.bodies[1:]
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient().WithBaseURL(server.URL).WithAPIKey("key").Create()
+	assert.NoError(t, err)
+	s.client = client
+	return s
+}
+
+// request returns the body of the i-th request.
+func (s *structuredOutputServer) request(i int) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[i]
+}
+
+// requestCount returns the number of requests received.
+func (s *structuredOutputServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+// structuredOutputBody returns a chat completion response with an assistant message.
+func structuredOutputBody(content string) string {
+	encoded, _ := json.Marshal(content)
+	return `{"id":"gen","model":"openai/gpt-4o","choices":[{"finish_reason":"stop",` +
+		`"message":{"role":"assistant","content":` + string(encoded) + `}}]}`
+}
+
+// structuredOutputRoles returns the roles of the messages separated by commas.
+func structuredOutputRoles(messages []ChatCompletionMessage) string {
+	roles := []string{}
+	for _, message := range messages {
+		roles = append(roles, message.Role.Value)
+	}
+	return strings.Join(roles, ",")
+}
+
+type structuredOutputCapital struct {
+	Country string  `json:"country" jsonschema_description:"The name of the country"`
+	Capital string  `json:"capital"`
+	Note    *string `json:"note"`
+}
+
+// Validate implements the `Validate() error` method called by ExecuteInto.
+func (c structuredOutputCapital) Validate() error {
+	if c.Capital == "" {
+		return errors.New("the capital is empty")
+	}
+	return nil
+}
+
+func TestExecuteInto(t *testing.T) {
+	server := newStructuredOutputServer(
+		t, structuredOutputBody("```json\n{\"country\":\"France\",\"capital\":\"Paris\",\"note\":null}\n```"),
+	)
+	completion := server.client.NewChatCompletion().WithModel("openai/gpt-4o").WithUserMessage("France?")
+
+	value, resp, err := ExecuteInto[structuredOutputCapital](completion)
+	assert.NoError(t, err)
+	assert.Equal(t, "France", value.Country)
+	assert.Equal(t, "Paris", value.Capital)
+	assert.True(t, value.Note == nil)
+	assert.Equal(t, "gen", resp.ID)
+
+	// The response is added to the conversation, but not the response format
+	assert.Equal(t, "user,assistant", structuredOutputRoles(completion.messages))
+	assert.False(t, completion.responseFormat.IsSet)
+
+	responseFormat := server.request(0)["response_format"].(map[string]any)
+	jsonSchema := responseFormat["json_schema"].(map[string]any)
+	assert.Equal(t, any("json_schema"), responseFormat["type"])
+	assert.Equal(t, any("structuredOutputCapital"), jsonSchema["name"])
+	assert.Equal(t, any(true), jsonSchema["strict"])
+
+	schema, err := json.Marshal(jsonSchema["schema"])
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		`{"additionalProperties":false,"properties":{"capital":{"type":"string"},`+
+			`"country":{"description":"The name of the country","type":"string"},"note":{"type":["string","null"]}},`+
+			`"required":["capital","country","note"],"type":"object"}`,
+		string(schema),
+	)
+}
+
+func TestExecuteIntoWrapped(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		strict  bool
+		execute func(completion *chatCompletionBuilder) (any, error)
+		value   any
+	}{
+		{
+			"Slice",
+			`{"value":["Paris","Berlin"]}`,
+			true,
+			func(completion *chatCompletionBuilder) (any, error) {
+				value, _, err := ExecuteInto[[]string](completion)
+				return strings.Join(value, ","), err
+			},
+			"Paris,Berlin",
+		},
+		{
+			// Maps can't be represented in strict mode
+			"Map",
+			`{"value":{"France":"Paris"}}`,
+			false,
+			func(completion *chatCompletionBuilder) (any, error) {
+				value, _, err := ExecuteInto[map[string]string](completion)
+				return value["France"], err
+			},
+			"Paris",
+		},
+		{
+			"Pointer",
+			`{"country":"France","capital":"Paris","note":"Also the largest city"}`,
+			true,
+			func(completion *chatCompletionBuilder) (any, error) {
+				value, _, err := ExecuteInto[*structuredOutputCapital](completion)
+				if err != nil {
+					return nil, err
+				}
+				return *value.Note, nil
+			},
+			"Also the largest city",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStructuredOutputServer(t, structuredOutputBody(tt.content))
+			value, err := tt.execute(server.client.NewChatCompletion().WithUserMessage("Capitals?"))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.value, value)
+
+			jsonSchema := server.request(0)["response_format"].(map[string]any)["json_schema"].(map[string]any)
+			assert.Equal(t, any(tt.strict), jsonSchema["strict"])
+		})
+	}
+}
+
+func TestExecuteIntoInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"NotJSON", structuredOutputBody("Paris"), "invalid"},
+		{"SchemaMismatch", structuredOutputBody(`{"country":"France","capital":1,"note":null}`), "capital"},
+		{"MissingRequired", structuredOutputBody(`{"country":"France","capital":"Paris"}`), "note"},
+		{"ValidateMethod", structuredOutputBody(`{"country":"France","capital":"","note":null}`), "the capital is empty"},
+		{
+			"Refusal",
+			`{"id":"gen","choices":[{"message":{"role":"assistant","content":"","refusal":"I can't"}}]}`,
+			"the model refused to answer: I can't",
+		},
+		{"NoChoices", `{"id":"gen","choices":[]}`, "the response has no choices"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStructuredOutputServer(t, tt.body)
+			_, _, err := ExecuteInto[structuredOutputCapital](server.client.NewChatCompletion().WithUserMessage("France?"))
+
+			var outputErr *StructuredOutputError
+			assert.True(t, errors.As(err, &outputErr))
+			assert.True(t, errors.Is(err, ErrInvalidStructuredOutput))
+			assert.True(t, strings.Contains(err.Error(), tt.message))
+		})
+	}
+}
+
+func TestExecuteIntoRetries(t *testing.T) {
+	valid := structuredOutputBody(`{"country":"France","capital":"Paris","note":null}`)
+	invalid := structuredOutputBody(`{"country":"France"}`)
+	refusal := `{"id":"gen","choices":[{"message":{"role":"assistant","content":"","refusal":"I can't"}}]}`
+
+	tests := []struct {
+		name     string
+		retries  int
+		bodies   []string
+		requests int
+		err      bool
+		output   bool
+	}{
+		{"WithoutRetries", 0, []string{invalid, valid}, 1, true, true},
+		{"Fixed", 2, []string{invalid, invalid, valid}, 3, false, false},
+		{"Exhausted", 1, []string{invalid, invalid, valid}, 2, true, true},
+		// A refusal is not retried
+		{"Refusal", 2, []string{refusal, valid}, 1, true, true},
+		// The request errors are returned without retrying, the server has no more responses
+		{"RequestError", 2, []string{invalid}, 2, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStructuredOutputServer(t, tt.bodies...)
+			completion := server.client.NewChatCompletion().
+				WithStructuredOutputRetries(tt.retries).
+				WithUserMessage("France?")
+
+			value, _, err := ExecuteInto[structuredOutputCapital](completion)
+			assert.Equal(t, tt.requests, server.requestCount())
+			assert.Equal(t, tt.err, err != nil)
+			assert.Equal(t, tt.output, errors.Is(err, ErrInvalidStructuredOutput))
+			if !tt.err {
+				assert.Equal(t, "Paris", value.Capital)
+			}
+
+			// Each retry sends the invalid response and explains the error
+			for i := 1; i < tt.requests; i++ {
+				messages := server.request(i)["messages"].([]any)
+				assert.Equal(t, 1+2*i, len(messages))
+				last := messages[len(messages)-1].(map[string]any)
+				assert.Equal(t, any("user"), last["role"])
+				assert.True(t, strings.HasPrefix(last["content"].(string), "Your previous response is not valid: "))
+			}
+		})
+	}
+}
+
+func TestExecuteIntoConversation(t *testing.T) {
+	valid := structuredOutputBody(`{"country":"France","capital":"Paris","note":null}`)
+	invalid := structuredOutputBody(`{"country":"France"}`)
+
+	tests := []struct {
+		name    string
+		retries int
+		bodies  []string
+		roles   string
+	}{
+		// Only the last response is added to the conversation
+		{"Valid", 1, []string{invalid, valid}, "user,assistant"},
+		// The invalid responses are not added to the conversation
+		{"Invalid", 1, []string{invalid, invalid}, "user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStructuredOutputServer(t, tt.bodies...)
+			completion := server.client.NewChatCompletion().WithStructuredOutputRetries(tt.retries).WithUserMessage("France?")
+
+			_, _, err := ExecuteInto[structuredOutputCapital](completion)
+			assert.Equal(t, tt.roles == "user", err != nil)
+			assert.Equal(t, tt.roles, structuredOutputRoles(completion.messages))
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package openroutergo
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"github.com/zachczx/openroutergo/tokenizer"
+)
+
+const (
+	// messageTokensOverhead is the number of tokens used by the chat format in each
+	// message, for the role and the delimiters.
+	messageTokensOverhead = 3
+	// nameTokensOverhead is the number of tokens added when the message has a name.
+	nameTokensOverhead = 1
+	// replyTokensOverhead is the number of tokens used to start the reply of the model.
+	replyTokensOverhead = 3
+	// toolsTokensOverhead is the number of tokens used to introduce the tool definitions.
+	toolsTokensOverhead = 12
+	// imageTokens is the estimated number of tokens of an image, the cost of a 1024x1024
+	// image in high detail for most vision models.
+	imageTokens = 765
+)
+
+// dataImageRegex matches the images embedded in the content as data URLs, they are
+// counted as imageTokens instead of as text.
+var dataImageRegex = regexp.MustCompile(`data:image/[a-zA-Z0-9.+-]+;base64,[A-Za-z0-9+/=]+`)
+
+// EstimatePromptTokens estimates the number of prompt tokens of the request, without
+// sending it, for example, for budgeting or rate limiting.
+//
+// It counts the messages of the conversation (including the tokens used by the chat
+// format for each message, the tool calls and the images embedded as data URLs) and
+// the definitions of the tools. The messages are counted before any truncation, see
+// WithTruncation.
+//
+// The number is a heuristic estimation for the family of the model, see
+// tokenizer.ForModel. The exact encoding is used for the OpenAI models when it's
+// registered in the tokenizer package, for example, by importing the tokenizer/tiktoken
+// package.
+func (b *chatCompletionBuilder) EstimatePromptTokens() int {
+	tools, err := b.prepareTools(b.requestTools())
+	if err != nil {
+		tools = b.requestTools()
+	}
+
+	tk := b.tokenizer()
+	return estimateMessagesTokens(tk, b.messages) + estimateToolsTokens(tk, tools) + replyTokensOverhead
+}
+
+// tokenizer returns the tokenizer of the model of the builder.
+func (b *chatCompletionBuilder) tokenizer() tokenizer.Tokenizer {
+	return tokenizer.ForModel(b.model.Value)
+}
+
+// estimateMessageTokens estimates the number of tokens of a message, including the
+// tokens used by the chat format.
+func estimateMessageTokens(tk tokenizer.Tokenizer, message ChatCompletionMessage) int {
+	tokens := messageTokensOverhead + estimateContentTokens(tk, message.Content)
+	if message.Name != "" {
+		tokens += nameTokensOverhead + tk.Count(message.Name)
+	}
+	if message.ToolCallID != "" {
+		tokens += tk.Count(message.ToolCallID)
+	}
+	for _, toolCall := range message.ToolCalls {
+		tokens += messageTokensOverhead + tk.Count(toolCall.Function.Name) + tk.Count(toolCall.Function.Arguments)
+	}
+	return tokens
+}
+
+// estimateContentTokens estimates the number of tokens of the content of a message,
+// counting each embedded image as imageTokens.
+func estimateContentTokens(tk tokenizer.Tokenizer, content string) int {
+	images := dataImageRegex.FindAllStringIndex(content, -1)
+	if len(images) == 0 {
+		return tk.Count(content)
+	}
+
+	tokens, start := 0, 0
+	for _, image := range images {
+		tokens += tk.Count(content[start:image[0]]) + imageTokens
+		start = image[1]
+	}
+	return tokens + tk.Count(content[start:])
+}
+
+// estimateMessagesTokens estimates the number of tokens of the messages.
+func estimateMessagesTokens(tk tokenizer.Tokenizer, messages []ChatCompletionMessage) int {
+	tokens := 0
+	for _, message := range messages {
+		tokens += estimateMessageTokens(tk, message)
+	}
+	return tokens
+}
+
+// estimateToolsTokens estimates the number of tokens used by the tool definitions.
+func estimateToolsTokens(tk tokenizer.Tokenizer, tools []chatCompletionToolFunction) int {
+	if len(tools) == 0 {
+		return 0
+	}
+	encoded, err := json.Marshal(tools)
+	if err != nil {
+		return 0
+	}
+	return toolsTokensOverhead + tk.Count(string(encoded))
+}
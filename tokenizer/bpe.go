@@ -0,0 +1,116 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidVocabulary is returned when a vocabulary file can't be parsed.
+var ErrInvalidVocabulary = errors.New("invalid vocabulary")
+
+// LoadTiktoken parses a vocabulary in the tiktoken format, one token per line with the
+// base64 encoded bytes of the token and its rank separated by a space.
+func LoadTiktoken(r io.Reader) (map[string]int, error) {
+	ranks := map[string]int{}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		encoded, rankText, found := strings.Cut(text, " ")
+		if !found {
+			return nil, fmt.Errorf("%w: line %d: missing rank", ErrInvalidVocabulary, line)
+		}
+		token, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidVocabulary, line, err)
+		}
+		rank, err := strconv.Atoi(rankText)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidVocabulary, line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read vocabulary: %w", err)
+	}
+
+	return ranks, nil
+}
+
+// BPE is a byte pair encoding tokenizer, like the ones used by OpenAI models.
+type BPE struct {
+	ranks   map[string]int
+	pattern *Pattern
+}
+
+// NewBPE creates a byte pair encoding tokenizer with the given ranks (see LoadTiktoken)
+// and pre-tokenization pattern (for example, CL100KPattern).
+//
+// The ranks must include every single byte, otherwise the bytes without rank are
+// encoded as the rank -1.
+func NewBPE(ranks map[string]int, pattern *Pattern) *BPE {
+	return &BPE{ranks: ranks, pattern: pattern}
+}
+
+// Encode returns the tokens of the text.
+func (b *BPE) Encode(text string) []int {
+	tokens := []int{}
+	for _, piece := range b.pattern.Split(text) {
+		tokens = append(tokens, b.encodePiece(piece)...)
+	}
+	return tokens
+}
+
+// Count implements the Tokenizer interface.
+func (b *BPE) Count(text string) int {
+	return len(b.Encode(text))
+}
+
+// encodePiece encodes a piece of the text by merging the pair of adjacent parts with
+// the lowest rank until no pair can be merged.
+func (b *BPE) encodePiece(piece string) []int {
+	if rank, ok := b.ranks[piece]; ok {
+		return []int{rank}
+	}
+
+	// The boundaries of the parts, the part i is piece[bounds[i]:bounds[i+1]]
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+
+	for len(bounds) > 2 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := b.ranks[piece[bounds[i]:bounds[i+2]]]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best == -1 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+
+	tokens := make([]int, 0, len(bounds)-1)
+	for i := 0; i+1 < len(bounds); i++ {
+		rank, ok := b.ranks[piece[bounds[i]:bounds[i+1]]]
+		if !ok {
+			rank = -1
+		}
+		tokens = append(tokens, rank)
+	}
+	return tokens
+}
@@ -0,0 +1,44 @@
+package tokenizer
+
+import (
+	"math"
+	"unicode"
+)
+
+// Heuristic estimates the number of tokens without a vocabulary.
+//
+// The text is split in pieces like the cl100k_base encoding does (words with their
+// leading space, groups of up to 3 digits, punctuation and spaces) and each piece
+// counts as one token for every CharsPerToken characters. The characters of scripts
+// without spaces between words, like Chinese or Japanese, count as one token each.
+type Heuristic struct {
+	// The average number of characters of a token in a word.
+	CharsPerToken float64
+}
+
+// Count implements the Tokenizer interface.
+func (h Heuristic) Count(text string) int {
+	charsPerToken := h.CharsPerToken
+	if charsPerToken <= 0 {
+		charsPerToken = defaultHeuristic.CharsPerToken
+	}
+
+	tokens := 0
+	for _, piece := range CL100KPattern.Split(text) {
+		ideographs, chars := 0, 0
+		for _, r := range piece {
+			if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul, unicode.Thai) {
+				ideographs++
+			} else {
+				chars++
+			}
+		}
+
+		tokens += ideographs
+		// A single space or punctuation before the ideographs is merged with them
+		if chars > 1 || (chars == 1 && ideographs == 0) {
+			tokens += max(1, int(math.Round(float64(chars)/charsPerToken)))
+		}
+	}
+	return tokens
+}
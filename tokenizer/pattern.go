@@ -0,0 +1,102 @@
+package tokenizer
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// whitespace is the Unicode White_Space property as a character class body, the \s
+// class of the regexp package only matches ASCII spaces.
+const whitespace = `\t\n\v\f\r\x{85}\p{Z}`
+
+// contractions matches the English contractions that are kept with the previous word.
+const contractions = `(?i:'s|'t|'re|'ve|'m|'ll|'d)`
+
+// Pattern splits a text in the pieces that are encoded separately by a BPE tokenizer.
+type Pattern struct {
+	re *regexp.Regexp
+	// True if the last space of a run of spaces followed by a non-space character is
+	// left for the next piece, the \s+(?!\S) alternative of the tiktoken patterns.
+	spaceLookahead bool
+}
+
+// The pre-tokenization patterns of the tiktoken encodings. The negative lookahead they
+// use is not supported by the regexp package, so it's implemented by Split.
+var (
+	// CL100KPattern splits a text in the pieces encoded by cl100k_base.
+	CL100KPattern = &Pattern{
+		re: regexp.MustCompile(
+			`^(?:` + contractions +
+				`|[^\r\n\p{L}\p{N}]?\p{L}+` +
+				`|\p{N}{1,3}` +
+				`| ?[^` + whitespace + `\p{L}\p{N}]+[\r\n]*` +
+				`|[` + whitespace + `]*[\r\n]+` +
+				`|[` + whitespace + `]+)`,
+		),
+		spaceLookahead: true,
+	}
+	// O200KPattern splits a text in the pieces encoded by o200k_base.
+	O200KPattern = &Pattern{
+		re: regexp.MustCompile(
+			`^(?:[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+` + contractions + `?` +
+				`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*` + contractions + `?` +
+				`|\p{N}{1,3}` +
+				`| ?[^` + whitespace + `\p{L}\p{N}]+[\r\n/]*` +
+				`|[` + whitespace + `]*[\r\n]+` +
+				`|[` + whitespace + `]+)`,
+		),
+		spaceLookahead: true,
+	}
+)
+
+// NewPattern compiles a pre-tokenization pattern, it must match at least one character
+// at every position of the texts.
+func NewPattern(expr string) (*Pattern, error) {
+	re, err := regexp.Compile(`^(?:` + expr + `)`)
+	if err != nil {
+		return nil, err
+	}
+	return &Pattern{re: re, spaceLookahead: false}, nil
+}
+
+// Split returns the pieces of the text.
+func (p *Pattern) Split(text string) []string {
+	pieces := []string{}
+	for len(text) > 0 {
+		loc := p.re.FindStringIndex(text)
+		end := 1
+		if loc != nil && loc[1] > 0 {
+			end = loc[1]
+		} else if _, size := utf8.DecodeRuneInString(text); size > 1 {
+			end = size
+		}
+
+		piece := text[:end]
+		if p.spaceLookahead && end < len(text) && isSpaceRun(piece) {
+			// \s+(?!\S) backtracks one character so the next word keeps its space
+			if _, size := utf8.DecodeLastRuneInString(piece); size < len(piece) {
+				piece = piece[:len(piece)-size]
+			}
+		}
+
+		pieces = append(pieces, piece)
+		text = text[len(piece):]
+	}
+	return pieces
+}
+
+// isSpaceRun returns true if the piece only has spaces and no line breaks, so it was
+// matched by the last alternative of the tiktoken patterns.
+func isSpaceRun(piece string) bool {
+	if strings.ContainsAny(piece, "\r\n") {
+		return false
+	}
+	for _, r := range piece {
+		if !unicode.Is(unicode.White_Space, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+// Package tiktoken embeds the cl100k_base and o200k_base encodings of the OpenAI models
+// and registers them in the tokenizer package, so the OpenAI models are counted exactly
+// instead of with a heuristic.
+//
+// The vocabularies add about 2.4MB to the binary, so they are only included when the
+// package is imported:
+//
+//	import _ "github.com/zachczx/openroutergo/tokenizer/tiktoken"
+package tiktoken
+
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"fmt"
+	"sync"
+
+	"github.com/zachczx/openroutergo/tokenizer"
+)
+
+// The vocabularies of the tiktoken encodings published by OpenAI (MIT License),
+// compressed with gzip. They are parsed the first time they are used.
+var (
+	//go:embed encodings/cl100k_base.tiktoken.gz
+	cl100kBaseVocabulary []byte
+	//go:embed encodings/o200k_base.tiktoken.gz
+	o200kBaseVocabulary []byte
+)
+
+// embeddedEncoding is an encoding embedded in the package, loaded lazily.
+type embeddedEncoding struct {
+	once       sync.Once
+	vocabulary []byte
+	pattern    *tokenizer.Pattern
+	bpe        *tokenizer.BPE
+}
+
+// load parses the vocabulary of the encoding, it panics if the embedded file is invalid
+// because that can only happen if the package is built incorrectly.
+func (e *embeddedEncoding) load() *tokenizer.BPE {
+	e.once.Do(func() {
+		reader, err := gzip.NewReader(bytes.NewReader(e.vocabulary))
+		if err != nil {
+			panic(fmt.Sprintf("tiktoken: invalid embedded vocabulary: %v", err))
+		}
+		ranks, err := tokenizer.LoadTiktoken(reader)
+		if err != nil {
+			panic(fmt.Sprintf("tiktoken: invalid embedded vocabulary: %v", err))
+		}
+		e.bpe = tokenizer.NewBPE(ranks, e.pattern)
+	})
+	return e.bpe
+}
+
+// Count implements the tokenizer.Tokenizer interface, the vocabulary is parsed the
+// first time it's called.
+func (e *embeddedEncoding) Count(text string) int {
+	return e.load().Count(text)
+}
+
+var (
+	cl100kBase = &embeddedEncoding{vocabulary: cl100kBaseVocabulary, pattern: tokenizer.CL100KPattern}
+	o200kBase  = &embeddedEncoding{vocabulary: o200kBaseVocabulary, pattern: tokenizer.O200KPattern}
+)
+
+func init() {
+	tokenizer.Register(tokenizer.CL100KBase, cl100kBase)
+	tokenizer.Register(tokenizer.O200KBase, o200kBase)
+}
+
+// CL100K returns the cl100k_base encoding, used by GPT-4 and GPT-3.5 models.
+func CL100K() *tokenizer.BPE {
+	return cl100kBase.load()
+}
+
+// O200K returns the o200k_base encoding, used by GPT-4o and newer OpenAI models.
+func O200K() *tokenizer.BPE {
+	return o200kBase.load()
+}
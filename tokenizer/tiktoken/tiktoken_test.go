@@ -0,0 +1,47 @@
+package tiktoken
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+	"github.com/zachczx/openroutergo/tokenizer"
+)
+
+func TestEncodings(t *testing.T) {
+	tests := []struct {
+		text   string
+		cl100k string
+		o200k  string
+	}{
+		{"hello world", "[15339 1917]", "[24912 2375]"},
+		{"tiktoken is great!", "[83 1609 5963 374 2294 0]", "[83 8251 2488 382 2212 0]"},
+		{"antidisestablishmentarianism", "[519 85342 34500 479 8997 2191]", "[493 129901 376 160388 21203 2367]"},
+		{"2 + 2 = 4", "[17 489 220 17 284 220 19]", "[17 659 220 17 314 220 19]"},
+		{
+			"お誕生日おめでとう",
+			"[33334 45918 243 21990 9080 33334 62004 16556 78699]",
+			"[8930 9697 243 128225 8930 17693 4344 48669]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.cl100k, fmt.Sprint(CL100K().Encode(tt.text)))
+			assert.Equal(t, tt.o200k, fmt.Sprint(O200K().Encode(tt.text)))
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	for _, encoding := range []string{tokenizer.CL100KBase, tokenizer.O200KBase} {
+		_, ok := tokenizer.Lookup(encoding)
+		assert.True(t, ok)
+	}
+
+	// The registered encodings are used for the OpenAI models
+	assert.Equal(t, 2, tokenizer.ForModel("openai/gpt-4o").Count("hello world"))
+	assert.Equal(t, 6, tokenizer.ForModel("openai/gpt-4-turbo").Count("tiktoken is great!"))
+	assert.Equal(t, 6, tokenizer.ForModel("openai/gpt-4o").Count("tiktoken is great!"))
+	assert.True(t, tokenizer.ForModel("openai/gpt-4o") == tokenizer.Tokenizer(o200kBase))
+}
@@ -0,0 +1,112 @@
+// Package tokenizer counts the tokens of a text offline, to estimate the prompt tokens
+// of a request before sending it, for example, for budgeting or truncation.
+//
+// By default the tokens are estimated with a heuristic calibrated for the model family,
+// see ForModel. The exact encodings are used when they are registered with Register:
+// import the tiktoken package to register the cl100k_base and o200k_base encodings of
+// the OpenAI models (they are not embedded by default because of their size), or load
+// other encodings from their tiktoken vocabulary files:
+//
+//	import _ "github.com/zachczx/openroutergo/tokenizer/tiktoken"
+//
+//	file, err := os.Open("p50k_base.tiktoken")
+//	ranks, err := tokenizer.LoadTiktoken(file)
+//	tokenizer.Register("p50k_base", tokenizer.NewBPE(ranks, tokenizer.CL100KPattern))
+package tokenizer
+
+import (
+	"strings"
+	"sync"
+)
+
+const (
+	// CL100KBase is the name of the encoding of GPT-4 and GPT-3.5 models.
+	CL100KBase = "cl100k_base"
+	// O200KBase is the name of the encoding of GPT-4o and newer OpenAI models.
+	O200KBase = "o200k_base"
+)
+
+// Tokenizer counts the tokens of a text.
+type Tokenizer interface {
+	// Count returns the number of tokens of the text.
+	Count(text string) int
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Tokenizer{}
+)
+
+// Register makes the tokenizer available with the given encoding name, it replaces any
+// tokenizer registered with the same name. It's safe for concurrent use.
+func Register(name string, tokenizer Tokenizer) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = tokenizer
+}
+
+// Lookup returns the tokenizer registered with the given encoding name.
+func Lookup(name string) (Tokenizer, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	tokenizer, ok := registry[name]
+	return tokenizer, ok
+}
+
+// o200kModels are the prefixes of the OpenAI models that use the o200k_base encoding.
+var o200kModels = []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4", "chatgpt-4o", "gpt-oss"}
+
+// familyHeuristics are the heuristics for the model authors of OpenRouter model IDs.
+var familyHeuristics = map[string]Heuristic{
+	"openai":     {CharsPerToken: 6},
+	"meta-llama": {CharsPerToken: 6},
+	"qwen":       {CharsPerToken: 6},
+	"deepseek":   {CharsPerToken: 6},
+	"anthropic":  {CharsPerToken: 5.5},
+	"google":     {CharsPerToken: 5.5},
+	"mistralai":  {CharsPerToken: 5},
+}
+
+// defaultHeuristic is the heuristic used for the unknown model families.
+var defaultHeuristic = Heuristic{CharsPerToken: 5}
+
+// Encoding returns the name of the encoding used by the model, for example, "gpt-4o"
+// or "openai/gpt-4o" use o200k_base, or an empty string if it's unknown.
+func Encoding(model string) string {
+	author, name, found := strings.Cut(model, "/")
+	if !found {
+		author, name = "openai", model
+	}
+	if author != "openai" {
+		return ""
+	}
+
+	for _, prefix := range o200kModels {
+		if strings.HasPrefix(name, prefix) {
+			return O200KBase
+		}
+	}
+	if strings.HasPrefix(name, "gpt-4") || strings.HasPrefix(name, "gpt-3.5") {
+		return CL100KBase
+	}
+	return ""
+}
+
+// ForModel returns the tokenizer for the given model, for example, "openai/gpt-4o".
+//
+// It returns the tokenizer registered for the encoding of the model (see Encoding and
+// Lookup) or, if the encoding is unknown or not registered, a heuristic calibrated for
+// the author of the model.
+func ForModel(model string) Tokenizer {
+	if encoding := Encoding(model); encoding != "" {
+		if tokenizer, ok := Lookup(encoding); ok {
+			return tokenizer
+		}
+	}
+
+	author, _, _ := strings.Cut(model, "/")
+	if heuristic, ok := familyHeuristics[author]; ok {
+		return heuristic
+	}
+	return defaultHeuristic
+}
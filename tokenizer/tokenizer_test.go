@@ -0,0 +1,103 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+// testVocabulary returns a tiktoken vocabulary with all the bytes and some merges.
+func testVocabulary() string {
+	lines := []string{}
+	for i := 0; i < 256; i++ {
+		lines = append(lines, fmt.Sprintf("%s %d", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i))
+	}
+	for i, merge := range []string{"he", "ll", "hell", "hello", " w", "or", " wor", "ld", " world"} {
+		lines = append(lines, fmt.Sprintf("%s %d", base64.StdEncoding.EncodeToString([]byte(merge)), 256+i))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestBPE(t *testing.T) {
+	ranks, err := LoadTiktoken(strings.NewReader(testVocabulary()))
+	assert.NoError(t, err)
+	assert.Equal(t, 265, len(ranks))
+
+	bpe := NewBPE(ranks, CL100KPattern)
+	tokens := bpe.Encode("hello world!")
+	assert.Equal(t, "[259 264 33]", fmt.Sprint(tokens))
+	assert.Equal(t, 3, bpe.Count("hello world!"))
+
+	// "hel" can't be merged into a single token
+	assert.Equal(t, "[256 108]", fmt.Sprint(bpe.Encode("hel")))
+}
+
+func TestLoadTiktokenInvalid(t *testing.T) {
+	for _, input := range []string{"aGVsbG8=", "!!! 1", "aGVsbG8= one"} {
+		_, err := LoadTiktoken(strings.NewReader(input))
+		assert.True(t, errors.Is(err, ErrInvalidVocabulary))
+	}
+}
+
+func TestHeuristic(t *testing.T) {
+	h := Heuristic{CharsPerToken: 6}
+	assert.Equal(t, 0, h.Count(""))
+	assert.Equal(t, 10, h.Count("The quick brown fox jumps over the lazy dog."))
+	assert.Equal(t, 2, h.Count("123456"))
+	assert.Equal(t, 4, h.Count("你好世界"))
+	assert.Equal(t, 5, h.Count("hi 你好世界"))
+}
+
+func TestEncoding(t *testing.T) {
+	assert.Equal(t, O200KBase, Encoding("openai/gpt-4o-mini"))
+	assert.Equal(t, O200KBase, Encoding("o3-mini"))
+	assert.Equal(t, CL100KBase, Encoding("openai/gpt-4-turbo"))
+	assert.Equal(t, CL100KBase, Encoding("gpt-3.5-turbo"))
+	assert.Equal(t, "", Encoding("anthropic/claude-3.5-sonnet"))
+}
+
+func TestForModel(t *testing.T) {
+	assert.Equal(t, Tokenizer(Heuristic{CharsPerToken: 5.5}), ForModel("anthropic/claude-3.5-sonnet"))
+	assert.Equal(t, Tokenizer(defaultHeuristic), ForModel("unknown/model"))
+	assert.Equal(t, Tokenizer(Heuristic{CharsPerToken: 6}), ForModel("openai/text-embedding-3-small"))
+	// The heuristic is used until the encoding is registered
+	assert.Equal(t, Tokenizer(Heuristic{CharsPerToken: 6}), ForModel("openai/gpt-4o"))
+	_, ok := Lookup(O200KBase)
+	assert.False(t, ok)
+
+	bpe := NewBPE(map[string]int{}, O200KPattern)
+	Register(O200KBase, bpe)
+	defer func() {
+		registryMu.Lock()
+		delete(registry, O200KBase)
+		registryMu.Unlock()
+	}()
+
+	tokenizer, ok := ForModel("openai/gpt-4o").(*BPE)
+	assert.True(t, ok)
+	assert.True(t, tokenizer == bpe)
+}
+
+func TestPatternSplit(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		// The last space of a run of spaces is kept with the next word
+		{"a   b", `["a" "  " " b"]`},
+		{"a   ", `["a" "   "]`},
+		{"a \n\n  b", `["a" " \n\n" " " " b"]`},
+		{"it's 1234", `["it" "'s" " " "123" "4"]`},
+		{"x\u00a0\u00a0y", `["x" "\u00a0" "\u00a0y"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.expected, fmt.Sprintf("%q", CL100KPattern.Split(tt.text)))
+		})
+	}
+}
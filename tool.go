@@ -0,0 +1,242 @@
+package openroutergo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/zachczx/openroutergo/internal/jsonschema"
+)
+
+// ToolFromStruct creates a [ChatCompletionTool] whose parameters JSON Schema is generated
+// from the fields of the struct T, so the schema never drifts from your Go types.
+//
+// Fields are read following the same rules as encoding/json. A field is required unless
+// it's a pointer or has the omitempty or omitzero json options, pointers also accept
+// null. Nested structs, slices, maps, pointers and time.Time are supported.
+//
+// Use the jsonschema tag to add constraints to the fields with comma separated options:
+// description, enum (values separated by |, applied to the items of a slice), minimum,
+// maximum, minLength, maxLength, minItems, maxItems, pattern, format, required and
+// optional. Use \, to write a comma inside a value, or the jsonschema_description tag
+// for long descriptions.
+//
+// Example:
+//
+//	type WeatherArgs struct {
+//		City  string `json:"city" jsonschema:"description=The city to get the weather for"`
+//		Units string `json:"units,omitempty" jsonschema:"enum=celsius|fahrenheit"`
+//	}
+//
+//	tool, err := openroutergo.ToolFromStruct[WeatherArgs]("getWeather", "Get the weather of a city")
+func ToolFromStruct[T any](name string, description string) (ChatCompletionTool, error) {
+	t := reflect.TypeFor[T]()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return ChatCompletionTool{}, fmt.Errorf("%w: %s is not a struct", ErrInvalidToolParameters, t)
+	}
+
+	parameters, err := jsonschema.FromType(t)
+	if err != nil {
+		return ChatCompletionTool{}, fmt.Errorf("%w: %w", ErrInvalidToolParameters, err)
+	}
+
+	return ChatCompletionTool{
+		Name:        name,
+		Description: description,
+		Parameters:  parameters,
+	}, nil
+}
+
+// toolArgumentsValidator can be implemented by the arguments type of a tool created
+// with NewTool to run custom validations after the arguments are decoded.
+type toolArgumentsValidator interface {
+	Validate() error
+}
+
+// ToolHandlerFunc handles a tool call made by the model, it receives the raw arguments
+// generated by the model (a JSON object) and returns the content sent back to the model.
+type ToolHandlerFunc func(ctx context.Context, arguments string) (string, error)
+
+// Tool is a [ChatCompletionTool] definition bundled with the Go function that handles
+// the calls the model makes to it.
+//
+// Create it using the NewTool or NewRawTool functions.
+type Tool struct {
+	// The definition of the tool that is sent to the model.
+	Definition ChatCompletionTool
+	// The maximum time the tool can take when run by the RunWithTools method, it
+	// overrides the timeout set with the WithToolTimeout method. Zero means the builder
+	// timeout is used.
+	Timeout time.Duration
+	// Approve is called by the RunWithTools method before running a tool call, it can
+	// approve, reject or modify the tool call, or defer the decision to pause the
+	// conversation until a human decides. If nil, all the tool calls are approved.
+	Approve ToolApprovalFunc
+	// handler decodes the raw arguments, runs the tool function and encodes the result.
+	handler ToolHandlerFunc
+}
+
+// NewTool creates a [Tool] from a typed Go function.
+//
+// The parameters JSON Schema is generated from the Args struct using the same rules as
+// [ToolFromStruct]. When the model calls the tool, the arguments are decoded into Args
+// and validated before calling fn:
+//
+//   - The arguments are validated against the generated JSON Schema by the Call
+//     method, see ValidateToolArguments.
+//   - If Args (or *Args) has a `Validate() error` method, it's called.
+//
+// The Result returned by fn is sent back to the model as the tool message content. If
+// Result is a string it's used as is, otherwise it's encoded as JSON.
+//
+// Example:
+//
+//	type WeatherArgs struct {
+//		City string `json:"city"`
+//	}
+//
+//	weatherTool, err := openroutergo.NewTool(
+//		"getWeather",
+//		"Get the weather of a city",
+//		func(ctx context.Context, args WeatherArgs) (string, error) {
+//			return "It's sunny in " + args.City, nil
+//		},
+//	)
+func NewTool[Args, Result any](
+	name string,
+	description string,
+	fn func(ctx context.Context, args Args) (Result, error),
+) (Tool, error) {
+	definition, err := ToolFromStruct[Args](name, description)
+	if err != nil {
+		return Tool{}, err
+	}
+
+	handler := func(ctx context.Context, arguments string) (string, error) {
+		if strings.TrimSpace(arguments) == "" {
+			arguments = "{}"
+		}
+
+		var args Args
+		decoder := json.NewDecoder(strings.NewReader(arguments))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&args); err != nil {
+			return "", fmt.Errorf("%w: %w", ErrInvalidToolArguments, err)
+		}
+
+		if validator, ok := any(args).(toolArgumentsValidator); ok {
+			if err := validator.Validate(); err != nil {
+				return "", fmt.Errorf("%w: %w", ErrInvalidToolArguments, err)
+			}
+		} else if validator, ok := any(&args).(toolArgumentsValidator); ok {
+			if err := validator.Validate(); err != nil {
+				return "", fmt.Errorf("%w: %w", ErrInvalidToolArguments, err)
+			}
+		}
+
+		result, err := fn(ctx, args)
+		if err != nil {
+			return "", err
+		}
+
+		if s, ok := any(result).(string); ok {
+			return s, nil
+		}
+
+		content, err := json.Marshal(result)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode tool result: %w", err)
+		}
+		return string(content), nil
+	}
+
+	return NewRawTool(definition, handler), nil
+}
+
+// NewRawTool creates a [Tool] from a definition and a handler that works with the raw
+// arguments generated by the model.
+//
+// Use it when the parameters schema is not generated from a Go type, for example, for
+// tools provided by another system. Prefer NewTool for tools implemented in Go.
+func NewRawTool(definition ChatCompletionTool, handler ToolHandlerFunc) Tool {
+	return Tool{Definition: definition, Timeout: 0, Approve: nil, handler: handler}
+}
+
+// Name returns the name of the tool.
+func (t Tool) Name() string {
+	return t.Definition.Name
+}
+
+// Call validates the arguments of the tool call made by the model against the
+// parameters schema, runs the tool function and returns the content that should be
+// sent back to the model using the WithToolMessage method.
+//
+// Returns ErrInvalidToolArguments if the arguments can't be decoded or are not valid.
+func (t Tool) Call(ctx context.Context, toolCall ChatCompletionMessageToolCall) (string, error) {
+	return t.call(ctx, toolCall, true)
+}
+
+// call runs the tool function, validating the arguments against the parameters schema
+// if validate is true. The RunWithTools method validates them before calling it.
+func (t Tool) call(ctx context.Context, toolCall ChatCompletionMessageToolCall, validate bool) (string, error) {
+	if t.handler == nil {
+		return "", fmt.Errorf("%w: tool %q has no handler", ErrToolNotFound, t.Definition.Name)
+	}
+	if toolCall.Function.Name != t.Definition.Name {
+		return "", fmt.Errorf(
+			"%w: tool call for %q sent to tool %q", ErrToolNotFound, toolCall.Function.Name, t.Definition.Name,
+		)
+	}
+
+	if validate {
+		if err := ValidateToolArguments(t.Definition, toolCall.Function.Arguments); err != nil {
+			return "", err
+		}
+	}
+
+	return t.handler(ctx, toolCall.Function.Arguments)
+}
+
+// ValidateToolArguments checks that the arguments of a tool call made by the model
+// (a JSON object) match the parameters JSON Schema of the tool, so hallucinated or
+// missing parameters are detected before calling your function.
+//
+// It supports a subset of JSON Schema draft 2020-12: type, enum, const, required,
+// properties, additionalProperties, items, prefixItems, minItems, maxItems, uniqueItems,
+// minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf, minLength,
+// maxLength, pattern, minProperties, maxProperties, allOf, anyOf, oneOf, not and local
+// $ref. Unknown keywords are ignored.
+//
+// If the tool is strict, the arguments are validated against the strict version of the
+// parameters sent to the model, where the optional properties can be null.
+//
+// Returns ErrInvalidToolArguments with a description of all the problems found if the
+// arguments are not valid. Empty arguments are treated as an empty object.
+func ValidateToolArguments(tool ChatCompletionTool, arguments string) error {
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+
+	if tool.Parameters == nil {
+		return nil
+	}
+
+	parameters := tool.Parameters
+	if tool.Strict {
+		if strict, err := jsonschema.Strict(parameters); err == nil {
+			parameters = strict
+		}
+	}
+
+	if err := jsonschema.ValidateJSON(parameters, []byte(arguments)); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToolArguments, err)
+	}
+
+	return nil
+}
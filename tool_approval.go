@@ -0,0 +1,170 @@
+package openroutergo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/orsinium-labs/enum"
+)
+
+// ToolApprovalDecision is an enum for the decision made about a tool call before the
+// RunWithTools method runs it.
+type ToolApprovalDecision enum.Member[string]
+
+// MarshalJSON implements the json.Marshaler interface for ToolApprovalDecision.
+func (tad ToolApprovalDecision) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tad.Value)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for ToolApprovalDecision.
+func (tad *ToolApprovalDecision) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	*tad = ToolApprovalDecision{Value: value}
+	return nil
+}
+
+var (
+	// ToolApprovalApprove runs the tool call with the arguments generated by the model.
+	ToolApprovalApprove = ToolApprovalDecision{"approve"}
+	// ToolApprovalReject does not run the tool call, the reason is sent back to the model
+	// as the tool result.
+	ToolApprovalReject = ToolApprovalDecision{"reject"}
+	// ToolApprovalModify runs the tool call with different arguments.
+	ToolApprovalModify = ToolApprovalDecision{"modify"}
+	// ToolApprovalDefer pauses the conversation until a decision is made, see the
+	// ResumeWithTools method.
+	ToolApprovalDefer = ToolApprovalDecision{"defer"}
+)
+
+// ToolApproval is the decision made about a tool call before it runs, create it using
+// the ApproveToolCall, RejectToolCall, ModifyToolCall or DeferToolCall functions.
+type ToolApproval struct {
+	// The decision made about the tool call.
+	Decision ToolApprovalDecision `json:"decision"`
+	// The reason of the rejection, it's sent back to the model as the tool result.
+	Reason string `json:"reason,omitempty,omitzero"`
+	// The arguments (a JSON object) used to run the tool call instead of the ones
+	// generated by the model.
+	Arguments string `json:"arguments,omitempty,omitzero"`
+}
+
+// ToolApprovalFunc is called by the RunWithTools method before running a tool call to
+// decide whether it can run or not.
+type ToolApprovalFunc func(ctx context.Context, toolCall ChatCompletionMessageToolCall) (ToolApproval, error)
+
+// ApproveToolCall approves a tool call so it runs with the arguments generated by the model.
+func ApproveToolCall() ToolApproval {
+	return ToolApproval{Decision: ToolApprovalApprove, Reason: "", Arguments: ""}
+}
+
+// RejectToolCall rejects a tool call, the reason is sent back to the model as the tool
+// result so it knows why the tool didn't run.
+func RejectToolCall(reason string) ToolApproval {
+	return ToolApproval{Decision: ToolApprovalReject, Reason: reason, Arguments: ""}
+}
+
+// ModifyToolCall approves a tool call but runs it with the given arguments (a JSON
+// object) instead of the ones generated by the model.
+//
+// The conversation keeps the arguments generated by the model.
+func ModifyToolCall(arguments string) ToolApproval {
+	return ToolApproval{Decision: ToolApprovalModify, Reason: "", Arguments: arguments}
+}
+
+// DeferToolCall pauses the conversation until a human makes a decision about the tool
+// call, RunWithTools returns ErrToolApprovalPending and the conversation can be resumed
+// later with the ResumeWithTools method.
+func DeferToolCall() ToolApproval {
+	return ToolApproval{Decision: ToolApprovalDefer, Reason: "", Arguments: ""}
+}
+
+// Messages returns a copy of all the messages of the conversation, in the same order
+// they were added.
+//
+// This is useful to persist a conversation, for example, while a tool call is waiting
+// for approval.
+func (b *chatCompletionBuilder) Messages() []ChatCompletionMessage {
+	messages := make([]ChatCompletionMessage, len(b.messages))
+	copy(messages, b.messages)
+	return messages
+}
+
+// approveToolCalls decides which tool calls can run.
+//
+// The decision for each tool call is taken from the approvals map (decisions made by a
+// human while the conversation was paused) or from the Approve function of the tool.
+// Tool calls without a decision are approved.
+//
+// Returns all the decisions made and the tool calls that were deferred.
+func (b *chatCompletionBuilder) approveToolCalls(
+	ctx context.Context,
+	toolCalls []ChatCompletionMessageToolCall,
+	approvals map[string]ToolApproval,
+) (map[string]ToolApproval, []ChatCompletionMessageToolCall, error) {
+	decisions := map[string]ToolApproval{}
+	deferred := []ChatCompletionMessageToolCall{}
+
+	for _, toolCall := range toolCalls {
+		approval, ok := approvals[toolCall.ID]
+		if !ok {
+			approval = ApproveToolCall()
+
+			if b.toolRegistry != nil {
+				if tool, exists := b.toolRegistry.Get(toolCall.Function.Name); exists && tool.Approve != nil {
+					var err error
+					approval, err = tool.Approve(ctx, toolCall)
+					if err != nil {
+						return nil, nil, fmt.Errorf("failed to approve tool call %q: %w", toolCall.ID, err)
+					}
+				}
+			}
+		}
+
+		switch approval.Decision {
+		case ToolApprovalApprove, ToolApprovalReject, ToolApprovalModify:
+		case ToolApprovalDefer:
+			deferred = append(deferred, toolCall)
+		default:
+			return nil, nil, fmt.Errorf(
+				"unknown approval decision %q for tool call %q", approval.Decision.Value, toolCall.ID,
+			)
+		}
+
+		decisions[toolCall.ID] = approval
+	}
+
+	return decisions, deferred, nil
+}
+
+// pendingToolCalls returns the tool calls of the last assistant message that don't
+// have a tool message with their result yet.
+func (b *chatCompletionBuilder) pendingToolCalls() []ChatCompletionMessageToolCall {
+	for i := len(b.messages) - 1; i >= 0; i-- {
+		message := b.messages[i]
+		if message.Role != RoleAssistant {
+			continue
+		}
+
+		answered := map[string]bool{}
+		for _, next := range b.messages[i+1:] {
+			if next.Role == RoleTool {
+				answered[next.ToolCallID] = true
+			}
+		}
+
+		pending := []ChatCompletionMessageToolCall{}
+		for _, toolCall := range message.ToolCalls {
+			if !answered[toolCall.ID] {
+				pending = append(pending, toolCall)
+			}
+		}
+		return pending
+	}
+
+	return []ChatCompletionMessageToolCall{}
+}
@@ -0,0 +1,167 @@
+package openroutergo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func TestRunWithToolsApprovals(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments string
+		approval  ToolApproval
+		content   string
+		err       error
+	}{
+		{"Approve", `{"a":1,"b":2}`, ApproveToolCall(), "3", nil},
+		{"Reject", `{"a":1,"b":2}`, RejectToolCall("Not today"), "The tool call was rejected: Not today", ErrToolCallRejected},
+		{"Modify", `{"a":1,"b":2}`, ModifyToolCall(`{"a":10,"b":20}`), "30", nil},
+		// The arguments are validated after the approval
+		{"ModifyInvalidArguments", `{"a":"one"}`, ModifyToolCall(`{"a":1,"b":1}`), "2", nil},
+		{"RejectInvalidArguments", `{"a":"one"}`, RejectToolCall("No"), "The tool call was rejected: No", ErrToolCallRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeOpenRouter(t, toolCallsResponse("add", tt.arguments), textResponse("Done"))
+			tool := newAddTool(t, 0)
+			tool.Approve = func(_ context.Context, _ ChatCompletionMessageToolCall) (ToolApproval, error) {
+				return tt.approval, nil
+			}
+
+			completion, result, err := server.client.NewChatCompletion().
+				WithToolRegistry(NewToolRegistry(tool)).
+				WithUserMessage("Add").
+				RunWithTools(context.Background())
+			assert.NoError(t, err)
+
+			callResult := result.Steps[0].ToolCalls[0]
+			assert.Equal(t, tt.approval.Decision, callResult.Approval.Decision)
+			assert.Equal(t, tt.content, callResult.Content)
+			assert.True(t, errors.Is(callResult.Err, tt.err))
+			assert.Equal(t, tt.content, completion.Messages()[2].Content)
+
+			// The conversation keeps the arguments generated by the model
+			assert.Equal(t, tt.arguments, completion.Messages()[1].ToolCalls[0].Function.Arguments)
+		})
+	}
+}
+
+func TestRunWithToolsApprovalValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments string
+		approval  ToolApproval
+	}{
+		{"InvalidArguments", `{"a":"one"}`, ApproveToolCall()},
+		{"InvalidModifiedArguments", `{"a":1,"b":2}`, ModifyToolCall(`{"a":"one"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeOpenRouter(t, toolCallsResponse("add", tt.arguments), textResponse("Done"))
+			tool := newAddTool(t, 0)
+			tool.Approve = func(_ context.Context, _ ChatCompletionMessageToolCall) (ToolApproval, error) {
+				return tt.approval, nil
+			}
+
+			completion, _, err := server.client.NewChatCompletion().
+				WithToolRegistry(NewToolRegistry(tool)).
+				WithUserMessage("Add").
+				RunWithTools(context.Background())
+			assert.True(t, errors.Is(err, ErrInvalidToolArguments))
+			assert.Equal(t, "user,assistant", roles(completion.Messages()))
+		})
+	}
+}
+
+func TestResumeWithTools(t *testing.T) {
+	server := newFakeOpenRouter(t,
+		toolCallsResponse("add", `{"a":1,"b":2}`, "add", `{"a":3,"b":4}`, "add", `{"a":5,"b":6}`),
+		textResponse("Done"),
+	)
+	asked := 0
+	tool := newAddTool(t, 0)
+	tool.Approve = func(_ context.Context, toolCall ChatCompletionMessageToolCall) (ToolApproval, error) {
+		asked++
+		if toolCall.ID == "call_1" {
+			return ApproveToolCall(), nil
+		}
+		return DeferToolCall(), nil
+	}
+	registry := NewToolRegistry(tool)
+
+	completion, result, err := server.client.NewChatCompletion().
+		WithToolRegistry(registry).
+		WithUserMessage("Add").
+		RunWithTools(context.Background())
+	assert.True(t, errors.Is(err, ErrToolApprovalPending))
+	assert.Equal(t, 3, asked)
+	assert.Equal(t, 2, len(result.PendingApprovals))
+	assert.Equal(t, "call_2", result.PendingApprovals[0].ID)
+	assert.Equal(t, 1, len(result.Approvals))
+	assert.Equal(t, ToolApprovalApprove, result.Approvals["call_1"].Decision)
+
+	// No tool call of the turn runs while a decision is pending
+	assert.Equal(t, "user,assistant", roles(completion.Messages()))
+
+	// The paused conversation can be persisted and restored before resuming it
+	data, err := json.Marshal(completion.Snapshot())
+	assert.NoError(t, err)
+	var snapshot ChatCompletionSnapshot
+	assert.NoError(t, json.Unmarshal(data, &snapshot))
+	restored, err := server.client.RestoreChatCompletion(snapshot)
+	assert.NoError(t, err)
+
+	approvals := result.Approvals
+	approvals["call_2"] = RejectToolCall("Too big")
+	approvals["call_3"] = ModifyToolCall(`{"a":0,"b":0}`)
+	restored, result, err = restored.WithToolRegistry(registry).ResumeWithTools(context.Background(), approvals)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, asked)
+	assert.Equal(t, "Done", result.Response.Choices[0].Message.Content)
+
+	messages := restored.Messages()
+	assert.Equal(t, "user,assistant,tool,tool,tool,assistant", roles(messages))
+	assert.Equal(t, "3", messages[2].Content)
+	assert.Equal(t, "The tool call was rejected: Too big", messages[3].Content)
+	assert.Equal(t, "0", messages[4].Content)
+
+	// Without pending tool calls, ResumeWithTools behaves like RunWithTools
+	assert.Equal(t, 0, len(restored.pendingToolCalls()))
+}
+
+func TestRunWithToolsApprovalErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		approval ToolApproval
+		err      error
+	}{
+		{"ApproveError", ApproveToolCall(), errors.New("approval service down")},
+		{"UnknownDecision", ToolApproval{Decision: ToolApprovalDecision{"maybe"}, Reason: "", Arguments: ""}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeOpenRouter(t, toolCallsResponse("add", `{"a":1,"b":2}`))
+			tool := newAddTool(t, 0)
+			tool.Approve = func(_ context.Context, _ ChatCompletionMessageToolCall) (ToolApproval, error) {
+				return tt.approval, tt.err
+			}
+
+			_, result, err := server.client.NewChatCompletion().
+				WithToolRegistry(NewToolRegistry(tool)).
+				WithUserMessage("Add").
+				RunWithTools(context.Background())
+			assert.NotNil(t, err)
+			if tt.err != nil {
+				assert.True(t, errors.Is(err, tt.err))
+			}
+			assert.Equal(t, 1, len(result.Steps))
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package openroutergo
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/zachczx/openroutergo/internal/jsonrepair"
+)
+
+// emulatedToolsInstructions are added to the system prompt when tool calling is
+// emulated, %s is replaced with the definitions of the tools.
+const emulatedToolsInstructions = `You have access to the following tools:
+
+<tools>
+%s
+</tools>
+
+To call a tool, reply with a block in this exact format, with the arguments as a JSON object that matches the parameters of the tool:
+
+<tool_call>
+{"name": "tool_name", "arguments": {"parameter": "value"}}
+</tool_call>
+
+You can call several tools at once using one block for each call. After calling tools, stop and wait for their results, they are sent to you inside <tool_result> blocks. If no tool is needed, answer normally without any <tool_call> block.`
+
+// emulatedToolCallRegexp matches the tool call blocks of the emulated tool calling, the
+// closing tag is optional because it can be cut by a stop sequence.
+var emulatedToolCallRegexp = regexp.MustCompile(`(?s)<tool_call>\s*(.*?)\s*(?:</tool_call>|$)`)
+
+// WithToolEmulation sets when tool calling is emulated with prompting, so the tools can
+// be used with the models that don't support them natively.
+//
+// When emulated, the tools are not sent in the request. Instead:
+//
+//   - The definitions of the tools are added to the system prompt together with the
+//     instructions to call them using <tool_call> blocks.
+//   - The tool calls and tool messages of the conversation are sent as text.
+//   - The <tool_call> blocks of the response are removed from the content and returned
+//     as normal tool calls, the ToolCallsEmulated field of the message is set to true.
+//
+// This way RunWithTools and the code that handles tool calls work with every model.
+// Use EmulationAuto to only emulate tool calling when the model or any of the fallback
+// models don't list "tools" in their supported parameters, see the ListModels method.
+//
+//   - Default: EmulationOff
+func (b *chatCompletionBuilder) WithToolEmulation(mode EmulationMode) *chatCompletionBuilder {
+	b.toolEmulation = mode
+	return b
+}
+
+// emulatedToolMessages returns the given messages of the conversation with the tools
+// described in the system prompt and the tool calls and results converted to text.
+func (b *chatCompletionBuilder) emulatedToolMessages(
+	conversation []ChatCompletionMessage, tools []chatCompletionToolFunction,
+) ([]ChatCompletionMessage, error) {
+	definitions := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		definition, err := json.Marshal(tool.Function)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode tool %q: %w", tool.Function.Name, err)
+		}
+		definitions = append(definitions, string(definition))
+	}
+
+	instructions := fmt.Sprintf(emulatedToolsInstructions, strings.Join(definitions, "\n"))
+	if b.toolChoice.IsSet {
+		switch b.toolChoice.Value {
+		case "none":
+			instructions += "\n\nDo not call any tool for now."
+		case "auto":
+		case "required":
+			instructions += "\n\nYou must call at least one tool."
+		default:
+			instructions += fmt.Sprintf("\n\nYou must call the %s tool.", b.toolChoice.Value)
+		}
+	}
+
+	messages := make([]ChatCompletionMessage, 0, len(conversation))
+	toolNames := map[string]string{}
+	for _, message := range conversation {
+		switch {
+		case message.Role == RoleAssistant && message.HasToolCalls():
+			content := strings.TrimSpace(message.Content)
+			for _, toolCall := range message.ToolCalls {
+				toolNames[toolCall.ID] = toolCall.Function.Name
+				arguments := json.RawMessage(toolCall.Function.Arguments)
+				if !json.Valid(arguments) {
+					arguments = json.RawMessage("{}")
+				}
+				block, err := json.Marshal(emulatedToolCall{Name: toolCall.Function.Name, Arguments: arguments})
+				if err != nil {
+					return nil, fmt.Errorf("failed to encode tool call %q: %w", toolCall.ID, err)
+				}
+				content += "\n<tool_call>\n" + string(block) + "\n</tool_call>"
+			}
+			messages = append(messages, ChatCompletionMessage{Role: RoleAssistant, Content: strings.TrimSpace(content)})
+		case message.Role == RoleTool:
+			name := toolNames[message.ToolCallID]
+			if name == "" {
+				name = message.Name
+			}
+			messages = append(messages, ChatCompletionMessage{
+				Role: RoleUser,
+				Content: fmt.Sprintf(
+					"<tool_result name=%q id=%q>\n%s\n</tool_result>", name, message.ToolCallID, message.Content,
+				),
+			})
+		default:
+			messages = append(messages, message)
+		}
+	}
+
+	return withSystemInstructions(messages, instructions), nil
+}
+
+// emulatedToolCall is the JSON inside a <tool_call> block.
+type emulatedToolCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// parseEmulatedToolCalls moves the <tool_call> blocks of the content of the response
+// messages to their tool calls.
+func parseEmulatedToolCalls(response *ChatCompletionResponse) {
+	for i := range response.Choices {
+		choice := &response.Choices[i]
+		matches := emulatedToolCallRegexp.FindAllStringSubmatchIndex(choice.Message.Content, -1)
+		if len(matches) == 0 {
+			continue
+		}
+
+		content := choice.Message.Content
+		remaining := strings.Builder{}
+		last := 0
+		for _, match := range matches {
+			toolCall, ok := parseEmulatedToolCall(content[match[2]:match[3]])
+			if !ok {
+				// Blocks that can't be parsed are kept in the content
+				continue
+			}
+			remaining.WriteString(content[last:match[0]])
+			last = match[1]
+			choice.Message.ToolCalls = append(choice.Message.ToolCalls, toolCall)
+		}
+		remaining.WriteString(content[last:])
+
+		if choice.Message.HasToolCalls() {
+			choice.Message.Content = strings.TrimSpace(remaining.String())
+			choice.Message.ToolCallsEmulated = true
+			choice.FinishReason = FinishReasonToolCalls
+		}
+	}
+}
+
+// parseEmulatedToolCall parses the JSON inside a <tool_call> block.
+func parseEmulatedToolCall(block string) (ChatCompletionMessageToolCall, bool) {
+	repaired, _, err := jsonrepair.Repair(block)
+	if err != nil {
+		return ChatCompletionMessageToolCall{}, false
+	}
+
+	var call struct {
+		emulatedToolCall
+		Parameters json.RawMessage `json:"parameters"`
+	}
+	if err := json.Unmarshal([]byte(repaired), &call); err != nil || call.Name == "" {
+		return ChatCompletionMessageToolCall{}, false
+	}
+
+	arguments := call.Arguments
+	if len(arguments) == 0 {
+		arguments = call.Parameters
+	}
+
+	// Some models send the arguments as a JSON encoded string
+	var encoded string
+	if err := json.Unmarshal(arguments, &encoded); err == nil {
+		arguments = json.RawMessage(encoded)
+	}
+	if len(arguments) == 0 || string(arguments) == "null" {
+		arguments = json.RawMessage("{}")
+	}
+
+	return ChatCompletionMessageToolCall{
+		ID:   newEmulatedToolCallID(),
+		Type: "function",
+		Function: ChatCompletionMessageToolCallFunction{
+			Name:              call.Name,
+			Arguments:         string(arguments),
+			ArgumentsRepaired: false,
+		},
+	}, true
+}
+
+// newEmulatedToolCallID returns a random ID for an emulated tool call.
+func newEmulatedToolCallID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "call_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,133 @@
+package openroutergo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+func TestShouldEmulate(t *testing.T) {
+	models := `{"data":[` +
+		`{"id":"openai/gpt-4o","supported_parameters":["tools"]},` +
+		`{"id":"mistralai/mistral-7b-instruct","supported_parameters":[]}` +
+		`]}`
+
+	tests := []struct {
+		name      string
+		models    string
+		mode      EmulationMode
+		model     string
+		fallbacks []string
+		expected  bool
+		err       bool
+	}{
+		{"Off", models, EmulationOff, "mistralai/mistral-7b-instruct", nil, false, false},
+		{"Always", models, EmulationAlways, "openai/gpt-4o", nil, true, false},
+		{"AutoSupported", models, EmulationAuto, "openai/gpt-4o", nil, false, false},
+		{"AutoNotSupported", models, EmulationAuto, "mistralai/mistral-7b-instruct", nil, true, false},
+		{"AutoVariant", models, EmulationAuto, "mistralai/mistral-7b-instruct:free", nil, true, false},
+		{"AutoFallbackNotSupported", models, EmulationAuto, "openai/gpt-4o", []string{"mistralai/mistral-7b-instruct"}, true, false},
+		{"AutoUnknownModel", models, EmulationAuto, "unknown/model", []string{"openai/gpt-4o"}, false, false},
+		{"AutoDefaultModel", models, EmulationAuto, "", nil, false, false},
+		{"AutoCatalogError", `not json`, EmulationAuto, "openai/gpt-4o", nil, false, true},
+		{"AlwaysCatalogError", `not json`, EmulationAlways, "openai/gpt-4o", nil, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeOpenRouter(t)
+			server.models = tt.models
+
+			b := server.client.NewChatCompletion()
+			if tt.model != "" {
+				b.WithModel(tt.model)
+			}
+			for _, fallback := range tt.fallbacks {
+				b.WithModelFallback(fallback)
+			}
+
+			emulate, err := b.shouldEmulate(context.Background(), tt.mode, "tools")
+			assert.Equal(t, tt.expected, emulate)
+			assert.Equal(t, tt.err, err != nil)
+		})
+	}
+}
+
+func TestExecuteCatalogError(t *testing.T) {
+	server := newFakeOpenRouter(t, textResponse("Hi"))
+	server.models = `not json`
+
+	_, _, err := server.client.NewChatCompletion().
+		WithModel("openai/gpt-4o").
+		WithTool(ChatCompletionTool{Name: "tool", Description: "", Parameters: nil, Strict: false}).
+		WithToolEmulation(EmulationAuto).
+		WithUserMessage("Hello").
+		Execute()
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, server.requestCount())
+}
+
+func TestParseEmulatedToolCalls(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		remaining string
+		calls     []string
+	}{
+		{"NoToolCalls", "Hello", "Hello", nil},
+		{
+			"ToolCall",
+			"Let me check.\n<tool_call>\n{\"name\": \"weather\", \"arguments\": {\"city\": \"Paris\"}}\n</tool_call>",
+			"Let me check.",
+			[]string{`weather {"city": "Paris"}`},
+		},
+		{
+			"Several",
+			`<tool_call>{"name": "a", "arguments": {}}</tool_call><tool_call>{"name": "b", "arguments": {"x": 1}}</tool_call>`,
+			"",
+			[]string{"a {}", `b {"x": 1}`},
+		},
+		{
+			// Some models use "parameters" or send the arguments as a string
+			"Parameters",
+			`<tool_call>{"name": "a", "parameters": {"x": 1}}</tool_call>`,
+			"",
+			[]string{`a {"x": 1}`},
+		},
+		{"StringArguments", `<tool_call>{"name": "a", "arguments": "{\"x\": 1}"}</tool_call>`, "", []string{`a {"x": 1}`}},
+		{"WithoutArguments", `<tool_call>{"name": "a"}</tool_call>`, "", []string{"a {}"}},
+		{"Repaired", `<tool_call>{"name": "a", "arguments": {"x": 1,}}</tool_call>`, "", []string{`a {"x":1}`}},
+		{
+			// Blocks that can't be parsed are kept in the content
+			"Invalid",
+			`<tool_call>{"arguments": {}}</tool_call> <tool_call>{"name": "a", "arguments": {}}</tool_call>`,
+			`<tool_call>{"arguments": {}}</tool_call>`,
+			[]string{"a {}"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := ChatCompletionResponse{
+				Choices: []ChatCompletionResponseChoice{
+					{Message: ChatCompletionMessage{Role: RoleAssistant, Content: tt.content}},
+				},
+			}
+			parseEmulatedToolCalls(&response)
+
+			choice := response.Choices[0]
+			assert.Equal(t, tt.remaining, choice.Message.Content)
+			assert.Equal(t, len(tt.calls), len(choice.Message.ToolCalls))
+			assert.Equal(t, len(tt.calls) > 0, choice.Message.ToolCallsEmulated)
+			for i, call := range tt.calls {
+				toolCall := choice.Message.ToolCalls[i]
+				assert.Equal(t, call, toolCall.Function.Name+" "+toolCall.Function.Arguments)
+				assert.NotEqual(t, "", toolCall.ID)
+			}
+			if len(tt.calls) > 0 {
+				assert.Equal(t, FinishReasonToolCalls, choice.FinishReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,527 @@
+package openroutergo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMaxToolIterations is the default maximum number of requests that
+	// RunWithTools sends to the model before giving up.
+	defaultMaxToolIterations = 10
+	// defaultToolConcurrency is the default number of tool calls that RunWithTools runs
+	// at the same time.
+	defaultToolConcurrency = 4
+)
+
+// ToolRegistry is a concurrency safe collection of tools that can be used by the
+// RunWithTools method to automatically handle the tool calls made by the model.
+//
+// Create it using the NewToolRegistry function.
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[string]Tool
+	order []string
+}
+
+// NewToolRegistry creates a new tool registry with the given tools.
+func NewToolRegistry(tools ...Tool) *ToolRegistry {
+	r := &ToolRegistry{
+		mu:    sync.RWMutex{},
+		tools: map[string]Tool{},
+		order: []string{},
+	}
+	return r.Register(tools...)
+}
+
+// Register adds tools to the registry.
+//
+// If a tool with the same name is already registered, it will be replaced.
+func (r *ToolRegistry) Register(tools ...Tool) *ToolRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, tool := range tools {
+		if _, exists := r.tools[tool.Name()]; !exists {
+			r.order = append(r.order, tool.Name())
+		}
+		r.tools[tool.Name()] = tool
+	}
+
+	return r
+}
+
+// Get returns the tool with the given name and true if it's registered.
+func (r *ToolRegistry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// Tools returns all the registered tools in the same order they were registered.
+func (r *ToolRegistry) Tools() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tools := make([]Tool, 0, len(r.order))
+	for _, name := range r.order {
+		tools = append(tools, r.tools[name])
+	}
+	return tools
+}
+
+// ToolRunResult is the result of the RunWithTools method.
+type ToolRunResult struct {
+	// The last response from the model, the one that didn't call any tool.
+	Response ChatCompletionResponse
+	// All the steps made during the run, in order. The last step is the final
+	// response and has no tool calls.
+	Steps []ToolRunStep
+	// The tool calls waiting for a human decision, only set when the run was paused
+	// with ErrToolApprovalPending.
+	PendingApprovals []ChatCompletionMessageToolCall
+	// The decisions already made for the tool calls of the paused turn, indexed by tool
+	// call ID. Add the decisions for the pending tool calls and pass them to the
+	// ResumeWithTools method.
+	Approvals map[string]ToolApproval
+}
+
+// ToolRunStep is a single request made to the model during the RunWithTools method
+// and the tool calls handled after it.
+type ToolRunStep struct {
+	// The response from the model for this step.
+	Response ChatCompletionResponse
+	// The results of the tool calls requested by the model in this step, in the same
+	// order the model requested them.
+	ToolCalls []ToolCallResult
+}
+
+// ToolCallResult is the result of handling a single tool call made by the model.
+type ToolCallResult struct {
+	// The tool call made by the model.
+	ToolCall ChatCompletionMessageToolCall
+	// The approval decision made for the tool call.
+	Approval ToolApproval
+	// The content sent back to the model as the tool message.
+	Content string
+	// The error returned by the tool, if any. When a tool fails, the error is sent back
+	// to the model as the tool message content so it can recover.
+	Err error
+	// How long the tool took to run.
+	Duration time.Duration
+}
+
+// WithToolRegistry sets the tool registry used by the RunWithTools method to handle the
+// tool calls made by the model.
+//
+// All the tools in the registry are sent to the model, you don't need to add them
+// using the WithTool method.
+func (b *chatCompletionBuilder) WithToolRegistry(registry *ToolRegistry) *chatCompletionBuilder {
+	b.toolRegistry = registry
+	return b
+}
+
+// WithMaxToolIterations sets the maximum number of requests the RunWithTools method
+// can send to the model before giving up with ErrToolMaxIterations. Values lower than 1
+// use the default.
+//
+//   - Default: 10
+func (b *chatCompletionBuilder) WithMaxToolIterations(maxToolIterations int) *chatCompletionBuilder {
+	b.maxToolIterations = maxToolIterations
+	return b
+}
+
+// WithToolConcurrency sets the maximum number of tool calls the RunWithTools method runs
+// at the same time when the model requests several tool calls in one message.
+//
+// The results are always sent back to the model in the same order the model requested
+// the tool calls. Values lower than 1 remove the limit.
+//
+// Make sure your tools are safe to run concurrently, or set it to 1 to run the tool
+// calls one after another.
+//
+//   - Default: 4
+func (b *chatCompletionBuilder) WithToolConcurrency(toolConcurrency int) *chatCompletionBuilder {
+	b.toolConcurrency = toolConcurrency
+	return b
+}
+
+// WithToolTimeout sets the maximum time a tool call can take when run by the
+// RunWithTools method. The context passed to the tool is derived from the context
+// passed to RunWithTools and the builder context (see WithContext), and is canceled
+// when the timeout is reached.
+//
+// When the timeout is reached, the ErrToolTimeout error is sent back to the model
+// without waiting for the tool, even if it ignores the context. The tool keeps running
+// in the background until it returns and its result is discarded.
+//
+// Tools with their own Timeout field set use that value instead.
+//
+//   - Default: 0 (no timeout)
+func (b *chatCompletionBuilder) WithToolTimeout(toolTimeout time.Duration) *chatCompletionBuilder {
+	b.toolTimeout = toolTimeout
+	return b
+}
+
+// WithToolValidationFeedback sets what the RunWithTools method does when the arguments
+// of a tool call don't match the parameters JSON Schema of the tool (see
+// ValidateToolArguments).
+//
+// The arguments are validated after the approval of the tool calls (see the Approve
+// field of Tool), so the arguments of a modified tool call are the ones validated and
+// the rejected tool calls are not validated.
+//
+//   - false (default): RunWithTools stops before running any tool of the turn and
+//     returns the ErrInvalidToolArguments error. The builder ends with the assistant
+//     message that contains the tool calls.
+//   - true: The validation error is sent back to the model as the tool message content
+//     so it can fix the arguments and call the tool again.
+func (b *chatCompletionBuilder) WithToolValidationFeedback(feedback bool) *chatCompletionBuilder {
+	b.toolArgsFeedback = feedback
+	return b
+}
+
+// requestTools returns the tools that should be sent to the model, the ones added
+// using the WithTool method followed by the ones in the tool registry.
+func (b *chatCompletionBuilder) requestTools() []chatCompletionToolFunction {
+	if b.toolRegistry == nil {
+		return b.tools
+	}
+
+	tools := make([]chatCompletionToolFunction, 0, len(b.tools))
+	names := map[string]bool{}
+	for _, tool := range b.tools {
+		tools = append(tools, tool)
+		names[tool.Function.Name] = true
+	}
+	for _, tool := range b.toolRegistry.Tools() {
+		if names[tool.Name()] {
+			continue
+		}
+		tools = append(tools, chatCompletionToolFunction{Type: "function", Function: tool.Definition})
+	}
+
+	return tools
+}
+
+// RunWithTools executes the chat completion request and automatically handles the tool
+// calls made by the model using the tools in the registry set with WithToolRegistry.
+//
+// It loops until the model returns a message without tool calls:
+//
+//  1. Executes the request.
+//  2. Dispatches every tool call to the registered tool with the same name.
+//  3. Sends the results back to the model as tool messages.
+//
+// If a tool fails or is not registered, the error is sent back to the model as the tool
+// message content so it can recover, and it's recorded in the trace.
+//
+// Tools with an Approve function are asked before running each tool call. If any tool
+// call of a turn is deferred, no tool call of the turn runs and ErrToolApprovalPending
+// is returned, see the ResumeWithTools method to continue the conversation.
+//
+// Returns:
+//
+//   - The chat completion builder with all the new messages added.
+//   - The result with the final response and a trace of all the steps.
+//   - ErrToolMaxIterations if the model keeps calling tools after the maximum number of
+//     iterations (see WithMaxToolIterations), or the error of a failed request. The
+//     result contains the steps made until the error.
+//
+// Example:
+//
+//	registry := openroutergo.NewToolRegistry(weatherTool)
+//
+//	_, result, err := client.
+//		NewChatCompletion().
+//		WithModel("...").
+//		WithToolRegistry(registry).
+//		WithUserMessage("What's the weather in Paris?").
+//		RunWithTools(ctx)
+//	if err != nil {
+//		// handle error
+//	}
+//
+//	fmt.Println("Response: ", result.Response.Choices[0].Message.Content)
+func (b *chatCompletionBuilder) RunWithTools(ctx context.Context) (*chatCompletionBuilder, ToolRunResult, error) {
+	return b.runWithTools(ctx, []ChatCompletionMessageToolCall{}, map[string]ToolApproval{})
+}
+
+// ResumeWithTools continues a conversation paused by RunWithTools with
+// ErrToolApprovalPending, using the decisions made for the pending tool calls.
+//
+// The approvals map is indexed by tool call ID, it should contain the decisions in the
+// Approvals field of the paused result plus the decisions for the tool calls in the
+// PendingApprovals field. Tool calls without a decision are sent to the Approve function
+// of the tool again.
+//
+// The builder can be a different one than the one that was paused, for example, if the
+// conversation was persisted and restored, as long as it has the same messages and tool
+// registry. If there are no pending tool calls, it behaves like RunWithTools.
+//
+// Example:
+//
+//	_, result, err := completion.RunWithTools(ctx)
+//	if errors.Is(err, openroutergo.ErrToolApprovalPending) {
+//		// Persist completion.Messages() and result.Approvals, ask a human and later...
+//		approvals := result.Approvals
+//		approvals[result.PendingApprovals[0].ID] = openroutergo.RejectToolCall("Not allowed")
+//		_, result, err = completion.ResumeWithTools(ctx, approvals)
+//	}
+func (b *chatCompletionBuilder) ResumeWithTools(
+	ctx context.Context, approvals map[string]ToolApproval,
+) (*chatCompletionBuilder, ToolRunResult, error) {
+	return b.runWithTools(ctx, b.pendingToolCalls(), approvals)
+}
+
+// runWithTools handles the pending tool calls (if any) and then runs the tool-execution
+// loop, see RunWithTools.
+func (b *chatCompletionBuilder) runWithTools(
+	ctx context.Context,
+	pending []ChatCompletionMessageToolCall,
+	approvals map[string]ToolApproval,
+) (*chatCompletionBuilder, ToolRunResult, error) {
+	result := ToolRunResult{
+		Response:         ChatCompletionResponse{},
+		Steps:            []ToolRunStep{},
+		PendingApprovals: []ChatCompletionMessageToolCall{},
+		Approvals:        map[string]ToolApproval{},
+	}
+
+	// The requests and the tools also stop when the builder context is done
+	if b.ctx != nil && b.ctx != ctx {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		builderCtx := b.ctx
+		stop := context.AfterFunc(builderCtx, func() { cancel(context.Cause(builderCtx)) })
+		defer stop()
+	}
+
+	if len(pending) > 0 {
+		step := ToolRunStep{Response: ChatCompletionResponse{}, ToolCalls: []ToolCallResult{}}
+		if err := b.handleToolCalls(ctx, pending, approvals, &step, &result); err != nil {
+			return b, result, err
+		}
+	}
+
+	maxIterations := b.maxToolIterations
+	if maxIterations < 1 {
+		maxIterations = defaultMaxToolIterations
+	}
+
+	for range maxIterations {
+		_, resp, err := b.execute(ctx)
+		if err != nil {
+			return b, result, err
+		}
+
+		step := ToolRunStep{Response: resp, ToolCalls: []ToolCallResult{}}
+		if !resp.HasChoices() || !resp.Choices[0].Message.HasToolCalls() {
+			result.Steps = append(result.Steps, step)
+			result.Response = resp
+			return b, result, nil
+		}
+
+		toolCalls := resp.Choices[0].Message.ToolCalls
+		if err := b.handleToolCalls(ctx, toolCalls, map[string]ToolApproval{}, &step, &result); err != nil {
+			return b, result, err
+		}
+	}
+
+	return b, result, fmt.Errorf("%w: %d iterations", ErrToolMaxIterations, maxIterations)
+}
+
+// handleToolCalls approves, validates and runs the tool calls of a turn, then adds the
+// tool messages with the results to the conversation and the step to the result.
+//
+// If any tool call is deferred, no tool call of the turn runs and ErrToolApprovalPending
+// is returned. The arguments are validated after the approval, so the modified
+// arguments are the ones validated and the rejected tool calls are not validated.
+func (b *chatCompletionBuilder) handleToolCalls(
+	ctx context.Context,
+	toolCalls []ChatCompletionMessageToolCall,
+	approvals map[string]ToolApproval,
+	step *ToolRunStep,
+	result *ToolRunResult,
+) error {
+	decisions, deferred, err := b.approveToolCalls(ctx, toolCalls, approvals)
+	if err != nil {
+		result.Steps = append(result.Steps, *step)
+		return err
+	}
+	if len(deferred) > 0 {
+		result.Steps = append(result.Steps, *step)
+		result.PendingApprovals = deferred
+		for id, decision := range decisions {
+			if decision.Decision != ToolApprovalDefer {
+				result.Approvals[id] = decision
+			}
+		}
+		return fmt.Errorf("%w: %d tool calls", ErrToolApprovalPending, len(deferred))
+	}
+
+	invalid := make([]error, len(toolCalls))
+	for i, toolCall := range toolCalls {
+		invalid[i] = b.validateToolCall(toolCall, decisions[toolCall.ID])
+		if invalid[i] != nil && !b.toolArgsFeedback {
+			result.Steps = append(result.Steps, *step)
+			return invalid[i]
+		}
+	}
+
+	step.ToolCalls = b.callTools(ctx, toolCalls, decisions, invalid)
+	for _, callResult := range step.ToolCalls {
+		b.WithToolMessage(callResult.ToolCall, callResult.Content)
+	}
+	result.Steps = append(result.Steps, *step)
+
+	return nil
+}
+
+// callTools dispatches the tool calls to the registered tools, running up to
+// toolConcurrency of them at the same time, and returns the results in the same order
+// as the tool calls. The tool calls with a validation error in invalid don't run.
+func (b *chatCompletionBuilder) callTools(
+	ctx context.Context,
+	toolCalls []ChatCompletionMessageToolCall,
+	decisions map[string]ToolApproval,
+	invalid []error,
+) []ToolCallResult {
+	results := make([]ToolCallResult, len(toolCalls))
+
+	workers := b.toolConcurrency
+	if workers < 1 || workers > len(toolCalls) {
+		workers = len(toolCalls)
+	}
+
+	if workers <= 1 {
+		for i, toolCall := range toolCalls {
+			results[i] = b.callTool(ctx, toolCall, decisions[toolCall.ID], invalid[i])
+		}
+		return results
+	}
+
+	semaphore := make(chan struct{}, workers)
+	wg := sync.WaitGroup{}
+	for i, toolCall := range toolCalls {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[i] = b.callTool(ctx, toolCall, decisions[toolCall.ID], invalid[i])
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// callTool dispatches a tool call to the registered tool and returns the result.
+//
+// Rejected tool calls don't run and tool calls with modified arguments run with the new
+// arguments, which were already validated by validateToolCall (invalidArgs is its
+// error). If the tool panics, the panic is recovered and returned as an ErrToolPanicked
+// error, and if the context is done before the tool returns, the cause of the context
+// is returned without waiting for it.
+func (b *chatCompletionBuilder) callTool(
+	ctx context.Context, toolCall ChatCompletionMessageToolCall, approval ToolApproval, invalidArgs error,
+) (result ToolCallResult) {
+	start := time.Now()
+	result = ToolCallResult{ToolCall: toolCall, Approval: approval, Content: "", Err: nil, Duration: 0}
+
+	defer func() {
+		if errors.Is(result.Err, ErrToolCallRejected) {
+			result.Content = "The tool call was rejected: " + approval.Reason
+		} else if errors.Is(result.Err, ErrInvalidToolArguments) {
+			result.Content = "Error: " + result.Err.Error() + ". Fix the arguments and call the tool again."
+		} else if result.Err != nil {
+			result.Content = "Error: " + result.Err.Error()
+		}
+		result.Duration = time.Since(start)
+	}()
+
+	if approval.Decision == ToolApprovalReject {
+		result.Err = fmt.Errorf("%w: %s", ErrToolCallRejected, approval.Reason)
+		return result
+	}
+	if approval.Decision == ToolApprovalModify {
+		toolCall.Function.Arguments = approval.Arguments
+	}
+
+	var tool Tool
+	ok := false
+	if b.toolRegistry != nil {
+		tool, ok = b.toolRegistry.Get(toolCall.Function.Name)
+	}
+	if !ok {
+		result.Err = fmt.Errorf("%w: %q", ErrToolNotFound, toolCall.Function.Name)
+		return result
+	}
+
+	if invalidArgs != nil {
+		result.Err = invalidArgs
+		return result
+	}
+
+	timeout := b.toolTimeout
+	if tool.Timeout > 0 {
+		timeout = tool.Timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, fmt.Errorf("%w after %s", ErrToolTimeout, timeout))
+		defer cancel()
+	}
+
+	// The tool runs in its own goroutine so it can be abandoned when the context is
+	// done, even if the tool ignores it
+	type toolOutput struct {
+		content string
+		err     error
+	}
+	output := make(chan toolOutput, 1)
+	go func() {
+		out := toolOutput{content: "", err: nil}
+		defer func() {
+			if r := recover(); r != nil {
+				out.err = fmt.Errorf("%w: %v", ErrToolPanicked, r)
+			}
+			output <- out
+		}()
+		out.content, out.err = tool.call(ctx, toolCall, false)
+	}()
+
+	select {
+	case out := <-output:
+		result.Content, result.Err = out.content, out.err
+	case <-ctx.Done():
+		result.Err = context.Cause(ctx)
+	}
+	return result
+}
+
+// validateToolCall validates the arguments the tool call runs with (the modified ones
+// if the approval modified them) against the schema of the registered tool. Rejected
+// tool calls and tool calls for unknown tools are not validated.
+func (b *chatCompletionBuilder) validateToolCall(toolCall ChatCompletionMessageToolCall, approval ToolApproval) error {
+	if b.toolRegistry == nil || approval.Decision == ToolApprovalReject {
+		return nil
+	}
+	if approval.Decision == ToolApprovalModify {
+		toolCall.Function.Arguments = approval.Arguments
+	}
+
+	tool, ok := b.toolRegistry.Get(toolCall.Function.Name)
+	if !ok {
+		return nil
+	}
+
+	return ValidateToolArguments(tool.Definition, toolCall.Function.Arguments)
+}
@@ -0,0 +1,377 @@
+package openroutergo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+// fakeResponse is a response of the fake OpenRouter server.
+type fakeResponse struct {
+	status int
+	body   string
+}
+
+// textResponse returns a chat completion response with an assistant message.
+func textResponse(content string) fakeResponse {
+	message, _ := json.Marshal(content)
+	return fakeResponse{
+		status: http.StatusOK,
+		body: `{"id":"gen","model":"openai/gpt-4o","choices":[{"finish_reason":"stop",` +
+			`"message":{"role":"assistant","content":` + string(message) + `}}],` +
+			`"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`,
+	}
+}
+
+// toolCallsResponse returns a chat completion response with the given tool calls, the
+// arguments are given as name and JSON arguments pairs.
+func toolCallsResponse(calls ...string) fakeResponse {
+	toolCalls := []ChatCompletionMessageToolCall{}
+	for i := 0; i+1 < len(calls); i += 2 {
+		toolCalls = append(toolCalls, ChatCompletionMessageToolCall{
+			ID:   fmt.Sprintf("call_%d", len(toolCalls)+1),
+			Type: "function",
+			Function: ChatCompletionMessageToolCallFunction{
+				Name:              calls[i],
+				Arguments:         calls[i+1],
+				ArgumentsRepaired: false,
+			},
+		})
+	}
+	encoded, _ := json.Marshal(toolCalls)
+
+	return fakeResponse{
+		status: http.StatusOK,
+		body: `{"id":"gen","model":"openai/gpt-4o","choices":[{"finish_reason":"tool_calls",` +
+			`"message":{"role":"assistant","content":"","tool_calls":` + string(encoded) + `}}]}`,
+	}
+}
+
+// apiErrorResponse returns an OpenRouter error response.
+func apiErrorResponse(status int, message string) fakeResponse {
+	encoded, _ := json.Marshal(message)
+	return fakeResponse{
+		status: status,
+		body:   fmt.Sprintf(`{"error":{"code":%d,"message":%s}}`, status, encoded),
+	}
+}
+
+// fakeOpenRouter is a fake OpenRouter server that answers the chat completion requests
+// with the queued responses in order and records the requests it receives.
+type fakeOpenRouter struct {
+	mu        sync.Mutex
+	client    *Client
+	models    string
+	responses []fakeResponse
+	requests  []map[string]any
+}
+
+func newFakeOpenRouter(t *testing.T, responses ...fakeResponse) *fakeOpenRouter {
+	t.Helper()
+	f := &fakeOpenRouter{
+		mu:        sync.Mutex{},
+		client:    nil,
+		models:    `{"data":[]}`,
+		responses: responses,
+		requests:  []map[string]any{},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/models":
+			_, _ = w.Write([]byte(f.models))
+		case "/chat/completions":
+			body := map[string]any{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			f.requests = append(f.requests, body)
+
+			if len(f.responses) == 0 {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"error":{"code":500,"message":"no more responses"}}`))
+				return
+			}
+			response := f.responses[0]
+			f.responses = f.responses[1:]
+			w.WriteHeader(response.status)
+			_, _ = w.Write([]byte(response.body))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	f.client = newTestClient(t, server.URL)
+	return f
+}
+
+// request returns the body of the i-th chat completion request.
+func (f *fakeOpenRouter) request(i int) map[string]any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i >= len(f.requests) {
+		return nil
+	}
+	return f.requests[i]
+}
+
+// requestCount returns the number of chat completion requests received.
+func (f *fakeOpenRouter) requestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+// roles returns the roles of the messages, to compare conversations.
+func roles(messages []ChatCompletionMessage) string {
+	parts := []string{}
+	for _, message := range messages {
+		parts = append(parts, message.Role.Value)
+	}
+	return strings.Join(parts, ",")
+}
+
+type addArgs struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+func newAddTool(t *testing.T, delay time.Duration) Tool {
+	t.Helper()
+	tool, err := NewTool("add", "Adds two numbers", func(_ context.Context, args addArgs) (int, error) {
+		time.Sleep(delay)
+		return args.A + args.B, nil
+	})
+	assert.NoError(t, err)
+	return tool
+}
+
+func TestRunWithTools(t *testing.T) {
+	server := newFakeOpenRouter(t,
+		toolCallsResponse("add", `{"a":1,"b":2}`, "add", `{"a":3,"b":4}`, "missing", `{}`),
+		toolCallsResponse("add", `{"a":10,"b":20}`),
+		textResponse("The results are 3, 7 and 30."),
+	)
+	slow, err := NewTool("add", "Adds two numbers", func(_ context.Context, args addArgs) (int, error) {
+		// The first tool call finishes last but its result is still the first one
+		if args.A == 1 {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return args.A + args.B, nil
+	})
+	assert.NoError(t, err)
+
+	completion, result, err := server.client.NewChatCompletion().
+		WithModel("openai/gpt-4o").
+		WithToolRegistry(NewToolRegistry(slow)).
+		WithUserMessage("Add the numbers").
+		RunWithTools(context.Background())
+	assert.NoError(t, err)
+
+	assert.Equal(t, "The results are 3, 7 and 30.", result.Response.Choices[0].Message.Content)
+	assert.Equal(t, 3, len(result.Steps))
+	assert.Equal(t, 3, len(result.Steps[0].ToolCalls))
+	assert.Equal(t, 1, len(result.Steps[1].ToolCalls))
+	assert.Equal(t, 0, len(result.Steps[2].ToolCalls))
+	assert.Equal(t, 3, server.requestCount())
+
+	messages := completion.Messages()
+	assert.Equal(t, "user,assistant,tool,tool,tool,assistant,tool,assistant", roles(messages))
+	assert.Equal(t, "call_1", messages[2].ToolCallID)
+	assert.Equal(t, "3", messages[2].Content)
+	assert.Equal(t, "call_2", messages[3].ToolCallID)
+	assert.Equal(t, "7", messages[3].Content)
+	assert.Equal(t, "call_3", messages[4].ToolCallID)
+	assert.Equal(t, `Error: tool not found: "missing"`, messages[4].Content)
+	assert.True(t, errors.Is(result.Steps[0].ToolCalls[2].Err, ErrToolNotFound))
+	assert.Equal(t, "30", messages[6].Content)
+
+	// The tools are sent in every request
+	tools, ok := server.request(0)["tools"].([]any)
+	assert.True(t, ok)
+	assert.Equal(t, 1, len(tools))
+}
+
+func TestRunWithToolsConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		expected    int
+	}{
+		{"Default", defaultToolConcurrency, 4},
+		{"Sequential", 1, 1},
+		{"Limited", 2, 2},
+		{"Unlimited", 0, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := []string{}
+			for i := range 6 {
+				calls = append(calls, "count", fmt.Sprintf(`{"a":%d,"b":0}`, i))
+			}
+			server := newFakeOpenRouter(t, toolCallsResponse(calls...), textResponse("Done"))
+
+			mu := sync.Mutex{}
+			running, maxRunning := 0, 0
+			tool, err := NewTool("count", "Counts", func(_ context.Context, args addArgs) (int, error) {
+				mu.Lock()
+				running++
+				maxRunning = max(maxRunning, running)
+				mu.Unlock()
+				time.Sleep(50 * time.Millisecond)
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return args.A, nil
+			})
+			assert.NoError(t, err)
+
+			completion, _, err := server.client.NewChatCompletion().
+				WithToolRegistry(NewToolRegistry(tool)).
+				WithToolConcurrency(tt.concurrency).
+				WithUserMessage("Count").
+				RunWithTools(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, maxRunning)
+
+			for i, message := range completion.Messages()[2:8] {
+				assert.Equal(t, fmt.Sprint(i), message.Content)
+			}
+		})
+	}
+}
+
+func TestRunWithToolsPanic(t *testing.T) {
+	server := newFakeOpenRouter(t, toolCallsResponse("panic", `{}`), textResponse("Sorry"))
+	tool, err := NewTool("panic", "Panics", func(_ context.Context, _ struct{}) (string, error) {
+		panic("boom")
+	})
+	assert.NoError(t, err)
+
+	completion, result, err := server.client.NewChatCompletion().
+		WithToolRegistry(NewToolRegistry(tool)).
+		WithUserMessage("Panic").
+		RunWithTools(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(result.Steps[0].ToolCalls[0].Err, ErrToolPanicked))
+	assert.Equal(t, "Error: the tool panicked: boom", completion.Messages()[2].Content)
+}
+
+func TestRunWithToolsTimeout(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	// The tool ignores the context, it's abandoned when the timeout is reached
+	stuck, err := NewTool("stuck", "Never finishes", func(_ context.Context, _ struct{}) (string, error) {
+		<-release
+		return "too late", nil
+	})
+	assert.NoError(t, err)
+	fast := newAddTool(t, 0)
+	fast.Timeout = time.Second
+
+	tests := []struct {
+		name    string
+		builder func(server *fakeOpenRouter) *chatCompletionBuilder
+		tool    Tool
+	}{
+		{
+			"BuilderTimeout",
+			func(server *fakeOpenRouter) *chatCompletionBuilder {
+				return server.client.NewChatCompletion().WithToolTimeout(20 * time.Millisecond)
+			},
+			stuck,
+		},
+		{
+			"ToolTimeout",
+			func(server *fakeOpenRouter) *chatCompletionBuilder {
+				tool := stuck
+				tool.Timeout = 20 * time.Millisecond
+				return server.client.NewChatCompletion().WithToolRegistry(NewToolRegistry(tool, fast))
+			},
+			stuck,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeOpenRouter(t, toolCallsResponse("stuck", `{}`), textResponse("It timed out"))
+			builder := tt.builder(server)
+			if builder.toolRegistry == nil {
+				builder.WithToolRegistry(NewToolRegistry(tt.tool))
+			}
+
+			start := time.Now()
+			completion, result, err := builder.WithUserMessage("Run").RunWithTools(context.Background())
+			assert.NoError(t, err)
+			assert.True(t, time.Since(start) < time.Second)
+			assert.True(t, errors.Is(result.Steps[0].ToolCalls[0].Err, ErrToolTimeout))
+			assert.Equal(t, "Error: the tool timed out after 20ms", completion.Messages()[2].Content)
+		})
+	}
+}
+
+func TestRunWithToolsBuilderContext(t *testing.T) {
+	server := newFakeOpenRouter(t, toolCallsResponse("wait", `{}`), textResponse("Canceled"))
+	ctx, cancel := context.WithCancel(context.Background())
+	tool, err := NewTool("wait", "Waits for the context", func(ctx context.Context, _ struct{}) (string, error) {
+		cancel()
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	assert.NoError(t, err)
+
+	// The tool context is canceled with the builder context, not only the run context
+	_, result, err := server.client.NewChatCompletion().
+		WithContext(ctx).
+		WithToolRegistry(NewToolRegistry(tool)).
+		WithUserMessage("Wait").
+		RunWithTools(context.Background())
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, errors.Is(result.Steps[0].ToolCalls[0].Err, context.Canceled))
+}
+
+func TestRunWithToolsMaxIterations(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxIterations int
+		expected      int
+	}{
+		{"Custom", 2, 2},
+		{"Default", defaultMaxToolIterations, defaultMaxToolIterations},
+		{"Zero", 0, defaultMaxToolIterations},
+		{"Negative", -1, defaultMaxToolIterations},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses := []fakeResponse{}
+			for range tt.expected + 1 {
+				responses = append(responses, toolCallsResponse("add", `{"a":1,"b":1}`))
+			}
+			server := newFakeOpenRouter(t, responses...)
+
+			_, result, err := server.client.NewChatCompletion().
+				WithToolRegistry(NewToolRegistry(newAddTool(t, 0))).
+				WithMaxToolIterations(tt.maxIterations).
+				WithUserMessage("Loop").
+				RunWithTools(context.Background())
+			assert.True(t, errors.Is(err, ErrToolMaxIterations))
+			assert.Equal(t, tt.expected, len(result.Steps))
+			assert.Equal(t, tt.expected, server.requestCount())
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package openroutergo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zachczx/openroutergo/internal/assert"
+)
+
+type weatherArgs struct {
+	City  string  `json:"city" jsonschema:"minLength=1"`
+	Units *string `json:"units" jsonschema:"enum=celsius|fahrenheit"`
+	Days  []int   `json:"days,omitempty"`
+}
+
+func (a weatherArgs) Validate() error {
+	if a.City == "Atlantis" {
+		return errors.New("the city doesn't exist")
+	}
+	return nil
+}
+
+type forecastArgs struct {
+	City string `json:"city"`
+}
+
+func (a *forecastArgs) Validate() error {
+	if a.City == "Atlantis" {
+		return errors.New("the city doesn't exist")
+	}
+	return nil
+}
+
+type forecast struct {
+	City        string `json:"city"`
+	Temperature int    `json:"temperature"`
+}
+
+func TestNewTool(t *testing.T) {
+	var received weatherArgs
+	weather, err := NewTool("weather", "Get the weather", func(_ context.Context, args weatherArgs) (string, error) {
+		received = args
+		return "Sunny in " + args.City, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "weather", weather.Name())
+	assert.Equal(t, "Get the weather", weather.Definition.Description)
+	assert.Equal(t, any("object"), weather.Definition.Parameters["type"])
+
+	// The string results are sent as they are
+	content, err := weather.Call(context.Background(), toolCall("weather", `{"city":"Paris","units":"celsius","days":[1,2]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "Sunny in Paris", content)
+	assert.Equal(t, "Paris", received.City)
+	assert.Equal(t, "celsius", *received.Units)
+	assert.Equal(t, 2, len(received.Days))
+
+	// The pointers accept null
+	content, err = weather.Call(context.Background(), toolCall("weather", `{"city":"Rome","units":null}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "Sunny in Rome", content)
+	assert.True(t, received.Units == nil)
+
+	// The other results are encoded as JSON
+	forecastTool, err := NewTool("forecast", "Get the forecast", func(_ context.Context, args forecastArgs) (forecast, error) {
+		return forecast{City: args.City, Temperature: 21}, nil
+	})
+	assert.NoError(t, err)
+	content, err = forecastTool.Call(context.Background(), toolCall("forecast", `{"city":"Paris"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"city":"Paris","temperature":21}`, content)
+
+	_, err = NewTool("invalid", "Not a struct", func(_ context.Context, args string) (string, error) {
+		return args, nil
+	})
+	assert.True(t, errors.Is(err, ErrInvalidToolParameters))
+}
+
+func TestNewToolInvalidArguments(t *testing.T) {
+	called := false
+	weather, err := NewTool("weather", "Get the weather", func(_ context.Context, _ weatherArgs) (string, error) {
+		called = true
+		return "Sunny", nil
+	})
+	assert.NoError(t, err)
+	forecastTool, err := NewTool("forecast", "Get the forecast", func(_ context.Context, _ forecastArgs) (string, error) {
+		called = true
+		return "Sunny", nil
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		tool      Tool
+		arguments string
+		message   string
+	}{
+		{"Missing", weather, `{}`, "city"},
+		{"Empty", weather, ``, "city"},
+		{"Schema", weather, `{"city":""}`, "city"},
+		{"Enum", weather, `{"city":"Paris","units":"kelvin"}`, "units"},
+		{"Unknown", weather, `{"city":"Paris","country":"France"}`, "country"},
+		{"Malformed", weather, `{"city":`, ""},
+		{"ValueValidate", weather, `{"city":"Atlantis"}`, "the city doesn't exist"},
+		{"PointerValidate", forecastTool, `{"city":"Atlantis"}`, "the city doesn't exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = false
+			_, err := tt.tool.Call(context.Background(), toolCall(tt.tool.Name(), tt.arguments))
+			assert.True(t, errors.Is(err, ErrInvalidToolArguments))
+			assert.True(t, strings.Contains(err.Error(), tt.message))
+			assert.False(t, called)
+		})
+	}
+}
+
+func TestNewRawTool(t *testing.T) {
+	definition := ChatCompletionTool{
+		Name:        "echo",
+		Description: "Echoes the text",
+		Parameters: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"text": map[string]any{"type": "string"}},
+			"required":   []any{"text"},
+		},
+	}
+	echo := NewRawTool(definition, func(_ context.Context, arguments string) (string, error) {
+		return arguments, nil
+	})
+
+	// The handler receives the raw arguments
+	content, err := echo.Call(context.Background(), toolCall("echo", `{"text": "hi"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"text": "hi"}`, content)
+
+	_, err = echo.Call(context.Background(), toolCall("echo", `{"text":1}`))
+	assert.True(t, errors.Is(err, ErrInvalidToolArguments))
+
+	// The tool calls for another tool are not run
+	_, err = echo.Call(context.Background(), toolCall("other", `{"text":"hi"}`))
+	assert.True(t, errors.Is(err, ErrToolNotFound))
+	_, err = Tool{Definition: definition, Timeout: 0, Approve: nil, handler: nil}.Call(context.Background(), toolCall("echo", `{"text":"hi"}`))
+	assert.True(t, errors.Is(err, ErrToolNotFound))
+}
+
+func TestRunWithToolsValidationFeedback(t *testing.T) {
+	server := newFakeOpenRouter(t,
+		toolCallsResponse("add", `{"a":"one","b":2}`),
+		toolCallsResponse("add", `{"a":1,"b":2}`),
+		textResponse("The result is 3."),
+	)
+	calls := 0
+	add, err := NewTool("add", "Adds two numbers", func(_ context.Context, args addArgs) (int, error) {
+		calls++
+		return args.A + args.B, nil
+	})
+	assert.NoError(t, err)
+
+	completion, result, err := server.client.NewChatCompletion().
+		WithModel("openai/gpt-4o").
+		WithToolRegistry(NewToolRegistry(add)).
+		WithToolValidationFeedback(true).
+		WithUserMessage("Add one and two").
+		RunWithTools(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 3, len(result.Steps))
+
+	// The invalid arguments are sent back to the model without running the tool
+	invalid := result.Steps[0].ToolCalls[0]
+	assert.True(t, errors.Is(invalid.Err, ErrInvalidToolArguments))
+	assert.True(t, strings.HasPrefix(invalid.Content, "Error: "))
+	assert.Equal(t, "3", result.Steps[1].ToolCalls[0].Content)
+	assert.Equal(t, "user,assistant,tool,assistant,tool,assistant", roles(completion.Messages()))
+}
+
+func toolCall(name string, arguments string) ChatCompletionMessageToolCall {
+	return ChatCompletionMessageToolCall{
+		ID:       "call_1",
+		Type:     "function",
+		Function: ChatCompletionMessageToolCallFunction{Name: name, Arguments: arguments},
+	}
+}